@@ -0,0 +1,125 @@
+package dnsmsg
+
+// AdditionalPriority is a priority of a resource queued in the [AdditionalPacker].
+type AdditionalPriority uint8
+
+const (
+	// AdditionalRequiredGlue is the priority of glue resources that are required for the
+	// referral to be usable (in-domain glue, RFC 9471, Section 2.1). When a required glue
+	// RRset does not fit in the message, the TC bit is set.
+	AdditionalRequiredGlue AdditionalPriority = iota
+
+	// AdditionalOptionalGlue is the priority of glue resources that are not required for the
+	// referral (sibling glue, RFC 9471, Section 2.2). They are omitted when they do not fit,
+	// without setting the TC bit.
+	AdditionalOptionalGlue
+
+	// AdditionalOther is the priority of all other additional resources (e.g. addresses of
+	// MX targets). They are omitted when they do not fit, without setting the TC bit.
+	AdditionalOther
+)
+
+// AdditionalPacker queues resources for the additional section, with priorities, and
+// appends as many of them as possible to the additional section of a [Builder], within the
+// message size limit (see [Builder.LimitMessageSize]), after the answer and authority sections
+// are built.
+//
+// Resources are appended in the order of priorities, resources with the same priority in the
+// order they were queued. All resources of an RRset (the same name, type and class) with the
+// same priority are appended together, an RRset that does not fit entirely is omitted.
+//
+// The zero value of AdditionalPacker is ready to use.
+type AdditionalPacker struct {
+	queued []additionalResource
+}
+
+type additionalResource struct {
+	priority AdditionalPriority
+	res      Resource
+}
+
+// Add queues the res resource with the priority.
+func (p *AdditionalPacker) Add(priority AdditionalPriority, res Resource) {
+	p.queued = append(p.queued, additionalResource{priority: priority, res: res})
+}
+
+// Reset removes all queued resources.
+func (p *AdditionalPacker) Reset() {
+	p.queued = p.queued[:0]
+}
+
+// Pack appends the queued resources to the additional section of b.
+// The building section of b must be set to authorities (then the additionals section is started
+// by Pack) or additionals, otherwise it panics.
+//
+// When a [AdditionalRequiredGlue] RRset does not fit, the TC bit is set in the header of b.
+// Resources of lower priorities never cause the TC bit to be set (RFC 9471), even when the
+// truncation mode ([Builder.EnableTruncation]) is enabled.
+//
+// Pack returns an error only when appending a resource failed for a reason other
+// than [ErrTruncated], the message is still valid in such case.
+func (p *AdditionalPacker) Pack(b *Builder) error {
+	if b.curSection == sectionAuthorities {
+		b.StartAdditionals()
+	}
+	if b.curSection != sectionAdditionals {
+		b.panicInvalidSection()
+	}
+
+	truncation := b.truncation
+	b.truncation = false
+	defer func() { b.truncation = truncation }()
+
+	packed := make([]bool, len(p.queued))
+	for priority := AdditionalRequiredGlue; priority <= AdditionalOther; priority++ {
+		for i := range p.queued {
+			if packed[i] || p.queued[i].priority != priority {
+				continue
+			}
+
+			truncated, err := p.packRRSet(b, i, packed)
+			if err != nil {
+				return err
+			}
+			if truncated && priority == AdditionalRequiredGlue {
+				b.hdr.Flags.SetBit(BitTC, true)
+			}
+		}
+	}
+	return nil
+}
+
+// packRRSet appends the RRset of the i-th queued resource (all not yet packed resources with the
+// same name, type, class and priority), the appended resources are marked in packed. When the RRset
+// does not fit, all its resources are removed from the message and truncated is set to true.
+func (p *AdditionalPacker) packRRSet(b *Builder, i int, packed []bool) (truncated bool, err error) {
+	var (
+		first      = &p.queued[i]
+		startLen   = len(b.buf)
+		startCount = b.hdr.ARCount
+	)
+
+	for j := i; j < len(p.queued); j++ {
+		cur := &p.queued[j]
+		if packed[j] || cur.priority != first.priority || cur.res.Header.Type != first.res.Header.Type ||
+			cur.res.Header.Class != first.res.Header.Class || !cur.res.Header.Name.Equal(&first.res.Header.Name) {
+			continue
+		}
+		packed[j] = true
+
+		if err == nil {
+			err = b.Resource(cur.res.Header, cur.res.Body)
+		}
+	}
+
+	if err != nil {
+		b.nb.removeNamesFromCompressionMap(b.headerStartOffset, startLen)
+		b.buf = b.buf[:startLen]
+		b.hdr.ARCount = startCount
+		if err == ErrTruncated {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
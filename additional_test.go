@@ -0,0 +1,153 @@
+package dnsmsg
+
+import "testing"
+
+func testAdditionalReferral(t *testing.T, sizeLimit int, p *AdditionalPacker) Msg {
+	t.Helper()
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.LimitMessageSize(sizeLimit)
+	q := Question{Name: MustParseName("www.sub.example.com"), Type: TypeA, Class: ClassIN}
+	if err := b.Question(q); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+	b.StartAnswers()
+	b.StartAuthorities()
+	nsHdr := ResourceHeader{Name: MustParseName("sub.example.com"), Class: ClassIN, TTL: 3600}
+	for _, ns := range []string{"ns1.sub.example.com", "ns.sibling.example.com"} {
+		if err := b.ResourceNS(nsHdr, ResourceNS{NS: MustParseName(ns)}); err != nil {
+			t.Fatalf("b.ResourceNS() unexpected error: %v", err)
+		}
+	}
+
+	if err := p.Pack(&b); err != nil {
+		t.Fatalf("p.Pack() unexpected error: %v", err)
+	}
+
+	msg := b.Bytes()
+	if len(msg) > sizeLimit {
+		t.Fatalf("message length: %v, want <= %v", len(msg), sizeLimit)
+	}
+	var m Msg
+	if err := m.Unpack(msg); err != nil {
+		t.Fatalf("m.Unpack() unexpected error: %v", err)
+	}
+	return m
+}
+
+func testAdditionalResource(name string, a byte) Resource {
+	return Resource{
+		Header: ResourceHeader{Name: MustParseName(name), Type: TypeA, Class: ClassIN, TTL: 3600},
+		Body:   ResourceA{A: [4]byte{192, 0, 2, a}},
+	}
+}
+
+func testAdditionalNames(m *Msg) []string {
+	var names []string
+	for _, res := range m.Additionals {
+		names = append(names, res.Header.Name.String()+"/"+res.Body.String())
+	}
+	return names
+}
+
+func TestAdditionalPacker(t *testing.T) {
+	var p AdditionalPacker
+	p.Add(AdditionalOther, testAdditionalResource("mail.example.com", 10))
+	p.Add(AdditionalOptionalGlue, testAdditionalResource("ns.sibling.example.com", 3))
+	p.Add(AdditionalRequiredGlue, testAdditionalResource("ns1.sub.example.com", 1))
+	p.Add(AdditionalOther, testAdditionalResource("mail2.example.com", 11))
+	p.Add(AdditionalRequiredGlue, testAdditionalResource("ns1.sub.example.com", 2))
+
+	var empty AdditionalPacker
+	base := testAdditionalReferral(t, 65535, &empty)
+	baseLength, err := base.Pack()
+	if err != nil {
+		t.Fatalf("base.Pack() unexpected error: %v", err)
+	}
+
+	// Names of all glue resources are compressed with a pointer to the NS resources,
+	// each of them takes 16 bytes. The mail.example.com resource takes 21 bytes
+	// (the example.com suffix is compressed).
+	const glueLength, otherLength = 16, 21
+
+	cases := []struct {
+		name      string
+		sizeLimit int
+		tc        bool
+		want      []string
+	}{
+		{
+			name:      "everything fits",
+			sizeLimit: 65535,
+			want: []string{
+				"ns1.sub.example.com./192.0.2.1",
+				"ns1.sub.example.com./192.0.2.2",
+				"ns.sibling.example.com./192.0.2.3",
+				"mail.example.com./192.0.2.10",
+				"mail2.example.com./192.0.2.11",
+			},
+		},
+		{
+			name:      "other additionals do not fit",
+			sizeLimit: len(baseLength) + 3*glueLength + otherLength - 1,
+			want: []string{
+				"ns1.sub.example.com./192.0.2.1",
+				"ns1.sub.example.com./192.0.2.2",
+				"ns.sibling.example.com./192.0.2.3",
+			},
+		},
+		{
+			name:      "optional glue does not fit",
+			sizeLimit: len(baseLength) + 3*glueLength - 1,
+			want: []string{
+				"ns1.sub.example.com./192.0.2.1",
+				"ns1.sub.example.com./192.0.2.2",
+			},
+		},
+		{
+			name:      "required glue RRset does not fit",
+			sizeLimit: len(baseLength) + 2*glueLength - 1,
+			tc:        true,
+			want: []string{
+				"ns.sibling.example.com./192.0.2.3",
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		m := testAdditionalReferral(t, tt.sizeLimit, &p)
+		if tc := m.Header.Flags.Bit(BitTC); tc != tt.tc {
+			t.Errorf("%v: TC bit: %v, want: %v", tt.name, tc, tt.tc)
+		}
+		got := testAdditionalNames(&m)
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: got additionals: %v, want: %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%v: got additionals: %v, want: %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestAdditionalPackerTruncationMode(t *testing.T) {
+	var p AdditionalPacker
+	p.Add(AdditionalOther, testAdditionalResource("mail.example.com", 10))
+
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.LimitMessageSize(headerLen + 10)
+	b.EnableTruncation()
+	b.StartAnswers()
+	b.StartAuthorities()
+	if err := p.Pack(&b); err != nil {
+		t.Fatalf("p.Pack() unexpected error: %v", err)
+	}
+	if hdr := b.Header(); hdr.Flags.Bit(BitTC) || hdr.ARCount != 0 {
+		t.Fatalf("unexpected header: %#v", hdr)
+	}
+	if !b.truncation {
+		t.Fatalf("truncation mode was not restored")
+	}
+}
@@ -0,0 +1,402 @@
+package dnsmsg
+
+import "errors"
+
+var (
+	errZoneClassMismatch = errors.New("resource class differs from the class of the zone SOA")
+	errCNAMEAndOtherData = errors.New("CNAME and other data at the same name")
+	errSingletonRRSet    = errors.New("SOA, CNAME or DNAME RRSet with multiple resources")
+)
+
+// maxAuthZoneRedirections is the maximum amount of CNAME and DNAME redirections
+// that are followed within the zone while answering a single question.
+const maxAuthZoneRedirections = 16
+
+// AuthoritativeZone answers questions with the data of a single zone loaded into memory,
+// as an authoritative server does (RFC 1034, Section 4.3.2). It handles referrals to
+// delegated zones (with glue), CNAME chains within the zone, wildcards (RFC 4592),
+// DNAME substitution (RFC 6672) and negative answers (RFC 2308).
+//
+// AuthoritativeZone is not modified after creation, so it is safe for concurrent use.
+type AuthoritativeZone struct {
+	origin Name
+	class  Class
+	soa    Resource
+	nodes  map[string]*authZoneNode
+}
+
+// authZoneNode is a single name in the zone. Empty non-terminals
+// are represented by nodes without any RRSets.
+type authZoneNode struct {
+	name   Name
+	rrsets []authZoneRRSet
+}
+
+type authZoneRRSet struct {
+	typ       Type
+	resources []Resource
+}
+
+func (n *authZoneNode) rrset(t Type) []Resource {
+	for i := range n.rrsets {
+		if n.rrsets[i].typ == t {
+			return n.rrsets[i].resources
+		}
+	}
+	return nil
+}
+
+func (n *authZoneNode) add(res Resource) {
+	for i := range n.rrsets {
+		if n.rrsets[i].typ == res.Header.Type {
+			n.rrsets[i].resources = append(n.rrsets[i].resources, res)
+			return
+		}
+	}
+	n.rrsets = append(n.rrsets, authZoneRRSet{typ: res.Header.Type, resources: []Resource{res}})
+}
+
+// NewAuthoritativeZone creates an AuthoritativeZone with the origin apex from resources.
+// All resources must be at or below the origin and have the same class as the SOA
+// resource at the origin, which is required.
+//
+// The resource data of the SOA, NS, CNAME and DNAME resources is expected to be of the
+// [ResourceSOA], [ResourceNS], [ResourceCNAME] and [ResourceDNAME] types (as returned by the
+// [ZoneParser]), resource data of these types in other forms (e.g. a [RawResource]) is ignored
+// while answering.
+func NewAuthoritativeZone(origin Name, resources []Resource) (*AuthoritativeZone, error) {
+	z := &AuthoritativeZone{
+		origin: origin,
+		nodes:  make(map[string]*authZoneNode),
+	}
+
+	found := false
+	for _, res := range resources {
+		if _, ok := res.Body.(ResourceSOA); ok && res.Header.Name.Equal(&origin) {
+			z.soa = res
+			z.soa.Header.Type = TypeSOA
+			z.class = res.Header.Class
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errMissingSOA
+	}
+
+	z.addNode(&origin)
+	for _, res := range resources {
+		if !res.Header.Name.isSubdomainOf(&origin) {
+			return nil, errNotInZone
+		}
+		if res.Header.Class != z.class {
+			return nil, errZoneClassMismatch
+		}
+		res.Header.Type = res.Body.ResourceType()
+		z.addNode(&res.Header.Name).add(res)
+	}
+
+	for _, n := range z.nodes {
+		for _, set := range n.rrsets {
+			switch set.typ {
+			case TypeSOA, TypeCNAME, TypeDNAME:
+				if len(set.resources) != 1 {
+					return nil, errSingletonRRSet
+				}
+			}
+			if set.typ != TypeCNAME && set.typ != TypeRRSIG && set.typ != TypeNSEC && n.rrset(TypeCNAME) != nil {
+				return nil, errCNAMEAndOtherData
+			}
+		}
+	}
+	return z, nil
+}
+
+// addNode returns the node of name, it creates it (with all
+// its missing ancestors up to the origin) when it does not exist.
+func (z *AuthoritativeZone) addNode(name *Name) *authZoneNode {
+	key := canonicalNameKey(name)
+	if n, ok := z.nodes[key]; ok {
+		return n
+	}
+	n := &authZoneNode{name: *name}
+	z.nodes[key] = n
+	if name.Length > z.origin.Length {
+		parent := name.parent()
+		z.addNode(&parent)
+	}
+	return n
+}
+
+func (z *AuthoritativeZone) node(name *Name) *authZoneNode {
+	return z.nodes[canonicalNameKey(name)]
+}
+
+// authZoneResponse is the response being created by the [AuthoritativeZone.Answer] method.
+type authZoneResponse struct {
+	rcode       RCode
+	aa          bool
+	answers     []Resource
+	authorities []Resource
+	additionals AdditionalPacker
+}
+
+// Answer appends the answer to the q question to b. The building section of b must be
+// set to questions (with q already appended), otherwise it panics. Answer changes the
+// building section of b, sets the AA bit and the RCode in the header of b.
+//
+// For questions not belonging to the zone, the RCode is set to [RCodeRefused] and
+// nothing is appended.
+//
+// When the answer or the authority section does not fit in the message (see [Builder.LimitMessageSize]),
+// the TC bit is set, as many resources as possible are appended, and the additional section is left
+// empty. Answer returns an error only when appending a resource failed for a reason other than [ErrTruncated].
+func (z *AuthoritativeZone) Answer(b *Builder, q Question) error {
+	if b.curSection != sectionQuestions {
+		b.panicInvalidSection()
+	}
+
+	flags := b.Header().Flags
+	if q.Class != z.class || !q.Name.isSubdomainOf(&z.origin) {
+		flags.SetRCode(RCodeRefused)
+		b.SetFlags(flags)
+		return nil
+	}
+
+	r := authZoneResponse{aa: true}
+	z.lookup(&r, q)
+
+	flags.SetBit(BitAA, r.aa)
+	flags.SetRCode(r.rcode)
+	b.SetFlags(flags)
+
+	b.StartAnswers()
+	for _, res := range r.answers {
+		if err := b.Resource(res.Header, res.Body); err != nil {
+			return authZoneTruncated(b, err)
+		}
+	}
+	b.StartAuthorities()
+	for _, res := range r.authorities {
+		if err := b.Resource(res.Header, res.Body); err != nil {
+			return authZoneTruncated(b, err)
+		}
+	}
+	return r.additionals.Pack(b)
+}
+
+func authZoneTruncated(b *Builder, err error) error {
+	if err == ErrTruncated {
+		b.hdr.Flags.SetBit(BitTC, true)
+		return nil
+	}
+	return err
+}
+
+// ServeDNS implements the [Handler] interface, it answers the request using the [AuthoritativeZone.Answer]
+// method. Requests with OpCodes other than QUERY are answered with the [RCodeNotImpl] RCode.
+func (z *AuthoritativeZone) ServeDNS(w *ResponseWriter, r *Request) {
+	if r.Header.Flags.OpCode() != 0 {
+		w.SetRCode(ExtendedRCode(RCodeNotImpl))
+		return
+	}
+	if err := z.Answer(w.Builder(), r.Question); err != nil {
+		w.SetRCode(ExtendedRCode(RCodeServerFail))
+	}
+}
+
+// lookup fills r with the answer to q, following CNAME and DNAME
+// redirections as long as their targets belong to the zone.
+func (z *AuthoritativeZone) lookup(r *authZoneResponse, q Question) {
+	visited := make([]Name, 0, 4)
+	qname := q.Name
+	for i := 0; i < maxAuthZoneRedirections; i++ {
+		for j := range visited {
+			if visited[j].Equal(&qname) {
+				return // CNAME loop.
+			}
+		}
+		visited = append(visited, qname)
+
+		next, redirected := z.lookupName(r, &qname, q.Type)
+		if !redirected || !next.isSubdomainOf(&z.origin) {
+			return
+		}
+		qname = next
+	}
+}
+
+// lookupName appends the answer to the qname and qtype question to r. When the answer is a
+// redirection (CNAME or DNAME), it returns the target name and sets redirected to true.
+func (z *AuthoritativeZone) lookupName(r *authZoneResponse, qname *Name, qtype Type) (next Name, redirected bool) {
+	// Names from qname up to the origin.
+	names := make([]Name, 0, 8)
+	for n := *qname; ; n = n.parent() {
+		names = append(names, n)
+		if n.Length == z.origin.Length {
+			break
+		}
+	}
+
+	// Walk down the tree, starting at the origin, until a delegation point,
+	// a DNAME (above qname), or the closest encloser of qname is found.
+	var encloser *authZoneNode
+	for i := len(names) - 1; i >= 0; i-- {
+		n := z.node(&names[i])
+		if n == nil {
+			break
+		}
+		encloser = n
+
+		// The DS RRSet belongs to the parent side of a delegation (RFC 4035, Section 3.1.4.1).
+		if i != len(names)-1 && !(i == 0 && qtype == TypeDS) {
+			if ns := n.rrset(TypeNS); ns != nil {
+				z.referral(r, n, ns)
+				return Name{}, false
+			}
+		}
+
+		if i != 0 {
+			if dname := n.rrset(TypeDNAME); dname != nil {
+				return z.substituteDNAME(r, qname, dname[0])
+			}
+		}
+
+		if i == 0 {
+			if len(n.rrsets) == 0 {
+				// Empty non-terminal.
+				z.negative(r, RCodeSuccess)
+				return Name{}, false
+			}
+			return z.answerNode(r, n, nil, qtype)
+		}
+	}
+
+	// RFC 4592, Section 4.3.3.
+	wildcard := Name{Name: [255]byte{1, '*'}, Length: 2}
+	if int(wildcard.Length)+int(encloser.name.Length) <= maxEncodedNameLen {
+		wildcard.Length += uint8(copy(wildcard.Name[2:], encloser.name.asSlice()))
+		if n := z.node(&wildcard); n != nil {
+			return z.answerNode(r, n, qname, qtype)
+		}
+	}
+
+	z.negative(r, RCodeNameError)
+	return Name{}, false
+}
+
+// answerNode appends the qtype RRSet of n to the answers, or the CNAME, when n has a CNAME
+// RRSet. When owner is not nil, the answers are synthesized from a wildcard with the owner name.
+func (z *AuthoritativeZone) answerNode(r *authZoneResponse, n *authZoneNode, owner *Name, qtype Type) (next Name, redirected bool) {
+	if rrset := n.rrset(qtype); rrset != nil {
+		r.addAnswers(rrset, owner)
+		for _, res := range rrset {
+			var target *Name
+			switch body := res.Body.(type) {
+			case ResourceNS:
+				target = &body.NS
+			case ResourceMX:
+				target = &body.MX
+			case ResourceSRV:
+				target = &body.Target
+			}
+			if target != nil {
+				z.addAddresses(r, AdditionalOther, target)
+			}
+		}
+		return Name{}, false
+	}
+
+	if cname := n.rrset(TypeCNAME); cname != nil {
+		r.addAnswers(cname, owner)
+		if body, ok := cname[0].Body.(ResourceCNAME); ok {
+			return body.CNAME, true
+		}
+		return Name{}, false
+	}
+
+	z.negative(r, RCodeSuccess)
+	return Name{}, false
+}
+
+func (r *authZoneResponse) addAnswers(rrset []Resource, owner *Name) {
+	for _, res := range rrset {
+		if owner != nil {
+			res.Header.Name = *owner
+		}
+		r.answers = append(r.answers, res)
+	}
+}
+
+// substituteDNAME appends the dname resource and a CNAME synthesized from it (RFC 6672, Section 3.1)
+// to the answers. It returns the target of the synthesized CNAME.
+func (z *AuthoritativeZone) substituteDNAME(r *authZoneResponse, qname *Name, dname Resource) (next Name, redirected bool) {
+	body, ok := dname.Body.(ResourceDNAME)
+	if !ok {
+		z.negative(r, RCodeNameError)
+		return Name{}, false
+	}
+	r.answers = append(r.answers, dname)
+
+	prefixLength := int(qname.Length) - int(dname.Header.Name.Length)
+	if prefixLength+int(body.DNAME.Length) > maxEncodedNameLen {
+		r.rcode = RCodeYXDomain
+		return Name{}, false
+	}
+	next.Length = uint8(copy(next.Name[:], qname.Name[:prefixLength]))
+	next.Length += uint8(copy(next.Name[next.Length:], body.DNAME.asSlice()))
+
+	r.answers = append(r.answers, Resource{
+		Header: ResourceHeader{Name: *qname, Type: TypeCNAME, Class: z.class, TTL: dname.Header.TTL},
+		Body:   ResourceCNAME{CNAME: next},
+	})
+	return next, true
+}
+
+// referral appends the ns RRSet of the n delegation point to the authorities and its glue
+// addresses to the additionals. Glue of name servers below the delegation point is required
+// for the referral, glue of other name servers in the zone is optional (RFC 9471).
+func (z *AuthoritativeZone) referral(r *authZoneResponse, n *authZoneNode, ns []Resource) {
+	// A referral at the end of a CNAME chain does not affect the AA bit,
+	// it applies to the first name in the answer section (RFC 1035, Section 4.1.1).
+	r.aa = len(r.answers) != 0
+	r.authorities = append(r.authorities, ns...)
+	for _, res := range ns {
+		body, ok := res.Body.(ResourceNS)
+		if !ok {
+			continue
+		}
+		priority := AdditionalOptionalGlue
+		if body.NS.isSubdomainOf(&n.name) {
+			priority = AdditionalRequiredGlue
+		}
+		z.addAddresses(r, priority, &body.NS)
+	}
+}
+
+// addAddresses queues the A and AAAA resources of name (when it belongs to the zone) to the additionals.
+func (z *AuthoritativeZone) addAddresses(r *authZoneResponse, priority AdditionalPriority, name *Name) {
+	if !name.isSubdomainOf(&z.origin) {
+		return
+	}
+	n := z.node(name)
+	if n == nil {
+		return
+	}
+	for _, t := range []Type{TypeA, TypeAAAA} {
+		for _, res := range n.rrset(t) {
+			r.additionals.Add(priority, res)
+		}
+	}
+}
+
+// negative sets the rcode and appends the SOA resource to the authorities, with the TTL
+// set to the minimum of the SOA TTL and the MINIMUM field (RFC 2308, Section 3).
+func (z *AuthoritativeZone) negative(r *authZoneResponse, rcode RCode) {
+	r.rcode = rcode
+	soa := z.soa
+	if minimum := soa.Body.(ResourceSOA).Minimum; minimum < soa.Header.TTL {
+		soa.Header.TTL = minimum
+	}
+	r.authorities = append(r.authorities, soa)
+}
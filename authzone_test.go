@@ -0,0 +1,336 @@
+package dnsmsg
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testAuthZone = `
+$ORIGIN example.com.
+$TTL 3600
+@	SOA	ns1 admin 1 3600 600 604800 300
+	NS	ns1
+	NS	ns.example.net.
+ns1	A	192.0.2.1
+www	A	192.0.2.10
+	AAAA	2001:db8::10
+alias	CNAME	www
+chain	CNAME	alias
+loop1	CNAME	loop2
+loop2	CNAME	loop1
+external	CNAME	www.example.net.
+mail	MX	10 mx
+mx	A	192.0.2.20
+*.wild	TXT	"wildcard"
+cname.wild	CNAME	*.wild
+a.b.ent	A	192.0.2.40
+sub	NS	ns1.sub
+	NS	ns.sibling
+	DS	60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118
+ns1.sub	A	192.0.2.50
+ns.sibling	A	192.0.2.51
+dname	600	DNAME	target
+x.target	A	192.0.2.60
+`
+
+func newTestAuthZone(t *testing.T, zone string) *AuthoritativeZone {
+	t.Helper()
+	resources, err := parseTestZone(t, zone, ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("parseTestZone() unexpected error: %v", err)
+	}
+	z, err := NewAuthoritativeZone(MustParseName("example.com"), resources)
+	if err != nil {
+		t.Fatalf("NewAuthoritativeZone() unexpected error: %v", err)
+	}
+	return z
+}
+
+func testAuthZoneAnswer(t *testing.T, z *AuthoritativeZone, q Question, sizeLimit int) Msg {
+	t.Helper()
+	b := StartBuilder(make([]byte, 0, 512), 1, 0)
+	b.LimitMessageSize(sizeLimit)
+	if err := b.Question(q); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+	if err := z.Answer(&b, q); err != nil {
+		t.Fatalf("z.Answer() unexpected error: %v", err)
+	}
+	var m Msg
+	if err := m.Unpack(b.Bytes()); err != nil {
+		t.Fatalf("m.Unpack() unexpected error: %v", err)
+	}
+	return m
+}
+
+func testAuthZoneResources(resources []Resource) []string {
+	var out []string
+	for _, res := range resources {
+		out = append(out, res.Header.Name.String()+" "+strconv.FormatUint(uint64(res.Header.TTL), 10)+" "+
+			res.Header.Type.String()+" "+res.Body.String())
+	}
+	return out
+}
+
+func TestAuthoritativeZoneAnswer(t *testing.T) {
+	z := newTestAuthZone(t, testAuthZone)
+
+	const negativeSOA = "example.com. 300 SOA ns1.example.com. admin.example.com. 1 3600 600 604800 300"
+
+	cases := []struct {
+		qname string
+		qtype Type
+
+		rcode       RCode
+		aa          bool
+		answers     []string
+		authorities []string
+		additionals []string
+	}{
+		{
+			qname: "www.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"www.example.com. 3600 A 192.0.2.10"},
+		},
+		{
+			qname: "WWW.Example.COM", qtype: TypeAAAA,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"www.example.com. 3600 AAAA 2001:db8::10"},
+		},
+		{
+			qname: "chain.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{
+				"chain.example.com. 3600 CNAME alias.example.com.",
+				"alias.example.com. 3600 CNAME www.example.com.",
+				"www.example.com. 3600 A 192.0.2.10",
+			},
+		},
+		{
+			qname: "alias.example.com", qtype: TypeCNAME,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"alias.example.com. 3600 CNAME www.example.com."},
+		},
+		{
+			qname: "loop1.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{
+				"loop1.example.com. 3600 CNAME loop2.example.com.",
+				"loop2.example.com. 3600 CNAME loop1.example.com.",
+			},
+		},
+		{
+			qname: "external.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"external.example.com. 3600 CNAME www.example.net."},
+		},
+		{
+			qname: "mail.example.com", qtype: TypeMX,
+			rcode: RCodeSuccess, aa: true,
+			answers:     []string{"mail.example.com. 3600 MX 10 mx.example.com."},
+			additionals: []string{"mx.example.com. 3600 A 192.0.2.20"},
+		},
+		{
+			qname: "example.com", qtype: TypeNS,
+			rcode: RCodeSuccess, aa: true,
+			answers:     []string{"example.com. 3600 NS ns1.example.com.", "example.com. 3600 NS ns.example.net."},
+			additionals: []string{"ns1.example.com. 3600 A 192.0.2.1"},
+		},
+		{
+			qname: "www.example.com", qtype: TypeMX,
+			rcode: RCodeSuccess, aa: true,
+			authorities: []string{negativeSOA},
+		},
+		{
+			qname: "ent.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			authorities: []string{negativeSOA},
+		},
+		{
+			qname: "nonexistent.example.com", qtype: TypeA,
+			rcode: RCodeNameError, aa: true,
+			authorities: []string{negativeSOA},
+		},
+		{
+			qname: "x.ent.example.com", qtype: TypeA,
+			rcode: RCodeNameError, aa: true,
+			authorities: []string{negativeSOA},
+		},
+		{
+			qname: "a.b.wild.example.com", qtype: TypeTXT,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"a.b.wild.example.com. 3600 TXT \"wildcard\""},
+		},
+		{
+			qname: "a.wild.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			authorities: []string{negativeSOA},
+		},
+		{
+			// cname.wild exists, so it is not synthesized from the wildcard.
+			qname: "cname.wild.example.com", qtype: TypeTXT,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"cname.wild.example.com. 3600 CNAME *.wild.example.com.", "*.wild.example.com. 3600 TXT \"wildcard\""},
+		},
+		{
+			qname: "host.sub.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: false,
+			authorities: []string{
+				"sub.example.com. 3600 NS ns1.sub.example.com.",
+				"sub.example.com. 3600 NS ns.sibling.example.com.",
+			},
+			additionals: []string{
+				"ns1.sub.example.com. 3600 A 192.0.2.50",
+				"ns.sibling.example.com. 3600 A 192.0.2.51",
+			},
+		},
+		{
+			qname: "sub.example.com", qtype: TypeNS,
+			rcode: RCodeSuccess, aa: false,
+			authorities: []string{
+				"sub.example.com. 3600 NS ns1.sub.example.com.",
+				"sub.example.com. 3600 NS ns.sibling.example.com.",
+			},
+			additionals: []string{
+				"ns1.sub.example.com. 3600 A 192.0.2.50",
+				"ns.sibling.example.com. 3600 A 192.0.2.51",
+			},
+		},
+		{
+			qname: "sub.example.com", qtype: TypeDS,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"sub.example.com. 3600 DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118"},
+		},
+		{
+			qname: "x.dname.example.com", qtype: TypeA,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{
+				"dname.example.com. 600 DNAME target.example.com.",
+				"x.dname.example.com. 600 CNAME x.target.example.com.",
+				"x.target.example.com. 3600 A 192.0.2.60",
+			},
+		},
+		{
+			qname: "y.dname.example.com", qtype: TypeA,
+			rcode: RCodeNameError, aa: true,
+			answers: []string{
+				"dname.example.com. 600 DNAME target.example.com.",
+				"y.dname.example.com. 600 CNAME y.target.example.com.",
+			},
+			authorities: []string{negativeSOA},
+		},
+		{
+			qname: "dname.example.com", qtype: TypeDNAME,
+			rcode: RCodeSuccess, aa: true,
+			answers: []string{"dname.example.com. 600 DNAME target.example.com."},
+		},
+		{
+			qname: "example.net", qtype: TypeA,
+			rcode: RCodeRefused, aa: false,
+		},
+	}
+
+	for _, tt := range cases {
+		m := testAuthZoneAnswer(t, z, Question{Name: MustParseName(tt.qname), Type: tt.qtype, Class: ClassIN}, 65535)
+		name := tt.qname + " " + tt.qtype.String()
+		if rcode := m.Header.Flags.RCode(); rcode != tt.rcode {
+			t.Errorf("%v: rcode: %v, want: %v", name, rcode, tt.rcode)
+		}
+		if aa := m.Header.Flags.Bit(BitAA); aa != tt.aa {
+			t.Errorf("%v: AA bit: %v, want: %v", name, aa, tt.aa)
+		}
+		if m.Header.Flags.Bit(BitTC) {
+			t.Errorf("%v: unexpected TC bit", name)
+		}
+
+		for _, section := range []struct {
+			name string
+			got  []string
+			want []string
+		}{
+			{"answers", testAuthZoneResources(m.Answers), tt.answers},
+			{"authorities", testAuthZoneResources(m.Authorities), tt.authorities},
+			{"additionals", testAuthZoneResources(m.Additionals), tt.additionals},
+		} {
+			if strings.Join(section.got, "\n") != strings.Join(section.want, "\n") {
+				t.Errorf("%v: unexpected %v:\n%v\nwant:\n%v", name, section.name,
+					strings.Join(section.got, "\n"), strings.Join(section.want, "\n"))
+			}
+		}
+	}
+}
+
+func TestAuthoritativeZoneDNAMETooLong(t *testing.T) {
+	label := strings.Repeat("a", 60)
+	z := newTestAuthZone(t, `
+$ORIGIN example.com.
+$TTL 3600
+@	SOA	ns1 admin 1 3600 600 604800 300
+d	DNAME	`+label+"."+label+"."+label+"."+label+".\n")
+
+	m := testAuthZoneAnswer(t, z, Question{Name: MustParseName(label + ".d.example.com"), Type: TypeA, Class: ClassIN}, 65535)
+	if rcode := m.Header.Flags.RCode(); rcode != RCodeYXDomain {
+		t.Errorf("rcode: %v, want: %v", rcode, RCodeYXDomain)
+	}
+	if len(m.Answers) != 1 || m.Answers[0].Header.Type != TypeDNAME {
+		t.Errorf("unexpected answers: %v", testAuthZoneResources(m.Answers))
+	}
+}
+
+func TestAuthoritativeZoneTruncation(t *testing.T) {
+	z := newTestAuthZone(t, testAuthZone)
+	q := Question{Name: MustParseName("chain.example.com"), Type: TypeA, Class: ClassIN}
+	full := testAuthZoneAnswer(t, z, q, 65535)
+	packed, err := full.Pack()
+	if err != nil {
+		t.Fatalf("full.Pack() unexpected error: %v", err)
+	}
+
+	m := testAuthZoneAnswer(t, z, q, len(packed)-1)
+	if !m.Header.Flags.Bit(BitTC) {
+		t.Errorf("TC bit is not set")
+	}
+	if len(m.Answers) != 2 {
+		t.Errorf("unexpected answers: %v", testAuthZoneResources(m.Answers))
+	}
+}
+
+func TestNewAuthoritativeZoneErrors(t *testing.T) {
+	const soa = "$ORIGIN example.com.\n$TTL 3600\n@ SOA ns1 admin 1 3600 600 604800 300\n"
+	for _, tt := range []struct {
+		name string
+		zone string
+		err  error
+	}{
+		{"missing SOA", "$ORIGIN example.com.\n$TTL 3600\nwww A 192.0.2.1\n", errMissingSOA},
+		{"not in zone", soa + "www.example.net. A 192.0.2.1\n", errNotInZone},
+		{"class mismatch", soa + "www CLASS3 A 192.0.2.1\n", errZoneClassMismatch},
+		{"CNAME and other data", soa + "www CNAME @\nwww A 192.0.2.1\n", errCNAMEAndOtherData},
+		{"multiple CNAMEs", soa + "www CNAME @\nwww CNAME ns1\n", errSingletonRRSet},
+	} {
+		resources, err := parseTestZone(t, tt.zone, ZoneParserConfig{})
+		if err != nil {
+			t.Fatalf("%v: parseTestZone() unexpected error: %v", tt.name, err)
+		}
+		if _, err := NewAuthoritativeZone(MustParseName("example.com"), resources); err != tt.err {
+			t.Errorf("%v: NewAuthoritativeZone() unexpected error: %v, want: %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestAuthoritativeZoneServeDNS(t *testing.T) {
+	s := Server{Handler: newTestAuthZone(t, testAuthZone)}
+	var w ResponseWriter
+
+	// testServerQuery uses the OpCode 2.
+	r := parseTestServerResponse(t, s.handle(&w, &serverJob{msg: testServerQuery(t, 1, nil), network: "udp"}))
+	if r.hdr.Flags.RCode() != RCodeNotImpl {
+		t.Errorf("rcode: %v, want: %v", r.hdr.Flags.RCode(), RCodeNotImpl)
+	}
+
+	r = parseTestServerResponse(t, s.handle(&w, &serverJob{msg: testClientQuery(t, 1, "www.example.com"), network: "udp"}))
+	if r.hdr.Flags.RCode() != RCodeSuccess || !r.hdr.Flags.Bit(BitAA) || r.hdr.ANCount != 1 {
+		t.Errorf("unexpected response header: %#v", r.hdr)
+	}
+}
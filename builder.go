@@ -39,8 +39,8 @@ const (
 // use of the [Builder.Question] method.
 // After changing the building section (using one of the Start* methods described above) the
 // resource building methods: [Builder.ResourceA], [Builder.ResourceAAAA], [Builder.ResourceNS], [Builder.ResourceCNAME],
-// [Builder.ResourceSOA], [Builder.ResourcePTR], [Builder.ResourceMX], [Builder.RawResourceTXT], [Builder.ResourceTXT]
-// or [Builder.RDBuilder] can be used to append DNS resources.
+// [Builder.ResourceSOA], [Builder.ResourcePTR], [Builder.ResourceMX], [Builder.RawResourceTXT], [Builder.ResourceTXT],
+// [Builder.ResourceSRV], [Builder.ResourceCAA] or [Builder.RDBuilder] can be used to append DNS resources.
 //
 // The zero value of this type shouldn't be used.
 type Builder struct {
@@ -55,6 +55,17 @@ type Builder struct {
 
 	curSection section
 	hdr        Header
+
+	// optReserve is the amount of bytes reserved for the OPT resource, see [Builder.ReserveOPT].
+	optReserve int
+
+	// truncation is set by [Builder.EnableTruncation], when set resourceOffsets
+	// contains the offsets of resources in the current section.
+	truncation      bool
+	resourceOffsets []int
+
+	// stream is set when the Builder was created by [StartStreamBuilder].
+	stream bool
 }
 
 // StartBuilder creates a new DNS builder.
@@ -87,15 +98,106 @@ func (b *Builder) panicInvalidSection() {
 //
 // Note: The message size will not be reduced if it is already larger than the specified limit.
 func (b *Builder) LimitMessageSize(size int) {
-	b.maxBufSize = b.headerStartOffset + size
+	b.maxBufSize = b.headerStartOffset + size - b.optReserve
+}
+
+// ReserveOPT reserves length bytes of the message size limit (see [Builder.LimitMessageSize])
+// for the OPT resource, so that the OPT resource can be appended even after all other resources
+// were truncated (ErrTruncated). All resources other than OPT cannot use the reserved space.
+//
+// The length should be equal to [EDNS0HeaderEncodingLength] plus the encoding lengths of
+// all EDNS(0) options that are going to be included in the OPT resource.
+func (b *Builder) ReserveOPT(length int) {
+	b.maxBufSize += b.optReserve - length
+	b.optReserve = length
+}
+
+// EnableTruncation enables the truncation mode of the Builder.
+//
+// In the truncation mode, when appending a resource fails with [ErrTruncated], the Builder sets the
+// TC bit in the header flags and removes all resources of the partially appended RRset (the resources
+// directly preceding the truncated resource in the same section, with the same name, type and class),
+// because an incomplete RRset must not be sent (RFC 2181, Section 9). The [ErrTruncated] error is still
+// returned. This also applies to resources built by the [RDBuilder] and [ResourceSVCBBuilder], when
+// they are removed with the Remove method after one of their methods returned [ErrTruncated].
+//
+// Use [Builder.ReserveOPT] to make sure that the OPT resource fits in the message.
+func (b *Builder) EnableTruncation() {
+	b.truncation = true
 }
 
-// Reset restes the DNS builder.
+// trackResource must be called after a resource header was appended at the offset.
+func (b *Builder) trackResource(offset int) {
+	if !b.truncation {
+		return
+	}
+	b.purgeResourceOffsets(offset)
+	b.resourceOffsets = append(b.resourceOffsets, offset)
+}
+
+// purgeResourceOffsets removes the offsets of removed resources (at or after the end offset).
+func (b *Builder) purgeResourceOffsets(end int) {
+	for len(b.resourceOffsets) != 0 && b.resourceOffsets[len(b.resourceOffsets)-1] >= end {
+		b.resourceOffsets = b.resourceOffsets[:len(b.resourceOffsets)-1]
+	}
+}
+
+// truncated must be called after appending a resource of the hdr type failed
+// with err and the resource was removed from the message.
+func (b *Builder) truncated(hdr *ResourceHeader, err error) {
+	if !b.truncation || err != ErrTruncated {
+		return
+	}
+	b.hdr.Flags.SetBit(BitTC, true)
+	b.purgeResourceOffsets(len(b.buf))
+
+	start := len(b.resourceOffsets)
+	for start > 0 && b.sameRRSet(b.resourceOffsets[start-1], hdr) {
+		start--
+	}
+	if start == len(b.resourceOffsets) {
+		return
+	}
+
+	removeOffset := b.resourceOffsets[start]
+	b.nb.removeNamesFromCompressionMap(b.headerStartOffset, removeOffset)
+	b.buf = b.buf[:removeOffset]
+	for i := start; i < len(b.resourceOffsets); i++ {
+		b.decResurceSection()
+	}
+	b.resourceOffsets = b.resourceOffsets[:start]
+}
+
+// sameRRSet reports whether the resource at offset belongs to the same RRset as hdr.
+func (b *Builder) sameRRSet(offset int, hdr *ResourceHeader) bool {
+	other := b.resourceHeaderAt(offset)
+	return other.Type == hdr.Type && other.Class == hdr.Class && other.Name.Equal(&hdr.Name)
+}
+
+// resourceHeaderAt returns the name, type and class of the resource at offset.
+func (b *Builder) resourceHeaderAt(offset int) ResourceHeader {
+	var hdr ResourceHeader
+	n, _ := hdr.Name.unpack(b.buf[b.headerStartOffset:], offset-b.headerStartOffset)
+	offset += int(n)
+	hdr.Type = Type(unpackUint16(b.buf[offset:]))
+	hdr.Class = Class(unpackUint16(b.buf[offset+2:]))
+	return hdr
+}
+
+// Reset resets the DNS builder.
 // The message is going to be appended to the provided byte slice (buf).
+//
+// A Builder created by [StartStreamBuilder] is reset to a Builder returned by [StartStreamBuilder].
+// The message size limit (see [Builder.LimitMessageSize]), the space reserved by [Builder.ReserveOPT]
+// and the truncation mode (see [Builder.EnableTruncation]) are not preserved.
 func (b *Builder) Reset(buf []byte, id uint16, flags Flags) {
 	nb := b.nb
 	nb.reset()
-	*b = StartBuilder(buf, id, flags)
+	if b.stream {
+		*b = StartStreamBuilder(buf, id, flags)
+	} else {
+		*b = StartBuilder(buf, id, flags)
+	}
 	b.nb = nb
 }
 
@@ -144,6 +246,7 @@ func (b *Builder) StartAnswers() {
 		b.panicInvalidSection()
 	}
 	b.curSection = sectionAnswers
+	b.resourceOffsets = b.resourceOffsets[:0]
 }
 
 // StartAuthorities changes the building section from answers to authorities.
@@ -154,6 +257,7 @@ func (b *Builder) StartAuthorities() {
 		b.panicInvalidSection()
 	}
 	b.curSection = sectionAuthorities
+	b.resourceOffsets = b.resourceOffsets[:0]
 }
 
 // StartAuthorities changes the building section from authorities to additionals.
@@ -164,6 +268,7 @@ func (b *Builder) StartAdditionals() {
 		b.panicInvalidSection()
 	}
 	b.curSection = sectionAdditionals
+	b.resourceOffsets = b.resourceOffsets[:0]
 }
 
 var errResourceCountLimitReached = errors.New("maximum amount of DNS resources/questions reached")
@@ -264,8 +369,7 @@ func (b *Builder) ResourceNS(hdr ResourceHeader, ns ResourceNS) error {
 	}
 	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, ns.NS.asSlice(), true)
 	if err != nil {
-		b.removeResourceHeader(hdrOffset)
-		return err
+		return b.removeResourceHeader(hdrOffset, err)
 	}
 	f.fixup(b)
 	return nil
@@ -283,8 +387,7 @@ func (b *Builder) ResourceCNAME(hdr ResourceHeader, cname ResourceCNAME) error {
 	}
 	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, cname.CNAME.asSlice(), true)
 	if err != nil {
-		b.removeResourceHeader(hdrOffset)
-		return err
+		return b.removeResourceHeader(hdrOffset, err)
 	}
 	f.fixup(b)
 	return nil
@@ -303,14 +406,12 @@ func (b *Builder) ResourceSOA(hdr ResourceHeader, soa ResourceSOA) error {
 
 	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, soa.NS.asSlice(), true)
 	if err != nil {
-		b.removeResourceHeader(hdrOffset)
-		return err
+		return b.removeResourceHeader(hdrOffset, err)
 	}
 
 	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize-20, b.headerStartOffset, soa.Mbox.asSlice(), true)
 	if err != nil {
-		b.removeResourceHeader(hdrOffset)
-		return err
+		return b.removeResourceHeader(hdrOffset, err)
 	}
 
 	b.buf = appendUint32(b.buf, soa.Serial)
@@ -334,9 +435,26 @@ func (b *Builder) ResourcePTR(hdr ResourceHeader, ptr ResourcePTR) error {
 	}
 	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, ptr.PTR.asSlice(), true)
 	if err != nil {
-		b.removeResourceHeader(hdrOffset)
+		return b.removeResourceHeader(hdrOffset, err)
+	}
+	f.fixup(b)
+	return nil
+}
+
+// ResourceDNAME appends a single DNAME resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceDNAME(hdr ResourceHeader, dname ResourceDNAME) error {
+	hdr.Type = TypeDNAME
+	f, hdrOffset, err := b.appendHeaderWithLengthFixup(hdr, b.maxBufSize)
+	if err != nil {
 		return err
 	}
+	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, dname.DNAME.asSlice(), false)
+	if err != nil {
+		return b.removeResourceHeader(hdrOffset, err)
+	}
 	f.fixup(b)
 	return nil
 }
@@ -354,13 +472,63 @@ func (b *Builder) ResourceMX(hdr ResourceHeader, mx ResourceMX) error {
 	b.buf = appendUint16(b.buf, mx.Pref)
 	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, mx.MX.asSlice(), true)
 	if err != nil {
-		b.removeResourceHeader(hdrOffset)
+		return b.removeResourceHeader(hdrOffset, err)
+	}
+	f.fixup(b)
+	return nil
+}
+
+// ResourceSRV appends a single SRV resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The Target name is never compressed, as required by RFC 2782.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceSRV(hdr ResourceHeader, srv ResourceSRV) error {
+	hdr.Type = TypeSRV
+	f, hdrOffset, err := b.appendHeaderWithLengthFixup(hdr, b.maxBufSize-6)
+	if err != nil {
 		return err
 	}
+	b.buf = appendUint16(b.buf, srv.Priority)
+	b.buf = appendUint16(b.buf, srv.Weight)
+	b.buf = appendUint16(b.buf, srv.Port)
+	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, srv.Target.asSlice(), false)
+	if err != nil {
+		return b.removeResourceHeader(hdrOffset, err)
+	}
 	f.fixup(b)
 	return nil
 }
 
+var errInvalidCAATag = errors.New("invalid caa tag")
+
+// ResourceCAA appends a single CAA resource.
+// It errors when the amount of resources in the current section is equal to 65535,
+// or when the Tag is not a 1 to 15 characters long alphanumeric string.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceCAA(hdr ResourceHeader, caa ResourceCAA) error {
+	hdr.Type = TypeCAA
+	if !isValidCAATag(caa.Tag) {
+		return errInvalidCAATag
+	}
+
+	length := 2 + len(caa.Tag) + len(caa.Value)
+	if length > math.MaxUint16 {
+		return errResourceTooLong
+	}
+
+	hdr.Length = uint16(length)
+	if err := b.appendHeader(hdr, b.maxBufSize-length); err != nil {
+		return err
+	}
+	b.buf = append(b.buf, caa.Flags, uint8(len(caa.Tag)))
+	b.buf = append(b.buf, caa.Tag...)
+	b.buf = append(b.buf, caa.Value...)
+	return nil
+}
+
 var errInvalidRawTXTResource = errors.New("invalid raw txt resource")
 
 // RawResourceTXT appends a single TXT resource.
@@ -424,11 +592,14 @@ func (b *Builder) appendHeader(hdr ResourceHeader, maxBufSize int) error {
 		return err
 	}
 	var err error
+	nameOffset := len(b.buf)
 	b.buf, err = b.nb.appendName(b.buf, maxBufSize-10, b.headerStartOffset, hdr.Name.asSlice(), true)
 	if err != nil {
 		b.decResurceSection()
+		b.truncated(&hdr, err)
 		return err
 	}
+	b.trackResource(nameOffset)
 	b.buf = appendUint16(b.buf, uint16(hdr.Type))
 	b.buf = appendUint16(b.buf, uint16(hdr.Class))
 	b.buf = appendUint32(b.buf, hdr.TTL)
@@ -459,8 +630,10 @@ func (b *Builder) appendHeaderWithLengthFixup(hdr ResourceHeader, maxBufSize int
 	b.buf, err = b.nb.appendName(b.buf, maxBufSize-10, b.headerStartOffset, hdr.Name.asSlice(), true)
 	if err != nil {
 		b.decResurceSection()
+		b.truncated(&hdr, err)
 		return 0, 0, err
 	}
+	b.trackResource(nameOffset)
 	b.buf = appendUint16(b.buf, uint16(hdr.Type))
 	b.buf = appendUint16(b.buf, uint16(hdr.Class))
 	b.buf = appendUint32(b.buf, hdr.TTL)
@@ -489,8 +662,10 @@ func (b *Builder) appendHeaderWithLengthFixupNoInc(hdr ResourceHeader, maxBufSiz
 	nameOffset := len(b.buf)
 	b.buf, err = b.nb.appendName(b.buf, maxBufSize-10, b.headerStartOffset, hdr.Name.asSlice(), true)
 	if err != nil {
+		b.truncated(&hdr, err)
 		return 0, 0, nil, err
 	}
+	b.trackResource(nameOffset)
 	b.buf = appendUint16(b.buf, uint16(hdr.Type))
 	b.buf = appendUint16(b.buf, uint16(hdr.Class))
 	b.buf = appendUint32(b.buf, hdr.TTL)
@@ -498,10 +673,18 @@ func (b *Builder) appendHeaderWithLengthFixupNoInc(hdr ResourceHeader, maxBufSiz
 	return headerLengthFixup(len(b.buf)), nameOffset, count, nil
 }
 
-func (b *Builder) removeResourceHeader(headerOffset int) {
+// removeResourceHeader removes the resource at headerOffset, that failed to be appended with err.
+// It returns err.
+func (b *Builder) removeResourceHeader(headerOffset int, err error) error {
+	var hdr ResourceHeader
+	if b.truncation {
+		hdr = b.resourceHeaderAt(headerOffset)
+	}
 	b.nb.removeNamesFromCompressionMap(b.headerStartOffset, headerOffset)
 	b.buf = b.buf[:headerOffset]
 	b.decResurceSection()
+	b.truncated(&hdr, err)
+	return err
 }
 
 // RDBuilder craeates a new [RDBuilder], used for building custom resource data.
@@ -539,6 +722,9 @@ type RDBuilder struct {
 	count     *uint16
 	fixup     headerLengthFixup
 	hdrOffset int
+
+	// truncated is set when any of the methods returned ErrTruncated.
+	truncated bool
 }
 
 var errResourceTooLong = errors.New("too long resource")
@@ -568,8 +754,12 @@ func (b *RDBuilder) End() {
 func (b *RDBuilder) Remove() {
 	b.b.fakeBufSize = math.MaxInt
 	b.b.curSection &= ^sectionDetachedMask
+	hdr := b.b.resourceHeaderAt(b.hdrOffset)
 	b.b.nb.removeNamesFromCompressionMap(b.b.headerStartOffset, b.hdrOffset)
 	b.b.buf = b.b.buf[:b.hdrOffset]
+	if b.truncated {
+		b.b.truncated(&hdr, ErrTruncated)
+	}
 	b.b = nil
 }
 
@@ -584,6 +774,9 @@ func (b *RDBuilder) Name(name Name, compress bool) error {
 	var err error
 	b.b.buf, err = b.b.nb.appendName(b.b.buf, b.b.maxBufSize, b.b.headerStartOffset, name.asSlice(), compress)
 	if err != nil {
+		if err == ErrTruncated {
+			b.truncated = true
+		}
 		return err
 	}
 
@@ -606,6 +799,7 @@ func (b *RDBuilder) Bytes(raw []byte) error {
 		return errResourceTooLong
 	}
 	if len(b.b.buf)+len(raw) > b.b.maxBufSize {
+		b.truncated = true
 		return ErrTruncated
 	}
 	b.b.buf = append(b.b.buf, raw...)
@@ -623,6 +817,7 @@ func (b *RDBuilder) Uint8(val uint8) error {
 		return errResourceTooLong
 	}
 	if len(b.b.buf)+1 > b.b.maxBufSize {
+		b.truncated = true
 		return ErrTruncated
 	}
 	b.b.buf = append(b.b.buf, val)
@@ -640,6 +835,7 @@ func (b *RDBuilder) Uint16(val uint16) error {
 		return errResourceTooLong
 	}
 	if len(b.b.buf)+2 > b.b.maxBufSize {
+		b.truncated = true
 		return ErrTruncated
 	}
 	b.b.buf = appendUint16(b.b.buf, val)
@@ -657,6 +853,7 @@ func (b *RDBuilder) Uint32(val uint32) error {
 		return errResourceTooLong
 	}
 	if len(b.b.buf)+4 > b.b.maxBufSize {
+		b.truncated = true
 		return ErrTruncated
 	}
 	b.b.buf = appendUint32(b.b.buf, val)
@@ -674,6 +871,7 @@ func (b *RDBuilder) Uint64(val uint64) error {
 		return errResourceTooLong
 	}
 	if len(b.b.buf)+8 > b.b.maxBufSize {
+		b.truncated = true
 		return ErrTruncated
 	}
 	b.b.buf = appendUint64(b.b.buf, val)
@@ -805,7 +1003,7 @@ func (m *nameBuilderState) appendName(msg []byte, msgSizeLimit, headerStartOffse
 					rawNameIndex := i
 					for {
 						if msg[msgNameIndex]&0xC0 == 0xC0 {
-							msgNameIndex = int(msg[msgNameIndex]^0xC0)<<8 | int(msg[msgNameIndex+1])
+							msgNameIndex = int(msg[msgNameIndex]^0xC0)<<8 | int(msg[msgNameIndex+1]) + headerStartOffset
 						}
 
 						labelLength := int(msg[msgNameIndex])
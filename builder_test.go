@@ -362,6 +362,24 @@ func TestAppendName(t *testing.T) {
 				1, 'w', 0xC0, 17,
 			),
 		},
+		{
+			name: "compressed name with headerStartOffset",
+			build: func() []byte {
+				b := nameBuilderState{}
+				headerStartOffset := 2
+				buf := make([]byte, headerStartOffset+headerLen)
+				buf, _ = b.appendName(buf, math.MaxInt, headerStartOffset, nameAsSlice("example.com."), true)
+				buf, _ = b.appendName(buf, math.MaxInt, headerStartOffset, nameAsSlice("mail.example.com."), true)
+				buf, _ = b.appendName(buf, math.MaxInt, headerStartOffset, nameAsSlice("mail.example.com."), true)
+				return buf
+			},
+			expect: append(
+				make([]byte, 2+headerLen),
+				7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+				4, 'm', 'a', 'i', 'l', 0xC0, 12,
+				0xC0, 25,
+			),
+		},
 	}
 
 	for _, tt := range cases {
@@ -773,6 +791,8 @@ func TestBuilder(t *testing.T) {
 		}
 		resourceCNAME = ResourceCNAME{CNAME: MustParseName("www.example.com")}
 		resourceMX    = ResourceMX{Pref: 54831, MX: MustParseName("smtp.example.com")}
+		resourceSRV   = ResourceSRV{Priority: 10, Weight: 60, Port: 5060, Target: MustParseName("sip.example.com")}
+		resourceCAA   = ResourceCAA{Flags: 128, Tag: []byte("issue"), Value: []byte("ca.example.net")}
 		resourceOPT   = ResourceOPT{Options: []EDNS0Option{
 			&EDNS0ClientSubnet{Family: AddressFamilyIPv4, SourcePrefixLength: 2, ScopePrefixLength: 3, Address: []byte{192, 0, 2, 1}},
 			&EDNS0Cookie{
@@ -836,6 +856,16 @@ func TestBuilder(t *testing.T) {
 		}
 		testAfterAppend(sectionName)
 
+		if err := b.ResourceSRV(rhdr, resourceSRV); err != nil {
+			t.Fatalf("%v section, b.ResourceSRV() unexpected error: %v", sectionName, err)
+		}
+		testAfterAppend(sectionName)
+
+		if err := b.ResourceCAA(rhdr, resourceCAA); err != nil {
+			t.Fatalf("%v section, b.ResourceCAA() unexpected error: %v", sectionName, err)
+		}
+		testAfterAppend(sectionName)
+
 		if err := b.ResourceOPT(rhdr, resourceOPT); err != nil {
 			t.Fatalf("%v section, b.ResourceMX() unexpected error: %v", sectionName, err)
 		}
@@ -973,6 +1003,20 @@ func TestBuilder(t *testing.T) {
 		}
 		equalRData(t, "p.ResourceMX()", resourceMX, resMX)
 
+		parseResourceHeader(curSectionName, TypeSRV, ClassIN, 3600)
+		resSRV, err := p.ResourceSRV()
+		if err != nil {
+			t.Fatalf("%v section, p.ResourceSRV(): unexpected error: %v", curSectionName, err)
+		}
+		equalRData(t, "p.ResourceSRV()", resourceSRV, resSRV)
+
+		parseResourceHeader(curSectionName, TypeCAA, ClassIN, 3600)
+		resCAA, err := p.ResourceCAA()
+		if err != nil {
+			t.Fatalf("%v section, p.ResourceCAA(): unexpected error: %v", curSectionName, err)
+		}
+		equalRData(t, "p.ResourceCAA()", resourceCAA, resCAA)
+
 		parseResourceHeader(curSectionName, TypeOPT, ClassIN, 3600)
 		resOPT, err := p.ResourceOPT()
 		if err != nil {
@@ -1787,6 +1831,30 @@ func FuzzBuilder(f *testing.F) {
 					if debugFuzz {
 						t.Logf("b.ResourceMX(%#v, %#v) = %v", hdr, res, err)
 					}
+				case 10:
+					res := ResourceSRV{
+						Priority: r.uint16(),
+						Weight:   r.uint16(),
+						Port:     r.uint16(),
+						Target:   r.rawName(),
+					}
+					err = b.ResourceSRV(hdr, res)
+					if debugFuzz {
+						t.Logf("b.ResourceSRV(%#v, %#v) = %v", hdr, res, err)
+					}
+				case 11:
+					res := ResourceCAA{
+						Flags: r.uint8(),
+						Tag:   r.arbitraryAmountOfBytes(),
+						Value: r.arbitraryAmountOfBytes(),
+					}
+					err = b.ResourceCAA(hdr, res)
+					if debugFuzz {
+						t.Logf("b.ResourceCAA(%#v, %#v) = %v", hdr, res, err)
+					}
+					if err == errInvalidCAATag || err == errResourceTooLong {
+						err = nil
+					}
 				case 9:
 					res := ResourceOPT{}
 					for r.bool() {
@@ -1895,6 +1963,10 @@ func FuzzBuilder(f *testing.F) {
 					_, err = p.ResourceMX()
 				case TypeTXT:
 					_, err = p.RawResourceTXT()
+				case TypeSRV:
+					_, err = p.ResourceSRV()
+				case TypeCAA:
+					_, err = p.ResourceCAA()
 				case TypeOPT:
 					_, err = p.ResourceOPT()
 				default:
@@ -1908,3 +1980,229 @@ func FuzzBuilder(f *testing.F) {
 		}
 	})
 }
+
+func TestBuilderResourceSRVTargetNotCompressed(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+
+	hdr := ResourceHeader{
+		Name:  MustParseName("_sip._udp.test"),
+		Class: ClassIN,
+		TTL:   3600,
+	}
+	if err := b.ResourceSRV(hdr, ResourceSRV{Priority: 1, Weight: 2, Port: 5060, Target: MustParseName("example.com")}); err != nil {
+		t.Fatalf("b.ResourceSRV() unexpected error: %v", err)
+	}
+
+	expect := []byte{0, 1, 0, 2, 0x13, 0xC4, 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}
+	msg := b.Bytes()
+	if rdata := msg[len(msg)-len(expect):]; !bytes.Equal(rdata, expect) {
+		t.Fatalf("b.ResourceSRV() appended resource data: %v, want: %v", rdata, expect)
+	}
+
+	// Names after the target can still be compressed with the target.
+	if err := b.ResourceCNAME(hdr, ResourceCNAME{CNAME: MustParseName("www.example.com")}); err != nil {
+		t.Fatalf("b.ResourceCNAME() unexpected error: %v", err)
+	}
+
+	expect = []byte{3, 'w', 'w', 'w', 0xC0, byte(len(msg) - 13)}
+	msg = b.Bytes()
+	if rdata := msg[len(msg)-len(expect):]; !bytes.Equal(rdata, expect) {
+		t.Fatalf("b.ResourceCNAME() appended resource data: %v, want: %v", rdata, expect)
+	}
+}
+
+func TestBuilderResourceCAA(t *testing.T) {
+	hdr := ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Class: ClassIN,
+		TTL:   3600,
+	}
+
+	cases := []struct {
+		tag   string
+		valid bool
+	}{
+		{tag: "issue", valid: true},
+		{tag: "issuewild", valid: true},
+		{tag: "iodef", valid: true},
+		{tag: "Tag0123456789ab", valid: true},
+		{tag: "", valid: false},
+		{tag: "Tag0123456789abc", valid: false},
+		{tag: "issue-wild", valid: false},
+		{tag: "issue wild", valid: false},
+		{tag: "\xffissue", valid: false},
+	}
+
+	for _, tt := range cases {
+		b := StartBuilder(make([]byte, 0, 512), 0, 0)
+		b.StartAnswers()
+
+		caa := ResourceCAA{Tag: []byte(tt.tag), Value: []byte("ca.example.net")}
+		err := b.ResourceCAA(hdr, caa)
+		if tt.valid && err != nil {
+			t.Errorf("b.ResourceCAA() with tag %q unexpected error: %v", tt.tag, err)
+		}
+		if !tt.valid && err != errInvalidCAATag {
+			t.Errorf("b.ResourceCAA() with tag %q unexpected error: %v, want: %v", tt.tag, err, errInvalidCAATag)
+		}
+		if !tt.valid {
+			continue
+		}
+
+		p, _, err := Parse(b.Bytes())
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		if err := p.StartAnswers(); err != nil {
+			t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+		}
+		if _, err := p.ResourceHeader(); err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		res, err := p.ResourceCAA()
+		if err != nil {
+			t.Fatalf("p.ResourceCAA() unexpected error: %v", err)
+		}
+		equalRData(t, "p.ResourceCAA()", caa, res)
+	}
+}
+
+func TestResourceCAAIssuerCritical(t *testing.T) {
+	caa := ResourceCAA{Flags: 0b00010001}
+	if caa.IssuerCritical() {
+		t.Fatalf("caa.IssuerCritical() = true, want: false")
+	}
+	caa.SetIssuerCritical(true)
+	if !caa.IssuerCritical() || caa.Flags != 0b10010001 {
+		t.Fatalf("caa.SetIssuerCritical(true): caa.IssuerCritical() = %v, caa.Flags = %08b", caa.IssuerCritical(), caa.Flags)
+	}
+	caa.SetIssuerCritical(false)
+	if caa.IssuerCritical() || caa.Flags != 0b00010001 {
+		t.Fatalf("caa.SetIssuerCritical(false): caa.IssuerCritical() = %v, caa.Flags = %08b", caa.IssuerCritical(), caa.Flags)
+	}
+}
+
+func TestBuilderTruncation(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.LimitMessageSize(200)
+	b.ReserveOPT(EDNS0HeaderEncodingLength)
+	b.EnableTruncation()
+
+	q := Question{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN}
+	if err := b.Question(q); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+	b.StartAnswers()
+
+	a := ResourceHeader{Name: q.Name, Type: TypeA, Class: ClassIN, TTL: 60}
+	if err := b.ResourceA(a, ResourceA{A: [4]byte{192, 0, 2, 1}}); err != nil {
+		t.Fatalf("b.ResourceA() unexpected error: %v", err)
+	}
+	if err := b.ResourceA(a, ResourceA{A: [4]byte{192, 0, 2, 2}}); err != nil {
+		t.Fatalf("b.ResourceA() unexpected error: %v", err)
+	}
+
+	txtHdr := ResourceHeader{Name: MustParseName("txt.example.com"), Type: TypeTXT, Class: ClassIN, TTL: 60}
+	var err error
+	txtCount := 0
+	for ; err == nil; txtCount++ {
+		err = b.ResourceTXT(txtHdr, ResourceTXT{TXT: [][]byte{[]byte("some text")}})
+	}
+	if err != ErrTruncated {
+		t.Fatalf("b.ResourceTXT() unexpected error: %v, want: %v", err, ErrTruncated)
+	}
+	if txtCount < 3 {
+		t.Fatalf("only %v TXT resources were appended", txtCount-1)
+	}
+
+	if err := b.ResourceA(a, ResourceA{A: [4]byte{192, 0, 2, 3}}); err != nil {
+		t.Fatalf("b.ResourceA() unexpected error: %v", err)
+	}
+
+	b.StartAuthorities()
+	b.StartAdditionals()
+	if err := b.ResourceOPT(EDNS0Header{Payload: 1232}.AsResourceHeader(), ResourceOPT{}); err != nil {
+		t.Fatalf("b.ResourceOPT() unexpected error: %v", err)
+	}
+
+	msg := b.Bytes()
+	if len(msg) > 200 {
+		t.Fatalf("message length: %v, want <= 200", len(msg))
+	}
+
+	var m Msg
+	if err := m.Unpack(msg); err != nil {
+		t.Fatalf("m.Unpack() unexpected error: %v", err)
+	}
+	if !m.Header.Flags.Bit(BitTC) {
+		t.Errorf("the TC bit is not set")
+	}
+	if len(m.Answers) != 3 {
+		t.Fatalf("got %v answers, want: 3: %v", len(m.Answers), m.Answers)
+	}
+	for _, res := range m.Answers {
+		if res.Header.Type != TypeA {
+			t.Errorf("unexpected answer: %v", res)
+		}
+	}
+	if len(m.Additionals) != 1 || m.Additionals[0].Header.Type != TypeOPT {
+		t.Errorf("unexpected additionals: %v", m.Additionals)
+	}
+}
+
+func TestBuilderTruncationRRSetNotAtTheEnd(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.LimitMessageSize(100)
+	b.EnableTruncation()
+	b.StartAnswers()
+
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN, TTL: 60}
+	if err := b.ResourceA(hdr, ResourceA{A: [4]byte{192, 0, 2, 1}}); err != nil {
+		t.Fatalf("b.ResourceA() unexpected error: %v", err)
+	}
+	if err := b.ResourceAAAA(hdr, ResourceAAAA{}); err != nil {
+		t.Fatalf("b.ResourceAAAA() unexpected error: %v", err)
+	}
+	length := b.Length()
+
+	// The RRset of the truncated resource is not the last one, nothing is removed.
+	if err := b.RawResource(hdr, RawResource{Type: TypeA, Data: make([]byte, 100)}); err != ErrTruncated {
+		t.Fatalf("b.RawResource() unexpected error: %v, want: %v", err, ErrTruncated)
+	}
+	if b.Length() != length || b.Header().ANCount != 2 || !b.Header().Flags.Bit(BitTC) {
+		t.Fatalf("unexpected builder state after truncation: length: %v, header: %#v", b.Length(), b.Header())
+	}
+}
+
+func TestBuilderTruncationRDBuilder(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.LimitMessageSize(100)
+	b.EnableTruncation()
+	b.StartAnswers()
+
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Type: 65280, Class: ClassIN, TTL: 60}
+	for i := 0; i < 2; i++ {
+		rdb, err := b.RDBuilder(hdr)
+		if err != nil {
+			t.Fatalf("b.RDBuilder() unexpected error: %v", err)
+		}
+		if err := rdb.Bytes(make([]byte, 10)); err != nil {
+			t.Fatalf("rdb.Bytes() unexpected error: %v", err)
+		}
+		rdb.End()
+	}
+
+	rdb, err := b.RDBuilder(hdr)
+	if err != nil {
+		t.Fatalf("b.RDBuilder() unexpected error: %v", err)
+	}
+	if err := rdb.Bytes(make([]byte, 100)); err != ErrTruncated {
+		t.Fatalf("rdb.Bytes() unexpected error: %v, want: %v", err, ErrTruncated)
+	}
+	rdb.Remove()
+
+	if b.Length() != headerLen || b.Header().ANCount != 0 || !b.Header().Flags.Bit(BitTC) {
+		t.Fatalf("unexpected builder state after truncation: length: %v, header: %#v", b.Length(), b.Header())
+	}
+}
@@ -0,0 +1,257 @@
+package dnsmsg
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"time"
+)
+
+var (
+	errClientInvalidQuery = errors.New("query must contain exactly one question")
+	errClientNoResponse   = errors.New("no valid response received")
+)
+
+// Client is a DNS stub client, it sends queries over UDP, and retries them
+// over TCP when the response is truncated (the TC bit is set).
+//
+// The zero value of Client is ready to use.
+type Client struct {
+	// Dial is used to dial connections, the network is either "udp" or "tcp".
+	// When nil, [net.Dialer.DialContext] is used.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// Timeout is the time to wait for a response to the first UDP query, it is
+	// doubled on every retry (exponential backoff). It also limits the time of
+	// the entire TCP exchange. When zero, 1 second is used.
+	Timeout time.Duration
+
+	// Attempts is the amount of attempts to send the query over UDP.
+	// When zero, 3 attempts are made.
+	Attempts int
+}
+
+func (c *Client) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if c.Dial != nil {
+		return c.Dial(ctx, network, address)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return time.Second
+	}
+	return c.Timeout
+}
+
+func (c *Client) attempts() int {
+	if c.Attempts <= 0 {
+		return 3
+	}
+	return c.Attempts
+}
+
+// Exchange sends the query to the DNS server at address (host:port) and returns the response.
+// The query must contain exactly one question.
+//
+// Responses with an ID or a question that does not match the query, received from a different
+// address than address, or that cannot be parsed, are ignored. The query is retransmitted with
+// an exponential backoff (see [Client.Timeout] and [Client.Attempts]) until a valid response is
+// received. A truncated UDP response causes the query to be retried over TCP.
+//
+// When ctx is canceled, Exchange returns ctx.Err().
+func (c *Client) Exchange(ctx context.Context, address string, query []byte) ([]byte, error) {
+	q, err := parseClientQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.exchangeUDP(ctx, address, query, &q)
+	if err != nil {
+		return nil, err
+	}
+
+	_, hdr, _ := Parse(resp)
+	if !hdr.Flags.Bit(BitTC) {
+		return resp, nil
+	}
+	return c.exchangeTCP(ctx, address, query, &q)
+}
+
+type clientQuery struct {
+	id       uint16
+	question Question
+}
+
+func parseClientQuery(query []byte) (clientQuery, error) {
+	p, hdr, err := Parse(query)
+	if err != nil {
+		return clientQuery{}, err
+	}
+	if hdr.QDCount != 1 {
+		return clientQuery{}, errClientInvalidQuery
+	}
+	q, err := p.Question()
+	if err != nil {
+		return clientQuery{}, err
+	}
+	return clientQuery{id: hdr.ID, question: q}, nil
+}
+
+// matches reports whether resp is a valid response to the query.
+func (q *clientQuery) matches(resp []byte) bool {
+	p, hdr, err := Parse(resp)
+	if err != nil || hdr.ID != q.id || !hdr.Flags.Response() || hdr.QDCount != 1 {
+		return false
+	}
+	rq, err := p.Question()
+	if err != nil {
+		return false
+	}
+	return rq.Type == q.question.Type && rq.Class == q.question.Class && rq.Name.Equal(&q.question.Name)
+}
+
+// watchContext sets an expired deadline on conn when ctx is canceled,
+// the returned function must be called to stop watching the context.
+func watchContext(ctx context.Context, conn net.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (c *Client) exchangeUDP(ctx context.Context, address string, query []byte, q *clientQuery) ([]byte, error) {
+	conn, err := c.dial(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer watchContext(ctx, conn)()
+
+	var (
+		buf     = make([]byte, math.MaxUint16)
+		timeout = c.timeout()
+		lastErr = errClientNoResponse
+	)
+
+	for i := 0; i < c.attempts(); i++ {
+		if _, err := conn.Write(query); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		timeout *= 2
+
+		// The deadline expired by watchContext might have been overwritten
+		// by the SetReadDeadline call above.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for {
+			n, ok, err := readUDPResponse(conn, buf)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					lastErr = err
+					break
+				}
+				return nil, err
+			}
+			if ok && q.matches(buf[:n]) {
+				return append([]byte(nil), buf[:n]...), nil
+			}
+		}
+	}
+
+	return nil, lastErr
+}
+
+// readUDPResponse reads a single datagram from conn. When the conn is a [net.PacketConn],
+// ok is set to false for datagrams received from an address other than the remote address.
+func readUDPResponse(conn net.Conn, buf []byte) (n int, ok bool, err error) {
+	pc, isPacketConn := conn.(net.PacketConn)
+	if !isPacketConn {
+		n, err := conn.Read(buf)
+		return n, true, err
+	}
+
+	n, addr, err := pc.ReadFrom(buf)
+	if err != nil {
+		return 0, false, err
+	}
+	return n, sameAddr(addr, conn.RemoteAddr()), nil
+}
+
+func sameAddr(a, b net.Addr) bool {
+	ua, okA := a.(*net.UDPAddr)
+	ub, okB := b.(*net.UDPAddr)
+	if okA && okB {
+		return ua.Port == ub.Port && ua.AddrPort().Addr().Unmap() == ub.AddrPort().Addr().Unmap()
+	}
+	return a.Network() == b.Network() && a.String() == b.String()
+}
+
+func (c *Client) exchangeTCP(ctx context.Context, address string, query []byte, q *clientQuery) ([]byte, error) {
+	conn, err := c.dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer watchContext(ctx, conn)()
+
+	deadline := time.Now().Add(c.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// The deadline expired by watchContext might have been overwritten
+	// by the SetDeadline call above.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp, err := exchangeStream(conn, query, q)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return resp, err
+}
+
+// exchangeStream sends the query over a stream connection, using the
+// 2-byte length prefix framing, and reads the matching response.
+func exchangeStream(conn net.Conn, query []byte, q *clientQuery) ([]byte, error) {
+	if err := NewStreamWriter(conn).WriteMsg(query); err != nil {
+		return nil, err
+	}
+
+	r := NewStreamReader(conn)
+	for {
+		resp, err := r.ReadMsg(nil)
+		if err != nil {
+			return nil, err
+		}
+		if q.matches(resp) {
+			return resp, nil
+		}
+	}
+}
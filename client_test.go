@@ -0,0 +1,323 @@
+package dnsmsg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testClientQuery(t *testing.T, id uint16, name string) []byte {
+	t.Helper()
+	b := StartBuilder(make([]byte, 0, 512), id, 0)
+	if err := b.Question(Question{Name: MustParseName(name), Type: TypeA, Class: ClassIN}); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+	return b.Bytes()
+}
+
+// testClientResponse creates a response to query, with the ID xored by idXor and the TC bit set to tc.
+func testClientResponse(query []byte, idXor uint16, tc bool, a byte) []byte {
+	p, hdr, err := Parse(query)
+	if err != nil {
+		return nil
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil
+	}
+
+	flags := hdr.Flags
+	flags.SetResponse()
+	flags.SetBit(BitTC, tc)
+	b := StartBuilder(make([]byte, 0, 512), hdr.ID^idXor, flags)
+	b.Question(q)
+	b.StartAnswers()
+	b.ResourceA(ResourceHeader{Name: q.Name, Class: ClassIN, TTL: 60}, ResourceA{A: [4]byte{192, 0, 2, a}})
+	return b.Bytes()
+}
+
+type testClientServer struct {
+	udp net.PacketConn
+	tcp net.Listener
+
+	udpQueries atomic.Int32
+	tcpQueries atomic.Int32
+}
+
+// startTestClientServer starts an UDP and TCP server on the same loopback port.
+// handleUDP is called for each UDP query, it returns the responses to send.
+func startTestClientServer(t *testing.T, handleUDP func(n int, query []byte) [][]byte) *testClientServer {
+	t.Helper()
+
+	var (
+		s   testClientServer
+		err error
+	)
+	for i := 0; i < 10; i++ {
+		s.udp, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.tcp, err = net.Listen("tcp", s.udp.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		s.udp.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.udp.Close()
+		s.tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := s.udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			num := int(s.udpQueries.Add(1))
+			for _, resp := range handleUDP(num, buf[:n]) {
+				s.udp.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := s.tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, unpackUint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				s.tcpQueries.Add(1)
+
+				// A response with a wrong ID first, it must be ignored.
+				for _, resp := range [][]byte{testClientResponse(query, 1, false, 2), testClientResponse(query, 0, false, 3)} {
+					msg := appendUint16(nil, uint16(len(resp)))
+					conn.Write(append(msg, resp...))
+				}
+			}()
+		}
+	}()
+
+	return &s
+}
+
+func (s *testClientServer) addr() string {
+	return s.udp.LocalAddr().String()
+}
+
+func testClientAnswer(t *testing.T, resp []byte) byte {
+	t.Helper()
+	var msg Msg
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatalf("msg.Unpack() unexpected error: %v", err)
+	}
+	if len(msg.Answers) != 1 {
+		t.Fatalf("got %v answers, want: 1", len(msg.Answers))
+	}
+	return msg.Answers[0].Body.(ResourceA).A[3]
+}
+
+func TestClientExchangeUDP(t *testing.T) {
+	s := startTestClientServer(t, func(n int, query []byte) [][]byte {
+		if n == 1 {
+			// Drop the first query, client must retry.
+			return nil
+		}
+		otherQuestion := testClientResponse(testClientQuery(t, 0, "other.example.com"), 0, false, 4)
+		packUint16(otherQuestion, unpackUint16(query))
+		return [][]byte{
+			testClientResponse(query, 1, false, 2),
+			otherQuestion,
+			{1, 2, 3},
+			testClientResponse(query, 0, false, 1),
+		}
+	})
+
+	c := Client{Timeout: 50 * time.Millisecond}
+	resp, err := c.Exchange(context.Background(), s.addr(), testClientQuery(t, 1234, "example.com"))
+	if err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+	if a := testClientAnswer(t, resp); a != 1 {
+		t.Fatalf("got answer from response %v, want: 1", a)
+	}
+	if n := s.udpQueries.Load(); n != 2 {
+		t.Fatalf("server received %v UDP queries, want: 2", n)
+	}
+}
+
+func TestClientExchangeTCPFallback(t *testing.T) {
+	s := startTestClientServer(t, func(n int, query []byte) [][]byte {
+		return [][]byte{testClientResponse(query, 0, true, 1)}
+	})
+
+	var networks []string
+	c := Client{
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			networks = append(networks, network)
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+	resp, err := c.Exchange(context.Background(), s.addr(), testClientQuery(t, 1234, "example.com"))
+	if err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+	if a := testClientAnswer(t, resp); a != 3 {
+		t.Fatalf("got answer from response %v, want: 3", a)
+	}
+	if len(networks) != 2 || networks[0] != "udp" || networks[1] != "tcp" {
+		t.Fatalf("unexpected dialed networks: %v", networks)
+	}
+	if s.tcpQueries.Load() != 1 {
+		t.Fatalf("server received %v TCP queries, want: 1", s.tcpQueries.Load())
+	}
+}
+
+func TestClientExchangeTimeout(t *testing.T) {
+	s := startTestClientServer(t, func(n int, query []byte) [][]byte { return nil })
+
+	c := Client{Timeout: 10 * time.Millisecond, Attempts: 3}
+	_, err := c.Exchange(context.Background(), s.addr(), testClientQuery(t, 1234, "example.com"))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("c.Exchange() unexpected error: %v, want timeout", err)
+	}
+	if n := s.udpQueries.Load(); n != 3 {
+		t.Fatalf("server received %v UDP queries, want: 3", n)
+	}
+}
+
+func TestClientExchangeContextCancel(t *testing.T) {
+	s := startTestClientServer(t, func(n int, query []byte) [][]byte { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	c := Client{Timeout: 10 * time.Second}
+	_, err := c.Exchange(ctx, s.addr(), testClientQuery(t, 1234, "example.com"))
+	if err != context.Canceled {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, context.Canceled)
+	}
+}
+
+// testClientCancelTimeoutError is a timeout error, that cancels a context when
+// its Timeout method is called, after the exchange checked the context.
+type testClientCancelTimeoutError struct {
+	cancel context.CancelFunc
+}
+
+func (testClientCancelTimeoutError) Error() string   { return "i/o timeout" }
+func (testClientCancelTimeoutError) Temporary() bool { return true }
+func (e testClientCancelTimeoutError) Timeout() bool {
+	e.cancel()
+	return true
+}
+
+type testClientCancelConn struct {
+	net.Conn
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (c *testClientCancelConn) Read(b []byte) (int, error) {
+	c.reads++
+	return 0, testClientCancelTimeoutError{cancel: c.cancel}
+}
+
+func TestClientExchangeContextCancelAfterTimeout(t *testing.T) {
+	s := startTestClientServer(t, func(n int, query []byte) [][]byte { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var conn *testClientCancelConn
+	c := Client{
+		Timeout: 10 * time.Second,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			inner, err := d.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			conn = &testClientCancelConn{Conn: inner, cancel: cancel}
+			return conn, nil
+		},
+	}
+	_, err := c.Exchange(ctx, s.addr(), testClientQuery(t, 1234, "example.com"))
+	if err != context.Canceled {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, context.Canceled)
+	}
+	if conn.reads != 1 {
+		t.Fatalf("conn read %v times, want: 1", conn.reads)
+	}
+}
+
+// testClientCancelDeadlineConn cancels the context in SetDeadline and
+// waits for watchContext to expire the deadline, before setting it.
+type testClientCancelDeadlineConn struct {
+	net.Conn
+	cancel  context.CancelFunc
+	expired chan struct{}
+}
+
+func (c *testClientCancelDeadlineConn) SetDeadline(t time.Time) error {
+	if t.Before(time.Now()) {
+		defer close(c.expired)
+		return c.Conn.SetDeadline(t)
+	}
+	c.cancel()
+	<-c.expired
+	return c.Conn.SetDeadline(t)
+}
+
+func TestClientExchangeTCPContextCancelDuringSetDeadline(t *testing.T) {
+	s := startTestClientServer(t, func(n int, query []byte) [][]byte {
+		return [][]byte{testClientResponse(query, 0, true, 1)}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := Client{
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, network, address)
+			if err != nil || network != "tcp" {
+				return conn, err
+			}
+			return &testClientCancelDeadlineConn{Conn: conn, cancel: cancel, expired: make(chan struct{})}, nil
+		},
+	}
+	_, err := c.Exchange(ctx, s.addr(), testClientQuery(t, 1234, "example.com"))
+	if err != context.Canceled {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, context.Canceled)
+	}
+}
+
+func TestClientExchangeInvalidQuery(t *testing.T) {
+	b := StartBuilder(nil, 0, 0)
+	var c Client
+	if _, err := c.Exchange(context.Background(), "127.0.0.1:53", b.Bytes()); err != errClientInvalidQuery {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, errClientInvalidQuery)
+	}
+}
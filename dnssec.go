@@ -0,0 +1,689 @@
+package dnsmsg
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// DNSSECAlgorithm is a DNSSEC security algorithm number, used
+// by the [ResourceDNSKEY], [ResourceDS] and [ResourceRRSIG].
+type DNSSECAlgorithm uint8
+
+const (
+	DNSSECAlgorithmRSAMD5           DNSSECAlgorithm = 1
+	DNSSECAlgorithmRSASHA1          DNSSECAlgorithm = 5
+	DNSSECAlgorithmRSASHA1NSEC3SHA1 DNSSECAlgorithm = 7
+	DNSSECAlgorithmRSASHA256        DNSSECAlgorithm = 8
+	DNSSECAlgorithmRSASHA512        DNSSECAlgorithm = 10
+	DNSSECAlgorithmECDSAP256SHA256  DNSSECAlgorithm = 13
+	DNSSECAlgorithmECDSAP384SHA384  DNSSECAlgorithm = 14
+	DNSSECAlgorithmED25519          DNSSECAlgorithm = 15
+	DNSSECAlgorithmED448            DNSSECAlgorithm = 16
+)
+
+// DigestType is a digest algorithm used by the [ResourceDS].
+type DigestType uint8
+
+const (
+	DigestTypeSHA1   DigestType = 1
+	DigestTypeSHA256 DigestType = 2
+	DigestTypeSHA384 DigestType = 4
+)
+
+// NSEC3HashAlgorithm is a hash algorithm used by the [ResourceNSEC3] and [ResourceNSEC3PARAM].
+type NSEC3HashAlgorithm uint8
+
+const (
+	NSEC3HashAlgorithmSHA1 NSEC3HashAlgorithm = 1
+)
+
+// Flags of the [ResourceDNSKEY].
+const (
+	DNSKEYFlagZone   uint16 = 1 << 8
+	DNSKEYFlagRevoke uint16 = 1 << 7
+	DNSKEYFlagSEP    uint16 = 1
+)
+
+// DNSKEYProtocol is the only valid value of the [ResourceDNSKEY] Protocol field.
+const DNSKEYProtocol = 3
+
+// NSEC3FlagOptOut is the Opt-Out flag of the [ResourceNSEC3].
+const NSEC3FlagOptOut uint8 = 1
+
+// ResourceDNSKEY is a DNSKEY resource defined in RFC 4034.
+type ResourceDNSKEY struct {
+	Flags     uint16
+	Protocol  uint8
+	Algorithm DNSSECAlgorithm
+	PublicKey []byte
+}
+
+// KeyTag calculates the key tag of the key, as defined in RFC 4034, Appendix B.
+func (r *ResourceDNSKEY) KeyTag() uint16 {
+	if r.Algorithm == DNSSECAlgorithmRSAMD5 {
+		if len(r.PublicKey) < 3 {
+			return 0
+		}
+		return unpackUint16(r.PublicKey[len(r.PublicKey)-3:])
+	}
+
+	ac := uint32(r.Flags) + uint32(r.Protocol)<<8 + uint32(r.Algorithm)
+	for i, v := range r.PublicKey {
+		if i&1 == 0 {
+			ac += uint32(v) << 8
+		} else {
+			ac += uint32(v)
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac)
+}
+
+// ResourceCDNSKEY is a CDNSKEY resource defined in RFC 7344, it uses
+// the same resource data format as the [ResourceDNSKEY].
+type ResourceCDNSKEY ResourceDNSKEY
+
+// ResourceDS is a DS resource defined in RFC 4034.
+type ResourceDS struct {
+	KeyTag     uint16
+	Algorithm  DNSSECAlgorithm
+	DigestType DigestType
+	Digest     []byte
+}
+
+// ResourceCDS is a CDS resource defined in RFC 7344, it uses
+// the same resource data format as the [ResourceDS].
+type ResourceCDS ResourceDS
+
+// ResourceRRSIG is a RRSIG resource defined in RFC 4034.
+type ResourceRRSIG struct {
+	TypeCovered Type
+	Algorithm   DNSSECAlgorithm
+	Labels      uint8
+	OriginalTTL uint32
+	Expiration  uint32
+	Inception   uint32
+	KeyTag      uint16
+
+	// SignerName is never compressed by the [Builder] (as required by RFC 4034),
+	// but the [Parser] accepts compressed names.
+	SignerName Name
+
+	Signature []byte
+}
+
+// ResourceNSEC is a NSEC resource defined in RFC 4034.
+type ResourceNSEC struct {
+	// NextDomain is never compressed by the [Builder] (as required by RFC 4034),
+	// but the [Parser] accepts compressed names.
+	NextDomain Name
+
+	TypeBitmap TypeBitmap
+}
+
+// ResourceNSEC3 is a NSEC3 resource defined in RFC 5155.
+type ResourceNSEC3 struct {
+	HashAlgorithm NSEC3HashAlgorithm
+	Flags         uint8
+	Iterations    uint16
+	Salt          []byte
+
+	// NextHashedOwner is the binary (not base32hex encoded) hash
+	// of the next owner name.
+	NextHashedOwner []byte
+
+	TypeBitmap TypeBitmap
+}
+
+// OptOut reports whether the Opt-Out flag is set.
+func (r *ResourceNSEC3) OptOut() bool {
+	return r.Flags&NSEC3FlagOptOut != 0
+}
+
+// ResourceNSEC3PARAM is a NSEC3PARAM resource defined in RFC 5155.
+type ResourceNSEC3PARAM struct {
+	HashAlgorithm NSEC3HashAlgorithm
+	Flags         uint8
+	Iterations    uint16
+	Salt          []byte
+}
+
+// TypeBitmap is a set of resource types in the wire format of the
+// "Type Bit Maps" field, used by the [ResourceNSEC] and [ResourceNSEC3]
+// (RFC 4034, Section 4.1.2).
+//
+// The zero value represents an empty set.
+type TypeBitmap []byte
+
+// NewTypeBitmap creates a [TypeBitmap] containing the provided types.
+// The types don't need to be sorted and may contain duplicates.
+func NewTypeBitmap(types ...Type) TypeBitmap {
+	if len(types) == 0 {
+		return nil
+	}
+
+	sorted := make([]Type, len(types))
+	copy(sorted, types)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var (
+		bitmap       TypeBitmap
+		windowOffset = -1
+		window       = -1
+	)
+
+	for _, t := range sorted {
+		if int(t>>8) != window {
+			window = int(t >> 8)
+			windowOffset = len(bitmap)
+			bitmap = append(bitmap, uint8(window), 0)
+		}
+		length := int(uint8(t)>>3) + 1
+		for int(bitmap[windowOffset+1]) < length {
+			bitmap = append(bitmap, 0)
+			bitmap[windowOffset+1]++
+		}
+		bitmap[windowOffset+2+int(uint8(t)>>3)] |= 0x80 >> (uint8(t) & 7)
+	}
+
+	return bitmap
+}
+
+// Contains reports whether the t type is present in the bitmap.
+func (b TypeBitmap) Contains(t Type) bool {
+	for i := 0; i+2 <= len(b); i += 2 + int(b[i+1]) {
+		if b[i] != uint8(t>>8) {
+			continue
+		}
+		octet := int(uint8(t) >> 3)
+		if octet >= int(b[i+1]) || i+2+octet >= len(b) {
+			return false
+		}
+		return b[i+2+octet]&(0x80>>(uint8(t)&7)) != 0
+	}
+	return false
+}
+
+// Types returns all types present in the bitmap, in increasing order.
+func (b TypeBitmap) Types() []Type {
+	return b.AppendTypes(nil)
+}
+
+// AppendTypes appends all types present in the bitmap to dst, in increasing order.
+func (b TypeBitmap) AppendTypes(dst []Type) []Type {
+	for i := 0; i+2 <= len(b); i += 2 + int(b[i+1]) {
+		window := Type(b[i]) << 8
+		length := int(b[i+1])
+		if len(b)-i-2 < length {
+			length = len(b) - i - 2
+		}
+		for octet, v := range b[i+2 : i+2+length] {
+			for bit := 0; bit < 8; bit++ {
+				if v&(0x80>>bit) != 0 {
+					dst = append(dst, window|Type(octet<<3|bit))
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// isValid reports whether the bitmap is correctly encoded, window blocks
+// must be sorted in strictly increasing order and their length must be between 1 and 32.
+func (b TypeBitmap) isValid() bool {
+	for i, lastWindow := 0, -1; i != len(b); {
+		if len(b)-i < 2 {
+			return false
+		}
+		window, length := int(b[i]), int(b[i+1])
+		if window <= lastWindow || length == 0 || length > 32 || len(b)-i-2 < length {
+			return false
+		}
+		lastWindow = window
+		i += 2 + length
+	}
+	return true
+}
+
+var (
+	errInvalidTypeBitmap = errors.New("invalid type bitmap")
+	errInvalidNSEC3      = errors.New("invalid nsec3 salt or next hashed owner name length")
+)
+
+// ResourceDNSKEY appends a single DNSKEY resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceDNSKEY(hdr ResourceHeader, dnskey ResourceDNSKEY) error {
+	hdr.Type = TypeDNSKEY
+	return b.resourceDNSKEY(hdr, dnskey)
+}
+
+// ResourceCDNSKEY appends a single CDNSKEY resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceCDNSKEY(hdr ResourceHeader, cdnskey ResourceCDNSKEY) error {
+	hdr.Type = TypeCDNSKEY
+	return b.resourceDNSKEY(hdr, ResourceDNSKEY(cdnskey))
+}
+
+func (b *Builder) resourceDNSKEY(hdr ResourceHeader, dnskey ResourceDNSKEY) error {
+	length := 4 + len(dnskey.PublicKey)
+	if length > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	hdr.Length = uint16(length)
+	if err := b.appendHeader(hdr, b.maxBufSize-length); err != nil {
+		return err
+	}
+	b.buf = appendDNSKEYRData(b.buf, &dnskey)
+	return nil
+}
+
+func appendDNSKEYRData(dst []byte, dnskey *ResourceDNSKEY) []byte {
+	dst = appendUint16(dst, dnskey.Flags)
+	dst = append(dst, dnskey.Protocol, uint8(dnskey.Algorithm))
+	return append(dst, dnskey.PublicKey...)
+}
+
+// ResourceDS appends a single DS resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceDS(hdr ResourceHeader, ds ResourceDS) error {
+	hdr.Type = TypeDS
+	return b.resourceDS(hdr, ds)
+}
+
+// ResourceCDS appends a single CDS resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceCDS(hdr ResourceHeader, cds ResourceCDS) error {
+	hdr.Type = TypeCDS
+	return b.resourceDS(hdr, ResourceDS(cds))
+}
+
+func (b *Builder) resourceDS(hdr ResourceHeader, ds ResourceDS) error {
+	length := 4 + len(ds.Digest)
+	if length > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	hdr.Length = uint16(length)
+	if err := b.appendHeader(hdr, b.maxBufSize-length); err != nil {
+		return err
+	}
+	b.buf = appendUint16(b.buf, ds.KeyTag)
+	b.buf = append(b.buf, uint8(ds.Algorithm), uint8(ds.DigestType))
+	b.buf = append(b.buf, ds.Digest...)
+	return nil
+}
+
+// ResourceRRSIG appends a single RRSIG resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The SignerName is never compressed, as required by RFC 4034.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceRRSIG(hdr ResourceHeader, rrsig ResourceRRSIG) error {
+	hdr.Type = TypeRRSIG
+	return b.resourceRRSIG(hdr, rrsig)
+}
+
+func (b *Builder) resourceRRSIG(hdr ResourceHeader, rrsig ResourceRRSIG) error {
+	if 18+int(rrsig.SignerName.Length)+len(rrsig.Signature) > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	f, hdrOffset, err := b.appendHeaderWithLengthFixup(hdr, b.maxBufSize-18)
+	if err != nil {
+		return err
+	}
+	b.buf = appendUint16(b.buf, uint16(rrsig.TypeCovered))
+	b.buf = append(b.buf, uint8(rrsig.Algorithm), rrsig.Labels)
+	b.buf = appendUint32(b.buf, rrsig.OriginalTTL)
+	b.buf = appendUint32(b.buf, rrsig.Expiration)
+	b.buf = appendUint32(b.buf, rrsig.Inception)
+	b.buf = appendUint16(b.buf, rrsig.KeyTag)
+	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize-len(rrsig.Signature), b.headerStartOffset, rrsig.SignerName.asSlice(), false)
+	if err != nil {
+		return b.removeResourceHeader(hdrOffset, err)
+	}
+	b.buf = append(b.buf, rrsig.Signature...)
+	f.fixup(b)
+	return nil
+}
+
+// ResourceNSEC appends a single NSEC resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The NextDomain is never compressed, as required by RFC 4034.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceNSEC(hdr ResourceHeader, nsec ResourceNSEC) error {
+	hdr.Type = TypeNSEC
+	if !nsec.TypeBitmap.isValid() {
+		return errInvalidTypeBitmap
+	}
+	if int(nsec.NextDomain.Length)+len(nsec.TypeBitmap) > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	f, hdrOffset, err := b.appendHeaderWithLengthFixup(hdr, b.maxBufSize)
+	if err != nil {
+		return err
+	}
+	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize-len(nsec.TypeBitmap), b.headerStartOffset, nsec.NextDomain.asSlice(), false)
+	if err != nil {
+		return b.removeResourceHeader(hdrOffset, err)
+	}
+	b.buf = append(b.buf, nsec.TypeBitmap...)
+	f.fixup(b)
+	return nil
+}
+
+// ResourceNSEC3 appends a single NSEC3 resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceNSEC3(hdr ResourceHeader, nsec3 ResourceNSEC3) error {
+	hdr.Type = TypeNSEC3
+	if len(nsec3.Salt) > math.MaxUint8 || len(nsec3.NextHashedOwner) == 0 || len(nsec3.NextHashedOwner) > math.MaxUint8 {
+		return errInvalidNSEC3
+	}
+	if !nsec3.TypeBitmap.isValid() {
+		return errInvalidTypeBitmap
+	}
+	length := 6 + len(nsec3.Salt) + len(nsec3.NextHashedOwner) + len(nsec3.TypeBitmap)
+	if length > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	hdr.Length = uint16(length)
+	if err := b.appendHeader(hdr, b.maxBufSize-length); err != nil {
+		return err
+	}
+	b.buf = appendNSEC3RData(b.buf, &nsec3)
+	return nil
+}
+
+func appendNSEC3RData(dst []byte, nsec3 *ResourceNSEC3) []byte {
+	dst = append(dst, uint8(nsec3.HashAlgorithm), nsec3.Flags)
+	dst = appendUint16(dst, nsec3.Iterations)
+	dst = append(dst, uint8(len(nsec3.Salt)))
+	dst = append(dst, nsec3.Salt...)
+	dst = append(dst, uint8(len(nsec3.NextHashedOwner)))
+	dst = append(dst, nsec3.NextHashedOwner...)
+	return append(dst, nsec3.TypeBitmap...)
+}
+
+// ResourceNSEC3PARAM appends a single NSEC3PARAM resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceNSEC3PARAM(hdr ResourceHeader, nsec3param ResourceNSEC3PARAM) error {
+	hdr.Type = TypeNSEC3PARAM
+	if len(nsec3param.Salt) > math.MaxUint8 {
+		return errInvalidNSEC3
+	}
+	length := 5 + len(nsec3param.Salt)
+	hdr.Length = uint16(length)
+	if err := b.appendHeader(hdr, b.maxBufSize-length); err != nil {
+		return err
+	}
+	b.buf = appendNSEC3PARAMRData(b.buf, &nsec3param)
+	return nil
+}
+
+func appendNSEC3PARAMRData(dst []byte, nsec3param *ResourceNSEC3PARAM) []byte {
+	dst = append(dst, uint8(nsec3param.HashAlgorithm), nsec3param.Flags)
+	dst = appendUint16(dst, nsec3param.Iterations)
+	dst = append(dst, uint8(len(nsec3param.Salt)))
+	return append(dst, nsec3param.Salt...)
+}
+
+// rData returns the resource data of the next resource, without
+// changing the state of the Parser.
+func (m *Parser) rData() ([]byte, error) {
+	if len(m.msg)-m.curOffset < int(m.nextResourceDataLength) {
+		return nil, errInvalidDNSMessage
+	}
+	return m.msg[m.curOffset : m.curOffset+int(m.nextResourceDataLength)], nil
+}
+
+// ResourceDNSKEY parses a single DNSKEY resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeDNSKEY].
+func (m *Parser) ResourceDNSKEY() (ResourceDNSKEY, error) {
+	if !m.resourceData || m.nextResourceType != TypeDNSKEY {
+		return ResourceDNSKEY{}, errInvalidOperation
+	}
+	return m.resourceDNSKEY()
+}
+
+// ResourceCDNSKEY parses a single CDNSKEY resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeCDNSKEY].
+func (m *Parser) ResourceCDNSKEY() (ResourceCDNSKEY, error) {
+	if !m.resourceData || m.nextResourceType != TypeCDNSKEY {
+		return ResourceCDNSKEY{}, errInvalidOperation
+	}
+	dnskey, err := m.resourceDNSKEY()
+	return ResourceCDNSKEY(dnskey), err
+}
+
+func (m *Parser) resourceDNSKEY() (ResourceDNSKEY, error) {
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceDNSKEY{}, err
+	}
+	if len(rdata) < 4 {
+		return ResourceDNSKEY{}, errInvalidDNSMessage
+	}
+	m.resourceData = false
+	m.curOffset += len(rdata)
+	return ResourceDNSKEY{
+		Flags:     unpackUint16(rdata),
+		Protocol:  rdata[2],
+		Algorithm: DNSSECAlgorithm(rdata[3]),
+		PublicKey: rdata[4:],
+	}, nil
+}
+
+// ResourceDS parses a single DS resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeDS].
+func (m *Parser) ResourceDS() (ResourceDS, error) {
+	if !m.resourceData || m.nextResourceType != TypeDS {
+		return ResourceDS{}, errInvalidOperation
+	}
+	return m.resourceDS()
+}
+
+// ResourceCDS parses a single CDS resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeCDS].
+func (m *Parser) ResourceCDS() (ResourceCDS, error) {
+	if !m.resourceData || m.nextResourceType != TypeCDS {
+		return ResourceCDS{}, errInvalidOperation
+	}
+	ds, err := m.resourceDS()
+	return ResourceCDS(ds), err
+}
+
+func (m *Parser) resourceDS() (ResourceDS, error) {
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceDS{}, err
+	}
+	if len(rdata) < 4 {
+		return ResourceDS{}, errInvalidDNSMessage
+	}
+	m.resourceData = false
+	m.curOffset += len(rdata)
+	return ResourceDS{
+		KeyTag:     unpackUint16(rdata),
+		Algorithm:  DNSSECAlgorithm(rdata[2]),
+		DigestType: DigestType(rdata[3]),
+		Digest:     rdata[4:],
+	}, nil
+}
+
+// ResourceRRSIG parses a single RRSIG resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeRRSIG].
+func (m *Parser) ResourceRRSIG() (ResourceRRSIG, error) {
+	if !m.resourceData || m.nextResourceType != TypeRRSIG {
+		return ResourceRRSIG{}, errInvalidOperation
+	}
+	return m.resourceRRSIG()
+}
+
+func (m *Parser) resourceRRSIG() (ResourceRRSIG, error) {
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if len(rdata) < 18 {
+		return ResourceRRSIG{}, errInvalidDNSMessage
+	}
+
+	var signerName Name
+	offset, err := signerName.unpack(m.msg, m.curOffset+18)
+	if err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if 18+int(offset) > len(rdata) {
+		return ResourceRRSIG{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += len(rdata)
+	return ResourceRRSIG{
+		TypeCovered: Type(unpackUint16(rdata)),
+		Algorithm:   DNSSECAlgorithm(rdata[2]),
+		Labels:      rdata[3],
+		OriginalTTL: unpackUint32(rdata[4:]),
+		Expiration:  unpackUint32(rdata[8:]),
+		Inception:   unpackUint32(rdata[12:]),
+		KeyTag:      unpackUint16(rdata[16:]),
+		SignerName:  signerName,
+		Signature:   rdata[18+int(offset):],
+	}, nil
+}
+
+// ResourceNSEC parses a single NSEC resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeNSEC].
+func (m *Parser) ResourceNSEC() (ResourceNSEC, error) {
+	if !m.resourceData || m.nextResourceType != TypeNSEC {
+		return ResourceNSEC{}, errInvalidOperation
+	}
+
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceNSEC{}, err
+	}
+
+	var nextDomain Name
+	offset, err := nextDomain.unpack(m.msg, m.curOffset)
+	if err != nil {
+		return ResourceNSEC{}, err
+	}
+	if int(offset) > len(rdata) {
+		return ResourceNSEC{}, errInvalidDNSMessage
+	}
+
+	bitmap := TypeBitmap(rdata[offset:])
+	if !bitmap.isValid() {
+		return ResourceNSEC{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += len(rdata)
+	return ResourceNSEC{
+		NextDomain: nextDomain,
+		TypeBitmap: bitmap,
+	}, nil
+}
+
+// ResourceNSEC3 parses a single NSEC3 resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeNSEC3].
+func (m *Parser) ResourceNSEC3() (ResourceNSEC3, error) {
+	if !m.resourceData || m.nextResourceType != TypeNSEC3 {
+		return ResourceNSEC3{}, errInvalidOperation
+	}
+
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceNSEC3{}, err
+	}
+
+	if len(rdata) < 5 {
+		return ResourceNSEC3{}, errInvalidDNSMessage
+	}
+
+	saltLength := int(rdata[4])
+	if len(rdata)-5 < saltLength+1 {
+		return ResourceNSEC3{}, errInvalidDNSMessage
+	}
+	salt := rdata[5 : 5+saltLength]
+
+	hashOffset := 5 + saltLength
+	hashLength := int(rdata[hashOffset])
+	if hashLength == 0 || len(rdata)-hashOffset-1 < hashLength {
+		return ResourceNSEC3{}, errInvalidDNSMessage
+	}
+	nextHashedOwner := rdata[hashOffset+1 : hashOffset+1+hashLength]
+
+	bitmap := TypeBitmap(rdata[hashOffset+1+hashLength:])
+	if !bitmap.isValid() {
+		return ResourceNSEC3{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += len(rdata)
+	return ResourceNSEC3{
+		HashAlgorithm:   NSEC3HashAlgorithm(rdata[0]),
+		Flags:           rdata[1],
+		Iterations:      unpackUint16(rdata[2:]),
+		Salt:            salt,
+		NextHashedOwner: nextHashedOwner,
+		TypeBitmap:      bitmap,
+	}, nil
+}
+
+// ResourceNSEC3PARAM parses a single NSEC3PARAM resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeNSEC3PARAM].
+func (m *Parser) ResourceNSEC3PARAM() (ResourceNSEC3PARAM, error) {
+	if !m.resourceData || m.nextResourceType != TypeNSEC3PARAM {
+		return ResourceNSEC3PARAM{}, errInvalidOperation
+	}
+
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceNSEC3PARAM{}, err
+	}
+
+	if len(rdata) < 5 || len(rdata)-5 != int(rdata[4]) {
+		return ResourceNSEC3PARAM{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += len(rdata)
+	return ResourceNSEC3PARAM{
+		HashAlgorithm: NSEC3HashAlgorithm(rdata[0]),
+		Flags:         rdata[1],
+		Iterations:    unpackUint16(rdata[2:]),
+		Salt:          rdata[5:],
+	}, nil
+}
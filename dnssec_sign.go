@@ -0,0 +1,481 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var (
+	errNoSigningKeys          = errors.New("no signing keys")
+	errMissingSOA             = errors.New("zone apex does not contain a SOA RRSet")
+	errNotInZone              = errors.New("RRSet does not belong to the zone")
+	errDuplicateRRSet         = errors.New("duplicate RRSet")
+	errZoneAlreadySigned      = errors.New("zone contains DNSSEC RRSets created while signing")
+	errNSEC3HashCollision     = errors.New("NSEC3 hash collision")
+	errSignerInvalidSignature = errors.New("signer produced an invalid signature")
+)
+
+// SigningKey is a DNSSEC key used by the [ZoneSigner].
+type SigningKey struct {
+	// DNSKEY is the public part of the key, the Algorithm field
+	// determines how the signatures are created.
+	DNSKEY ResourceDNSKEY
+
+	// Signer is the private key corresponding to the DNSKEY.
+	Signer crypto.Signer
+}
+
+// NSEC3Params configures the NSEC3 chain created by the [ZoneSigner].
+type NSEC3Params struct {
+	Iterations uint16
+	Salt       []byte
+
+	// OptOut excludes the insecure delegations (delegations without a DS RRSet)
+	// from the NSEC3 chain and sets the Opt-Out flag on all NSEC3 resources.
+	OptOut bool
+}
+
+// SignedRRSet is a [RRSet] with its signatures.
+type SignedRRSet struct {
+	RRSet
+	Signatures []ResourceRRSIG
+}
+
+// ZoneSigner signs DNS zones, as described in RFC 4035, Section 2 and RFC 5155, Section 7.
+type ZoneSigner struct {
+	// Zone is the name of the zone apex.
+	Zone Name
+
+	// Keys are used to sign the zone. Keys with the SEP flag set only sign the DNSKEY
+	// RRSet, unless all keys have the SEP flag set, then all keys sign all RRSets.
+	Keys []SigningKey
+
+	// Inception and Expiration are the validity period of the created signatures.
+	Inception  time.Time
+	Expiration time.Time
+
+	// NSEC3 when not nil, causes the ZoneSigner to create a NSEC3 chain
+	// (and a NSEC3PARAM RRSet) instead of a NSEC chain.
+	NSEC3 *NSEC3Params
+
+	// Rand is a source of randomness used while signing, when nil [crypto/rand.Reader] is used.
+	Rand io.Reader
+}
+
+type signerNode struct {
+	name       Name
+	sets       []*SignedRRSet
+	delegation bool
+	occluded   bool
+}
+
+func (n *signerNode) hasType(t Type) bool {
+	for _, v := range n.sets {
+		if v.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *signerNode) types() []Type {
+	types := make([]Type, 0, len(n.sets)+2)
+	for _, v := range n.sets {
+		types = append(types, v.Type)
+	}
+	return types
+}
+
+// canonicalNameKey returns a lowercase wire form of n, used as a map key.
+func canonicalNameKey(n *Name) string {
+	c := *n
+	c.lowerASCII()
+	return string(c.asSlice())
+}
+
+// Sign signs the zone consisting of rrsets, each RRSet must have an unique owner name and type.
+// The zone apex must contain a SOA RRSet. When the zone apex does not contain a DNSKEY RRSet,
+// or it does not contain some of the keys, the DNSKEYs of all Keys are added to it.
+// RRSets at or below delegation points are not signed, except for DS RRSets.
+//
+// It returns all RRSets of the zone (including the created DNSKEY, NSEC, NSEC3 and NSEC3PARAM RRSets)
+// sorted in the canonical order by the owner name and then by type, with their signatures.
+func (z *ZoneSigner) Sign(rrsets []RRSet) ([]SignedRRSet, error) {
+	if len(z.Keys) == 0 {
+		return nil, errNoSigningKeys
+	}
+	for i := range z.Keys {
+		if !isSupportedAlgorithm(z.Keys[i].DNSKEY.Algorithm) {
+			return nil, ErrUnsupportedAlgorithm
+		}
+	}
+
+	nodes := make(map[string]*signerNode, len(rrsets))
+	for i := range rrsets {
+		set := &rrsets[i]
+		if !set.Name.isSubdomainOf(&z.Zone) {
+			return nil, errNotInZone
+		}
+		switch set.Type {
+		case TypeRRSIG, TypeNSEC, TypeNSEC3, TypeNSEC3PARAM:
+			return nil, errZoneAlreadySigned
+		}
+
+		key := canonicalNameKey(&set.Name)
+		node, ok := nodes[key]
+		if !ok {
+			node = &signerNode{name: set.Name}
+			nodes[key] = node
+		}
+		if node.hasType(set.Type) {
+			return nil, errDuplicateRRSet
+		}
+
+		rdata := make([][]byte, len(set.RData))
+		copy(rdata, set.RData)
+		node.sets = append(node.sets, &SignedRRSet{RRSet: RRSet{
+			Name:  set.Name,
+			Type:  set.Type,
+			Class: set.Class,
+			TTL:   set.TTL,
+			RData: rdata,
+		}})
+	}
+
+	apex := nodes[canonicalNameKey(&z.Zone)]
+	if apex == nil || !apex.hasType(TypeSOA) {
+		return nil, errMissingSOA
+	}
+
+	var soa *SignedRRSet
+	for _, v := range apex.sets {
+		if v.Type == TypeSOA {
+			soa = v
+		}
+	}
+	if len(soa.RData) != 1 || len(soa.RData[0]) < 20 {
+		return nil, errInvalidDNSMessage
+	}
+	negativeTTL := unpackUint32(soa.RData[0][len(soa.RData[0])-4:])
+	if soa.TTL < negativeTTL {
+		negativeTTL = soa.TTL
+	}
+
+	z.addDNSKEYs(apex, soa)
+
+	for _, node := range nodes {
+		node.delegation = node != apex && node.hasType(TypeNS)
+	}
+	for _, node := range nodes {
+		for p := node.name.parent(); p.isSubdomainOf(&z.Zone) && !p.Equal(&z.Zone); p = p.parent() {
+			if parent, ok := nodes[canonicalNameKey(&p)]; ok && parent.delegation {
+				node.occluded = true
+				break
+			}
+		}
+	}
+
+	sorted := make([]*signerNode, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.occluded {
+			sorted = append(sorted, node)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].name.Compare(&sorted[j].name) < 0
+	})
+
+	if z.NSEC3 != nil {
+		apex.sets = append(apex.sets, &SignedRRSet{RRSet: RRSet{
+			Name:  z.Zone,
+			Type:  TypeNSEC3PARAM,
+			Class: soa.Class,
+			TTL:   negativeTTL,
+			RData: [][]byte{appendNSEC3PARAMRData(nil, &ResourceNSEC3PARAM{
+				HashAlgorithm: NSEC3HashAlgorithmSHA1,
+				Iterations:    z.NSEC3.Iterations,
+				Salt:          z.NSEC3.Salt,
+			})},
+		}})
+		if err := z.addNSEC3Chain(nodes, sorted, soa.Class, negativeTTL); err != nil {
+			return nil, err
+		}
+	} else {
+		z.addNSECChain(sorted, soa.Class, negativeTTL)
+	}
+
+	all := make([]*signerNode, 0, len(nodes))
+	for _, node := range nodes {
+		all = append(all, node)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].name.Compare(&all[j].name) < 0
+	})
+
+	var signed []SignedRRSet
+	for _, node := range all {
+		sort.Slice(node.sets, func(i, j int) bool {
+			return node.sets[i].Type < node.sets[j].Type
+		})
+		for _, set := range node.sets {
+			// Occluded names (glue) and RRSets at delegation points (except
+			// DS and the authenticated denial of existence RRSets) are not signed.
+			if !node.occluded && (!node.delegation || set.Type == TypeDS || set.Type == TypeNSEC) {
+				if err := z.signRRSet(set); err != nil {
+					return nil, err
+				}
+			}
+			signed = append(signed, *set)
+		}
+	}
+
+	return signed, nil
+}
+
+func (z *ZoneSigner) addDNSKEYs(apex *signerNode, soa *SignedRRSet) {
+	var dnskeys *SignedRRSet
+	for _, v := range apex.sets {
+		if v.Type == TypeDNSKEY {
+			dnskeys = v
+		}
+	}
+	if dnskeys == nil {
+		dnskeys = &SignedRRSet{RRSet: RRSet{
+			Name:  z.Zone,
+			Type:  TypeDNSKEY,
+			Class: soa.Class,
+			TTL:   soa.TTL,
+		}}
+		apex.sets = append(apex.sets, dnskeys)
+	}
+
+next:
+	for i := range z.Keys {
+		rdata := appendDNSKEYRData(nil, &z.Keys[i].DNSKEY)
+		for _, v := range dnskeys.RData {
+			if bytes.Equal(v, rdata) {
+				continue next
+			}
+		}
+		dnskeys.RData = append(dnskeys.RData, rdata)
+	}
+}
+
+func (z *ZoneSigner) addNSECChain(sorted []*signerNode, class Class, ttl uint32) {
+	for i, node := range sorted {
+		next := sorted[0].name
+		if i+1 != len(sorted) {
+			next = sorted[i+1].name
+		}
+		nsec := ResourceNSEC{
+			NextDomain: next,
+			TypeBitmap: NewTypeBitmap(append(node.types(), TypeNSEC, TypeRRSIG)...),
+		}
+		node.sets = append(node.sets, &SignedRRSet{RRSet: RRSet{
+			Name:  node.name,
+			Type:  TypeNSEC,
+			Class: class,
+			TTL:   ttl,
+			RData: [][]byte{append(nsec.NextDomain.asSlice(), nsec.TypeBitmap...)},
+		}})
+	}
+}
+
+// addNSEC3Chain creates the NSEC3 chain of the sorted nodes, the NSEC3 RRSets are added to nodes.
+func (z *ZoneSigner) addNSEC3Chain(nodes map[string]*signerNode, sorted []*signerNode, class Class, ttl uint32) error {
+	type hashedNode struct {
+		hash  []byte
+		types []Type
+	}
+
+	var (
+		hashed = make([]hashedNode, 0, len(sorted))
+		seen   = make(map[string]bool, len(sorted))
+	)
+
+	addHashed := func(name *Name, types []Type) {
+		key := canonicalNameKey(name)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		hashed = append(hashed, hashedNode{
+			hash:  nsec3Hash(name, z.NSEC3.Salt, z.NSEC3.Iterations),
+			types: types,
+		})
+	}
+
+	for _, node := range sorted {
+		if node.delegation && z.NSEC3.OptOut && !node.hasType(TypeDS) {
+			continue
+		}
+
+		types := node.types()
+		if !node.delegation || node.hasType(TypeDS) {
+			types = append(types, TypeRRSIG)
+		}
+		addHashed(&node.name, types)
+
+		// Empty non-terminals.
+		for p := node.name.parent(); p.isSubdomainOf(&z.Zone) && !p.Equal(&z.Zone); p = p.parent() {
+			if _, ok := nodes[canonicalNameKey(&p)]; ok {
+				continue
+			}
+			addHashed(&p, nil)
+		}
+	}
+
+	sort.Slice(hashed, func(i, j int) bool {
+		return bytes.Compare(hashed[i].hash, hashed[j].hash) < 0
+	})
+
+	var flags uint8
+	if z.NSEC3.OptOut {
+		flags |= NSEC3FlagOptOut
+	}
+
+	for i, v := range hashed {
+		next := hashed[0].hash
+		if i+1 != len(hashed) {
+			next = hashed[i+1].hash
+		}
+		if bytes.Equal(v.hash, next) && len(hashed) != 1 {
+			return errNSEC3HashCollision
+		}
+
+		owner, ok := nsec3OwnerName(v.hash, &z.Zone)
+		if !ok {
+			return errInvalidName
+		}
+
+		nsec3 := ResourceNSEC3{
+			HashAlgorithm:   NSEC3HashAlgorithmSHA1,
+			Flags:           flags,
+			Iterations:      z.NSEC3.Iterations,
+			Salt:            z.NSEC3.Salt,
+			NextHashedOwner: next,
+			TypeBitmap:      NewTypeBitmap(v.types...),
+		}
+
+		node, ok := nodes[canonicalNameKey(&owner)]
+		if !ok {
+			node = &signerNode{name: owner}
+			nodes[canonicalNameKey(&owner)] = node
+		}
+		node.sets = append(node.sets, &SignedRRSet{RRSet: RRSet{
+			Name:  owner,
+			Type:  TypeNSEC3,
+			Class: class,
+			TTL:   ttl,
+			RData: [][]byte{appendNSEC3RData(nil, &nsec3)},
+		}})
+	}
+
+	return nil
+}
+
+func (z *ZoneSigner) signRRSet(set *SignedRRSet) error {
+	allSEP := true
+	for i := range z.Keys {
+		if z.Keys[i].DNSKEY.Flags&DNSKEYFlagSEP == 0 {
+			allSEP = false
+		}
+	}
+
+	labels := set.Name.labelCount()
+	if set.Name.isWildcard() {
+		labels--
+	}
+
+	for i := range z.Keys {
+		key := &z.Keys[i]
+		sep := key.DNSKEY.Flags&DNSKEYFlagSEP != 0
+		if !allSEP && sep != (set.Type == TypeDNSKEY) {
+			continue
+		}
+
+		rrsig := ResourceRRSIG{
+			TypeCovered: set.Type,
+			Algorithm:   key.DNSKEY.Algorithm,
+			Labels:      uint8(labels),
+			OriginalTTL: set.TTL,
+			Expiration:  uint32(z.Expiration.Unix()),
+			Inception:   uint32(z.Inception.Unix()),
+			KeyTag:      key.DNSKEY.KeyTag(),
+			SignerName:  z.Zone,
+		}
+
+		data, err := appendRRSIGSignedData(nil, &set.RRSet, &rrsig)
+		if err != nil {
+			return err
+		}
+
+		rrsig.Signature, err = key.sign(z.Rand, data)
+		if err != nil {
+			return err
+		}
+		set.Signatures = append(set.Signatures, rrsig)
+	}
+	return nil
+}
+
+// sign signs data using the key, the returned signature is encoded as required by DNSSEC.
+func (key *SigningKey) sign(r io.Reader, data []byte) ([]byte, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+
+	switch key.DNSKEY.Algorithm {
+	case DNSSECAlgorithmRSASHA256:
+		h := sha256.Sum256(data)
+		return key.Signer.Sign(r, h[:], crypto.SHA256)
+	case DNSSECAlgorithmRSASHA512:
+		h := sha512.Sum512(data)
+		return key.Signer.Sign(r, h[:], crypto.SHA512)
+	case DNSSECAlgorithmECDSAP256SHA256:
+		h := sha256.Sum256(data)
+		sig, err := key.Signer.Sign(r, h[:], crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsaSignatureFromASN1(sig, 32)
+	case DNSSECAlgorithmECDSAP384SHA384:
+		h := sha512.Sum384(data)
+		sig, err := key.Signer.Sign(r, h[:], crypto.SHA384)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsaSignatureFromASN1(sig, 48)
+	case DNSSECAlgorithmED25519:
+		return key.Signer.Sign(r, data, crypto.Hash(0))
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// ecdsaSignatureFromASN1 converts an ASN.1 encoded ECDSA signature (as produced by
+// [crypto/ecdsa.PrivateKey.Sign]) into the format described in RFC 6605, Section 4.
+func ecdsaSignatureFromASN1(sig []byte, size int) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(sig, &parsed)
+	if err != nil || len(rest) != 0 {
+		return nil, errSignerInvalidSignature
+	}
+	if parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 || parsed.R.BitLen() > size*8 || parsed.S.BitLen() > size*8 {
+		return nil, errSignerInvalidSignature
+	}
+	out := make([]byte, 2*size)
+	parsed.R.FillBytes(out[:size])
+	parsed.S.FillBytes(out[size:])
+	return out, nil
+}
@@ -0,0 +1,260 @@
+package dnsmsg
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func testZone() []RRSet {
+	soa := append(nameAsSlice("ns1.example.com"), nameAsSlice("admin.example.com")...)
+	soa = appendUint32(soa, 2023010101)
+	soa = appendUint32(soa, 3600)
+	soa = appendUint32(soa, 600)
+	soa = appendUint32(soa, 86400)
+	soa = appendUint32(soa, 300)
+
+	rrset := func(name string, typ Type, rdata ...[]byte) RRSet {
+		return RRSet{Name: MustParseName(name), Type: typ, Class: ClassIN, TTL: 3600, RData: rdata}
+	}
+
+	return []RRSet{
+		rrset("example.com", TypeSOA, soa),
+		rrset("example.com", TypeNS, nameAsSlice("ns1.example.com")),
+		rrset("ns1.example.com", TypeA, []byte{192, 0, 2, 1}),
+		rrset("www.example.com", TypeA, []byte{192, 0, 2, 2}, []byte{192, 0, 2, 3}),
+		rrset("*.wild.example.com", TypeTXT, []byte{4, 't', 'e', 's', 't'}),
+		rrset("a.b.example.com", TypeA, []byte{192, 0, 2, 4}),
+		rrset("insecure.example.com", TypeNS, nameAsSlice("ns.insecure.example.com")),
+		rrset("ns.insecure.example.com", TypeA, []byte{192, 0, 2, 5}),
+		rrset("secure.example.com", TypeNS, nameAsSlice("ns.example.net")),
+		rrset("secure.example.com", TypeDS, []byte{1, 2, byte(DNSSECAlgorithmED25519), byte(DigestTypeSHA256), 1, 2, 3}),
+	}
+}
+
+func testZoneSigner(t *testing.T) (ZoneSigner, []ResourceDNSKEY) {
+	ksPub, ksPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zsPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zsPub := make([]byte, 64)
+	zsPriv.X.FillBytes(zsPub[:32])
+	zsPriv.Y.FillBytes(zsPub[32:])
+
+	ksk := ResourceDNSKEY{
+		Flags:     DNSKEYFlagZone | DNSKEYFlagSEP,
+		Protocol:  DNSKEYProtocol,
+		Algorithm: DNSSECAlgorithmED25519,
+		PublicKey: ksPub,
+	}
+	zsk := ResourceDNSKEY{
+		Flags:     DNSKEYFlagZone,
+		Protocol:  DNSKEYProtocol,
+		Algorithm: DNSSECAlgorithmECDSAP256SHA256,
+		PublicKey: zsPub,
+	}
+
+	return ZoneSigner{
+		Zone:       MustParseName("example.com"),
+		Keys:       []SigningKey{{DNSKEY: ksk, Signer: ksPriv}, {DNSKEY: zsk, Signer: zsPriv}},
+		Inception:  time.Unix(1700000000, 0).Add(-time.Hour),
+		Expiration: time.Unix(1700000000, 0).Add(time.Hour),
+	}, []ResourceDNSKEY{ksk, zsk}
+}
+
+func verifySignedZone(t *testing.T, zone []SignedRRSet, keys []ResourceDNSKEY) {
+	v := Verifier{Now: func() time.Time { return time.Unix(1700000000, 0) }}
+
+	insecure := MustParseName("insecure.example.com")
+	apex := MustParseName("example.com")
+
+	for i := range zone {
+		set := &zone[i]
+		expectUnsigned := (set.Type == TypeNS && !set.Name.Equal(&apex)) ||
+			(set.Type != TypeNSEC && set.Type != TypeNSEC3 && set.Name.isSubdomainOf(&insecure))
+		if expectUnsigned {
+			if len(set.Signatures) != 0 {
+				t.Errorf("%v %v: unexpected signatures", set.Name.String(), set.Type)
+			}
+			continue
+		}
+
+		if len(set.Signatures) != 1 {
+			t.Errorf("%v %v: got %v signatures, want: 1", set.Name.String(), set.Type, len(set.Signatures))
+		}
+
+		for j := range set.Signatures {
+			sig := &set.Signatures[j]
+			if set.Type == TypeDNSKEY && sig.Algorithm != DNSSECAlgorithmED25519 {
+				t.Errorf("DNSKEY RRSet signed by a non-SEP key")
+			}
+			if set.Type != TypeDNSKEY && sig.Algorithm != DNSSECAlgorithmECDSAP256SHA256 {
+				t.Errorf("%v %v: RRSet signed by a SEP key", set.Name.String(), set.Type)
+			}
+			if _, err := v.Verify(&set.RRSet, sig, keys); err != nil {
+				t.Errorf("%v %v: Verify() unexpected error: %v", set.Name.String(), set.Type, err)
+			}
+		}
+	}
+}
+
+func TestZoneSignerNSEC(t *testing.T) {
+	signer, keys := testZoneSigner(t)
+	zone, err := signer.Sign(testZone())
+	if err != nil {
+		t.Fatalf("signer.Sign() unexpected error: %v", err)
+	}
+
+	verifySignedZone(t, zone, keys)
+
+	for i := 1; i < len(zone); i++ {
+		if zone[i-1].Name.Compare(&zone[i].Name) > 0 {
+			t.Fatalf("signed zone is not sorted in the canonical order")
+		}
+	}
+
+	expectChain := []struct {
+		name  string
+		types []Type
+	}{
+		{"example.com", []Type{TypeNS, TypeSOA, TypeRRSIG, TypeNSEC, TypeDNSKEY}},
+		{"a.b.example.com", []Type{TypeA, TypeRRSIG, TypeNSEC}},
+		{"insecure.example.com", []Type{TypeNS, TypeRRSIG, TypeNSEC}},
+		{"ns1.example.com", []Type{TypeA, TypeRRSIG, TypeNSEC}},
+		{"secure.example.com", []Type{TypeNS, TypeDS, TypeRRSIG, TypeNSEC}},
+		{"*.wild.example.com", []Type{TypeTXT, TypeRRSIG, TypeNSEC}},
+		{"www.example.com", []Type{TypeA, TypeRRSIG, TypeNSEC}},
+	}
+
+	var nsecs []*SignedRRSet
+	for i := range zone {
+		if zone[i].Type == TypeNSEC {
+			nsecs = append(nsecs, &zone[i])
+		}
+	}
+
+	if len(nsecs) != len(expectChain) {
+		t.Fatalf("got %v NSEC RRSets, want: %v", len(nsecs), len(expectChain))
+	}
+
+	for i, nsec := range nsecs {
+		expect := expectChain[i]
+		expectNext := expectChain[(i+1)%len(expectChain)].name
+
+		if name := nsec.Name.String(); name != expect.name+"." {
+			t.Errorf("nsec[%v].Name = %v, want: %v", i, name, expect.name+".")
+		}
+		if nsec.TTL != 300 {
+			t.Errorf("nsec[%v].TTL = %v, want: 300", i, nsec.TTL)
+		}
+
+		next := MustParseName(expectNext)
+		if len(nsec.RData) != 1 || string(nsec.RData[0][:next.Length]) != string(next.asSlice()) {
+			t.Errorf("nsec[%v] next domain does not point to %v", i, expectNext)
+			continue
+		}
+		bitmap := TypeBitmap(nsec.RData[0][next.Length:])
+		if !equalTypes(bitmap.Types(), expect.types) {
+			t.Errorf("nsec[%v] types = %v, want: %v", i, bitmap.Types(), expect.types)
+		}
+	}
+}
+
+func equalTypes(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestZoneSignerNSEC3(t *testing.T) {
+	signer, keys := testZoneSigner(t)
+	signer.NSEC3 = &NSEC3Params{
+		Iterations: 5,
+		Salt:       []byte{0xAA, 0xBB},
+		OptOut:     true,
+	}
+
+	zone, err := signer.Sign(testZone())
+	if err != nil {
+		t.Fatalf("signer.Sign() unexpected error: %v", err)
+	}
+
+	verifySignedZone(t, zone, keys)
+
+	apex := MustParseName("example.com")
+	hashedNames := map[string]bool{}
+	for _, name := range []string{"example.com", "a.b.example.com", "b.example.com", "ns1.example.com", "secure.example.com", "*.wild.example.com", "wild.example.com", "www.example.com"} {
+		n := MustParseName(name)
+		owner, _ := nsec3OwnerName(nsec3Hash(&n, signer.NSEC3.Salt, signer.NSEC3.Iterations), &apex)
+		hashedNames[canonicalNameKey(&owner)] = true
+	}
+
+	var (
+		nsec3Count      int
+		nsec3ParamFound bool
+	)
+	for i := range zone {
+		set := &zone[i]
+		switch set.Type {
+		case TypeNSEC3PARAM:
+			nsec3ParamFound = true
+			if !set.Name.Equal(&apex) {
+				t.Errorf("NSEC3PARAM RRSet is not at zone apex")
+			}
+		case TypeNSEC3:
+			nsec3Count++
+			if !hashedNames[canonicalNameKey(&set.Name)] {
+				t.Errorf("unexpected NSEC3 owner name: %v", set.Name.String())
+			}
+			rdata := set.RData[0]
+			if rdata[0] != uint8(NSEC3HashAlgorithmSHA1) || rdata[1] != NSEC3FlagOptOut ||
+				unpackUint16(rdata[2:]) != 5 || rdata[4] != 2 || rdata[5] != 0xAA || rdata[6] != 0xBB || rdata[7] != 20 {
+				t.Errorf("unexpected NSEC3 resource data: %v", rdata)
+			}
+		}
+	}
+
+	if !nsec3ParamFound {
+		t.Errorf("NSEC3PARAM RRSet not found")
+	}
+	if nsec3Count != len(hashedNames) {
+		t.Errorf("got %v NSEC3 RRSets, want: %v", nsec3Count, len(hashedNames))
+	}
+}
+
+func TestZoneSignerErrors(t *testing.T) {
+	signer, _ := testZoneSigner(t)
+
+	zone := testZone()
+	if _, err := signer.Sign(zone[1:]); err != errMissingSOA {
+		t.Errorf("signer.Sign() unexpected error: %v, want: %v", err, errMissingSOA)
+	}
+
+	zone = append(testZone(), RRSet{Name: MustParseName("example.net"), Type: TypeA, Class: ClassIN, RData: [][]byte{{1, 1, 1, 1}}})
+	if _, err := signer.Sign(zone); err != errNotInZone {
+		t.Errorf("signer.Sign() unexpected error: %v, want: %v", err, errNotInZone)
+	}
+
+	zone = append(testZone(), RRSet{Name: MustParseName("WWW.example.com"), Type: TypeA, Class: ClassIN, RData: [][]byte{{1, 1, 1, 1}}})
+	if _, err := signer.Sign(zone); err != errDuplicateRRSet {
+		t.Errorf("signer.Sign() unexpected error: %v, want: %v", err, errDuplicateRRSet)
+	}
+
+	signer.Keys = nil
+	if _, err := signer.Sign(testZone()); err != errNoSigningKeys {
+		t.Errorf("signer.Sign() unexpected error: %v, want: %v", err, errNoSigningKeys)
+	}
+}
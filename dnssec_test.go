@@ -0,0 +1,358 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestDNSSECResources(t *testing.T) {
+	hdr := ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Class: ClassIN,
+		TTL:   3600,
+	}
+
+	var (
+		dnskey = ResourceDNSKEY{
+			Flags:     DNSKEYFlagZone | DNSKEYFlagSEP,
+			Protocol:  DNSKEYProtocol,
+			Algorithm: DNSSECAlgorithmECDSAP256SHA256,
+			PublicKey: bytes.Repeat([]byte{1, 2, 3, 4}, 16),
+		}
+		cdnskey = ResourceCDNSKEY{
+			Flags:     DNSKEYFlagZone,
+			Protocol:  DNSKEYProtocol,
+			Algorithm: DNSSECAlgorithmED25519,
+			PublicKey: bytes.Repeat([]byte{5}, 32),
+		}
+		ds = ResourceDS{
+			KeyTag:     12345,
+			Algorithm:  DNSSECAlgorithmRSASHA256,
+			DigestType: DigestTypeSHA256,
+			Digest:     bytes.Repeat([]byte{0xAB}, 32),
+		}
+		cds = ResourceCDS{
+			KeyTag:     54321,
+			Algorithm:  DNSSECAlgorithmRSASHA512,
+			DigestType: DigestTypeSHA384,
+			Digest:     bytes.Repeat([]byte{0xCD}, 48),
+		}
+		rrsig = ResourceRRSIG{
+			TypeCovered: TypeA,
+			Algorithm:   DNSSECAlgorithmECDSAP256SHA256,
+			Labels:      2,
+			OriginalTTL: 3600,
+			Expiration:  1700000000,
+			Inception:   1690000000,
+			KeyTag:      12345,
+			SignerName:  MustParseName("example.com"),
+			Signature:   bytes.Repeat([]byte{0xEF}, 64),
+		}
+		nsec = ResourceNSEC{
+			NextDomain: MustParseName("www.example.com"),
+			TypeBitmap: NewTypeBitmap(TypeA, TypeNS, TypeSOA, TypeRRSIG, TypeNSEC, TypeDNSKEY, TypeCAA),
+		}
+		nsec3 = ResourceNSEC3{
+			HashAlgorithm:   NSEC3HashAlgorithmSHA1,
+			Flags:           NSEC3FlagOptOut,
+			Iterations:      10,
+			Salt:            []byte{0xAA, 0xBB, 0xCC, 0xDD},
+			NextHashedOwner: bytes.Repeat([]byte{0x11}, 20),
+			TypeBitmap:      NewTypeBitmap(TypeA, TypeRRSIG),
+		}
+		nsec3param = ResourceNSEC3PARAM{
+			HashAlgorithm: NSEC3HashAlgorithmSHA1,
+			Iterations:    10,
+			Salt:          []byte{0xAA, 0xBB, 0xCC, 0xDD},
+		}
+	)
+
+	b := StartBuilder(make([]byte, 0, 1024), 0, 0)
+	b.StartAnswers()
+
+	if err := b.ResourceDNSKEY(hdr, dnskey); err != nil {
+		t.Fatalf("b.ResourceDNSKEY() unexpected error: %v", err)
+	}
+	if err := b.ResourceCDNSKEY(hdr, cdnskey); err != nil {
+		t.Fatalf("b.ResourceCDNSKEY() unexpected error: %v", err)
+	}
+	if err := b.ResourceDS(hdr, ds); err != nil {
+		t.Fatalf("b.ResourceDS() unexpected error: %v", err)
+	}
+	if err := b.ResourceCDS(hdr, cds); err != nil {
+		t.Fatalf("b.ResourceCDS() unexpected error: %v", err)
+	}
+	if err := b.ResourceRRSIG(hdr, rrsig); err != nil {
+		t.Fatalf("b.ResourceRRSIG() unexpected error: %v", err)
+	}
+	if err := b.ResourceNSEC(hdr, nsec); err != nil {
+		t.Fatalf("b.ResourceNSEC() unexpected error: %v", err)
+	}
+	if err := b.ResourceNSEC3(hdr, nsec3); err != nil {
+		t.Fatalf("b.ResourceNSEC3() unexpected error: %v", err)
+	}
+	if err := b.ResourceNSEC3PARAM(hdr, nsec3param); err != nil {
+		t.Fatalf("b.ResourceNSEC3PARAM() unexpected error: %v", err)
+	}
+
+	msg := b.Bytes()
+
+	// The signer name of the RRSIG and the next domain of the NSEC must not be compressed,
+	// example.com is expected to appear in: the first resource header, RRSIG signer
+	// name and at the end of the NSEC next domain.
+	if c := bytes.Count(msg, nameAsSlice("example.com")); c != 3 {
+		t.Errorf("example.com found %v times in the message, want: 3", c)
+	}
+	if c := bytes.Count(msg, nameAsSlice("www.example.com")); c != 1 {
+		t.Errorf("www.example.com found %v times in the message, want: 1", c)
+	}
+
+	p, _, err := Parse(msg)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+
+	next := func(expect Type) {
+		rhdr, err := p.ResourceHeader()
+		if err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		if rhdr.Type != expect {
+			t.Fatalf("rhdr.Type = %v, want: %v", rhdr.Type, expect)
+		}
+	}
+
+	next(TypeDNSKEY)
+	resDNSKEY, err := p.ResourceDNSKEY()
+	if err != nil {
+		t.Fatalf("p.ResourceDNSKEY() unexpected error: %v", err)
+	}
+	equalRData(t, "p.ResourceDNSKEY()", dnskey, resDNSKEY)
+
+	next(TypeCDNSKEY)
+	if _, err := p.ResourceDNSKEY(); err != errInvalidOperation {
+		t.Fatalf("p.ResourceDNSKEY() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+	resCDNSKEY, err := p.ResourceCDNSKEY()
+	if err != nil {
+		t.Fatalf("p.ResourceCDNSKEY() unexpected error: %v", err)
+	}
+	equalRData(t, "p.ResourceCDNSKEY()", cdnskey, resCDNSKEY)
+
+	next(TypeDS)
+	resDS, err := p.ResourceDS()
+	if err != nil {
+		t.Fatalf("p.ResourceDS() unexpected error: %v", err)
+	}
+	equalRData(t, "p.ResourceDS()", ds, resDS)
+
+	next(TypeCDS)
+	resCDS, err := p.ResourceCDS()
+	if err != nil {
+		t.Fatalf("p.ResourceCDS() unexpected error: %v", err)
+	}
+	equalRData(t, "p.ResourceCDS()", cds, resCDS)
+
+	next(TypeRRSIG)
+	resRRSIG, err := p.ResourceRRSIG()
+	if err != nil {
+		t.Fatalf("p.ResourceRRSIG() unexpected error: %v", err)
+	}
+	equalRData(t, "p.ResourceRRSIG()", rrsig, resRRSIG)
+
+	next(TypeNSEC)
+	resNSEC, err := p.ResourceNSEC()
+	if err != nil {
+		t.Fatalf("p.ResourceNSEC() unexpected error: %v", err)
+	}
+	expectParserName(t, "p.ResourceNSEC().NextDomain", resNSEC.NextDomain, "www.example.com", false)
+	if !bytes.Equal(resNSEC.TypeBitmap, nsec.TypeBitmap) {
+		t.Errorf("p.ResourceNSEC().TypeBitmap = %v, want: %v", resNSEC.TypeBitmap, nsec.TypeBitmap)
+	}
+
+	next(TypeNSEC3)
+	resNSEC3, err := p.ResourceNSEC3()
+	if err != nil {
+		t.Fatalf("p.ResourceNSEC3() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(resNSEC3, nsec3) {
+		t.Errorf("p.ResourceNSEC3() = %#v, want: %#v", resNSEC3, nsec3)
+	}
+	if !resNSEC3.OptOut() {
+		t.Errorf("p.ResourceNSEC3().OptOut() = false, want: true")
+	}
+
+	next(TypeNSEC3PARAM)
+	resNSEC3PARAM, err := p.ResourceNSEC3PARAM()
+	if err != nil {
+		t.Fatalf("p.ResourceNSEC3PARAM() unexpected error: %v", err)
+	}
+	equalRData(t, "p.ResourceNSEC3PARAM()", nsec3param, resNSEC3PARAM)
+
+	if err := p.End(); err != nil {
+		t.Fatalf("p.End() unexpected error: %v", err)
+	}
+}
+
+func TestTypeBitmap(t *testing.T) {
+	// Example from RFC 4034, Section 4.3.
+	bitmap := NewTypeBitmap(TypeA, TypeMX, TypeRRSIG, TypeNSEC, 1234, TypeA)
+	expect := TypeBitmap{
+		0x00, 0x06, 0x40, 0x01, 0x00, 0x00, 0x00, 0x03,
+		0x04, 0x1b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x20,
+	}
+	if !bytes.Equal(bitmap, expect) {
+		t.Fatalf("NewTypeBitmap() = %v, want: %v", bitmap, expect)
+	}
+
+	if !bitmap.isValid() {
+		t.Fatalf("bitmap.isValid() = false, want: true")
+	}
+
+	expectTypes := []Type{TypeA, TypeMX, TypeRRSIG, TypeNSEC, 1234}
+	if types := bitmap.Types(); !reflect.DeepEqual(types, expectTypes) {
+		t.Fatalf("bitmap.Types() = %v, want: %v", types, expectTypes)
+	}
+
+	for _, tt := range expectTypes {
+		if !bitmap.Contains(tt) {
+			t.Errorf("bitmap.Contains(%v) = false, want: true", tt)
+		}
+	}
+
+	for _, tt := range []Type{TypeNS, TypeAAAA, TypeDNSKEY, 1235, 1233, 65535, 256} {
+		if bitmap.Contains(tt) {
+			t.Errorf("bitmap.Contains(%v) = true, want: false", tt)
+		}
+	}
+
+	if b := NewTypeBitmap(); len(b) != 0 || !b.isValid() || len(b.Types()) != 0 {
+		t.Errorf("NewTypeBitmap() = %v, want empty bitmap", b)
+	}
+
+	invalid := []TypeBitmap{
+		{0},
+		{0, 0},
+		{0, 33, 1},
+		{0, 2, 1},
+		{1, 1, 1, 0, 1, 1},
+		{1, 1, 1, 1, 1, 1},
+	}
+	for _, b := range invalid {
+		if b.isValid() {
+			t.Errorf("%v.isValid() = true, want: false", b)
+		}
+	}
+}
+
+func TestDNSKEYKeyTag(t *testing.T) {
+	// Example from RFC 4034, Section 5.4.
+	key, err := base64.StdEncoding.DecodeString(
+		"AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw==",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dnskey := ResourceDNSKEY{
+		Flags:     DNSKEYFlagZone,
+		Protocol:  DNSKEYProtocol,
+		Algorithm: DNSSECAlgorithmRSASHA1,
+		PublicKey: key,
+	}
+
+	if tag := dnskey.KeyTag(); tag != 60485 {
+		t.Fatalf("dnskey.KeyTag() = %v, want: 60485", tag)
+	}
+}
+
+func TestDNSSECResourcesInvalid(t *testing.T) {
+	hdr := ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Class: ClassIN,
+	}
+
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+
+	if err := b.ResourceNSEC(hdr, ResourceNSEC{NextDomain: MustParseName("."), TypeBitmap: TypeBitmap{0, 0}}); err != errInvalidTypeBitmap {
+		t.Errorf("b.ResourceNSEC() unexpected error: %v, want: %v", err, errInvalidTypeBitmap)
+	}
+	if err := b.ResourceNSEC3(hdr, ResourceNSEC3{}); err != errInvalidNSEC3 {
+		t.Errorf("b.ResourceNSEC3() unexpected error: %v, want: %v", err, errInvalidNSEC3)
+	}
+	if err := b.ResourceNSEC3(hdr, ResourceNSEC3{NextHashedOwner: []byte{1}, Salt: make([]byte, 256)}); err != errInvalidNSEC3 {
+		t.Errorf("b.ResourceNSEC3() unexpected error: %v, want: %v", err, errInvalidNSEC3)
+	}
+	if err := b.ResourceNSEC3PARAM(hdr, ResourceNSEC3PARAM{Salt: make([]byte, 256)}); err != errInvalidNSEC3 {
+		t.Errorf("b.ResourceNSEC3PARAM() unexpected error: %v, want: %v", err, errInvalidNSEC3)
+	}
+	if b.Length() != headerLen || b.Header().ANCount != 0 {
+		t.Fatalf("builder modified after errors")
+	}
+
+	cases := []struct {
+		typ   Type
+		rdata []byte
+	}{
+		{TypeDNSKEY, []byte{1, 0, 3}},
+		{TypeDS, []byte{1, 0, 3}},
+		{TypeRRSIG, make([]byte, 17)},
+		{TypeNSEC, []byte{0, 0, 0}},
+		{TypeNSEC3, []byte{1, 0, 0, 10, 4, 1, 2, 3}},
+		{TypeNSEC3, []byte{1, 0, 0, 10, 0, 0}},
+		{TypeNSEC3PARAM, []byte{1, 0, 0, 10, 2, 1}},
+	}
+
+	for _, tt := range cases {
+		b := StartBuilder(make([]byte, 0, 512), 0, 0)
+		b.StartAnswers()
+		rdb, err := b.RDBuilder(ResourceHeader{
+			Name:  MustParseName("example.com"),
+			Type:  tt.typ,
+			Class: ClassIN,
+		})
+		if err != nil {
+			t.Fatalf("b.RDBuilder() unexpected error: %v", err)
+		}
+		rdb.Bytes(tt.rdata)
+		rdb.End()
+
+		p, _, err := Parse(b.Bytes())
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		if err := p.StartAnswers(); err != nil {
+			t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+		}
+		if _, err := p.ResourceHeader(); err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+
+		switch tt.typ {
+		case TypeDNSKEY:
+			_, err = p.ResourceDNSKEY()
+		case TypeDS:
+			_, err = p.ResourceDS()
+		case TypeRRSIG:
+			_, err = p.ResourceRRSIG()
+		case TypeNSEC:
+			_, err = p.ResourceNSEC()
+		case TypeNSEC3:
+			_, err = p.ResourceNSEC3()
+		case TypeNSEC3PARAM:
+			_, err = p.ResourceNSEC3PARAM()
+		}
+		if err != errInvalidDNSMessage {
+			t.Errorf("parsing %v resource with resource data %v, unexpected error: %v, want: %v", tt.typ, tt.rdata, err, errInvalidDNSMessage)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrSignatureExpired is returned by [Verifier.Verify] when the
+	// current time is after the expiration time of the RRSIG.
+	ErrSignatureExpired = errors.New("signature expired")
+
+	// ErrSignatureNotYetValid is returned by [Verifier.Verify] when the
+	// current time is before the inception time of the RRSIG.
+	ErrSignatureNotYetValid = errors.New("signature not yet valid")
+
+	// ErrNoMatchingKey is returned by [Verifier.Verify] when none of the
+	// provided DNSKEYs can be used to verify the RRSIG.
+	ErrNoMatchingKey = errors.New("no DNSKEY matching the RRSIG")
+
+	// ErrUnsupportedAlgorithm is returned by [Verifier.Verify] when the RRSIG
+	// uses an unsupported DNSSEC algorithm.
+	ErrUnsupportedAlgorithm = errors.New("unsupported DNSSEC algorithm")
+
+	// ErrInvalidSignature is returned by [Verifier.Verify] when the
+	// signature does not verify with any of the matching DNSKEYs.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	errRRSetMismatch = errors.New("resource does not belong to the RRSet")
+	errRRSIGMismatch = errors.New("RRSIG does not cover the RRSet")
+)
+
+// RRSet is a set of resources with the same owner name, type and class.
+type RRSet struct {
+	Name  Name
+	Type  Type
+	Class Class
+	TTL   uint32
+
+	// RData contains the uncompressed resource data of each resource in the RRSet.
+	RData [][]byte
+}
+
+// AppendResource parses the resource data of the next resource (using p) and appends it
+// to the RRSet. hdr must be the [ResourceHeader] returned by the last call to [Parser.ResourceHeader].
+//
+// When the RRSet is empty, the Name, Type, Class and TTL fields are set from hdr, otherwise
+// hdr must match the RRSet and the TTL is set to the lowest TTL of all resources in the RRSet.
+func (s *RRSet) AppendResource(p *Parser, hdr ResourceHeader) error {
+	if !p.resourceData || p.nextResourceType != hdr.Type {
+		return errInvalidOperation
+	}
+
+	if len(s.RData) == 0 {
+		s.Name = hdr.Name
+		s.Type = hdr.Type
+		s.Class = hdr.Class
+		s.TTL = hdr.TTL
+	} else {
+		if s.Type != hdr.Type || s.Class != hdr.Class || !s.Name.Equal(&hdr.Name) {
+			return errRRSetMismatch
+		}
+		if hdr.TTL < s.TTL {
+			s.TTL = hdr.TTL
+		}
+	}
+
+	rdata, err := p.appendUncompressedResourceData(make([]byte, 0, hdr.Length))
+	if err != nil {
+		return err
+	}
+	s.RData = append(s.RData, rdata)
+	return nil
+}
+
+// Verifier verifies DNSSEC signatures (RFC 4034, RFC 4035).
+//
+// The zero value is ready to use.
+type Verifier struct {
+	// Now returns the current time, it is used to check the inception
+	// and expiration times of the RRSIGs. When nil, [time.Now] is used.
+	Now func() time.Time
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// Verify verifies the signature of rrsig over set, using one of the provided keys.
+//
+// keys should contain the DNSKEYs of the zone named by the SignerName of rrsig,
+// only the keys with a matching algorithm and key tag, with the zone flag set
+// and without the revoke flag are tried.
+//
+// RRSIGs produced by a wildcard expansion (with the Labels field lower than the amount of labels
+// in the owner name) are verified against the wildcard owner name (RFC 4035, Section 5.3.2).
+//
+// On success it returns the TTL that should be used for the set (RFC 4035, Section 5.3.3),
+// that is the lowest value of: the TTL of set, the OriginalTTL of rrsig and the amount of seconds
+// remaining until the signature expires. Callers should further limit it to the TTL of the RRSIG resource.
+func (v *Verifier) Verify(set *RRSet, rrsig *ResourceRRSIG, keys []ResourceDNSKEY) (uint32, error) {
+	if rrsig.TypeCovered != set.Type || !set.Name.isSubdomainOf(&rrsig.SignerName) {
+		return 0, errRRSIGMismatch
+	}
+
+	now := uint32(v.now().Unix())
+	if int32(now-rrsig.Inception) < 0 {
+		return 0, ErrSignatureNotYetValid
+	}
+	if int32(rrsig.Expiration-now) < 0 {
+		return 0, ErrSignatureExpired
+	}
+
+	if !isSupportedAlgorithm(rrsig.Algorithm) {
+		return 0, ErrUnsupportedAlgorithm
+	}
+
+	signedData, err := appendRRSIGSignedData(nil, set, rrsig)
+	if err != nil {
+		return 0, err
+	}
+
+	matchingKey := false
+	for i := range keys {
+		key := &keys[i]
+		if key.Algorithm != rrsig.Algorithm || key.Protocol != DNSKEYProtocol ||
+			key.Flags&DNSKEYFlagZone == 0 || key.Flags&DNSKEYFlagRevoke != 0 ||
+			key.KeyTag() != rrsig.KeyTag {
+			continue
+		}
+		matchingKey = true
+		if verifySignature(key, signedData, rrsig.Signature) {
+			ttl := set.TTL
+			if rrsig.OriginalTTL < ttl {
+				ttl = rrsig.OriginalTTL
+			}
+			if remaining := rrsig.Expiration - now; remaining < ttl {
+				ttl = remaining
+			}
+			return ttl, nil
+		}
+	}
+
+	if !matchingKey {
+		return 0, ErrNoMatchingKey
+	}
+	return 0, ErrInvalidSignature
+}
+
+func isSupportedAlgorithm(alg DNSSECAlgorithm) bool {
+	switch alg {
+	case DNSSECAlgorithmRSASHA256, DNSSECAlgorithmRSASHA512,
+		DNSSECAlgorithmECDSAP256SHA256, DNSSECAlgorithmECDSAP384SHA384,
+		DNSSECAlgorithmED25519:
+		return true
+	}
+	return false
+}
+
+func verifySignature(key *ResourceDNSKEY, signedData, sig []byte) bool {
+	switch key.Algorithm {
+	case DNSSECAlgorithmRSASHA256, DNSSECAlgorithmRSASHA512:
+		pub, ok := parseRSAPublicKey(key.PublicKey)
+		if !ok {
+			return false
+		}
+		if key.Algorithm == DNSSECAlgorithmRSASHA256 {
+			h := sha256.Sum256(signedData)
+			return rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], sig) == nil
+		}
+		h := sha512.Sum512(signedData)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA512, h[:], sig) == nil
+	case DNSSECAlgorithmECDSAP256SHA256:
+		h := sha256.Sum256(signedData)
+		return verifyECDSA(elliptic.P256(), 32, key.PublicKey, h[:], sig)
+	case DNSSECAlgorithmECDSAP384SHA384:
+		h := sha512.Sum384(signedData)
+		return verifyECDSA(elliptic.P384(), 48, key.PublicKey, h[:], sig)
+	case DNSSECAlgorithmED25519:
+		if len(key.PublicKey) != ed25519.PublicKeySize || len(sig) != ed25519.SignatureSize {
+			return false
+		}
+		return ed25519.Verify(ed25519.PublicKey(key.PublicKey), signedData, sig)
+	}
+	return false
+}
+
+// parseRSAPublicKey parses a RSA public key encoded as described in RFC 3110, Section 2.
+func parseRSAPublicKey(key []byte) (*rsa.PublicKey, bool) {
+	if len(key) < 1 {
+		return nil, false
+	}
+	expLen := int(key[0])
+	key = key[1:]
+	if expLen == 0 {
+		if len(key) < 2 {
+			return nil, false
+		}
+		expLen = int(unpackUint16(key))
+		key = key[2:]
+	}
+
+	// Exponents larger than 32 bits are not supported by crypto/rsa.
+	if expLen == 0 || expLen > 4 || len(key) <= expLen {
+		return nil, false
+	}
+
+	exp := 0
+	for _, v := range key[:expLen] {
+		exp = exp<<8 | int(v)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(key[expLen:]),
+		E: exp,
+	}, true
+}
+
+func verifyECDSA(curve elliptic.Curve, size int, key, hash, sig []byte) bool {
+	if len(key) != 2*size || len(sig) != 2*size {
+		return false
+	}
+	pub := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(key[:size]),
+		Y:     new(big.Int).SetBytes(key[size:]),
+	}
+	r := new(big.Int).SetBytes(sig[:size])
+	s := new(big.Int).SetBytes(sig[size:])
+	return ecdsa.Verify(pub, hash, r, s)
+}
+
+// appendRRSIGRData appends the resource data of rrsig to dst, with uncompressed SignerName.
+func appendRRSIGRData(dst []byte, rrsig *ResourceRRSIG, includeSignature bool) []byte {
+	dst = appendUint16(dst, uint16(rrsig.TypeCovered))
+	dst = append(dst, uint8(rrsig.Algorithm), rrsig.Labels)
+	dst = appendUint32(dst, rrsig.OriginalTTL)
+	dst = appendUint32(dst, rrsig.Expiration)
+	dst = appendUint32(dst, rrsig.Inception)
+	dst = appendUint16(dst, rrsig.KeyTag)
+	dst = append(dst, rrsig.SignerName.asSlice()...)
+	if includeSignature {
+		dst = append(dst, rrsig.Signature...)
+	}
+	return dst
+}
+
+// appendRRSIGSignedData appends the data covered by the RRSIG signature
+// to dst (RFC 4034, Section 3.1.8.1).
+func appendRRSIGSignedData(dst []byte, set *RRSet, rrsig *ResourceRRSIG) ([]byte, error) {
+	owner := set.Name
+	labels := owner.labelCount()
+	if owner.isWildcard() {
+		labels--
+	}
+
+	if int(rrsig.Labels) > labels {
+		return dst, errRRSIGMismatch
+	}
+
+	if int(rrsig.Labels) < labels {
+		owner = wildcardName(&owner, int(rrsig.Labels))
+	}
+	owner.lowerASCII()
+
+	dstStart := len(dst)
+	dst = appendRRSIGRData(dst, rrsig, false)
+	lowerASCII(dst[dstStart+18:])
+
+	rdatas, err := canonicalRDatas(set.Type, set.RData)
+	if err != nil {
+		return dst, err
+	}
+
+	for _, rdata := range rdatas {
+		dst = append(dst, owner.asSlice()...)
+		dst = appendUint16(dst, uint16(set.Type))
+		dst = appendUint16(dst, uint16(set.Class))
+		dst = appendUint32(dst, rrsig.OriginalTTL)
+		dst = appendUint16(dst, uint16(len(rdata)))
+		dst = append(dst, rdata...)
+	}
+
+	return dst, nil
+}
+
+// wildcardName returns the wildcard name ("*." followed by the labels rightmost labels of n).
+func wildcardName(n *Name, labels int) Name {
+	var offsetsBuf [128]uint8
+	offsets := n.labelOffsets(offsetsBuf[:0])
+
+	suffixStart := int(n.Length) - 1
+	if labels > 0 {
+		suffixStart = int(offsets[len(offsets)-labels])
+	}
+
+	w := Name{Length: 2}
+	w.Name[0] = 1
+	w.Name[1] = '*'
+	w.Length += uint8(copy(w.Name[2:], n.Name[suffixStart:n.Length]))
+	return w
+}
+
+// canonicalRDatas returns the resource datas in the canonical form (RFC 4034, Section 6.2),
+// sorted in the canonical order (RFC 4034, Section 6.3), without duplicates.
+func canonicalRDatas(typ Type, rdatas [][]byte) ([][]byte, error) {
+	canonical := make([][]byte, 0, len(rdatas))
+	for _, rdata := range rdatas {
+		c, err := canonicalRData(typ, rdata)
+		if err != nil {
+			return nil, err
+		}
+		canonical = append(canonical, c)
+	}
+
+	sort.Slice(canonical, func(i, j int) bool {
+		return bytes.Compare(canonical[i], canonical[j]) < 0
+	})
+
+	out := canonical[:0]
+	for i, v := range canonical {
+		if i == 0 || !bytes.Equal(v, canonical[i-1]) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// canonicalRData returns a copy of the uncompressed rdata, with the
+// embedded names converted to lowercase (RFC 4034, Section 6.2).
+func canonicalRData(typ Type, rdata []byte) ([]byte, error) {
+	c := make([]byte, len(rdata))
+	copy(c, rdata)
+
+	lowerName := func(offset int) (int, error) {
+		if offset > len(c) {
+			return 0, errInvalidDNSMessage
+		}
+		l, ok := uncompressedNameLength(c[offset:])
+		if !ok {
+			return 0, errInvalidDNSMessage
+		}
+		lowerASCII(c[offset : offset+l])
+		return offset + l, nil
+	}
+
+	var err error
+	switch typ {
+	case TypeNS, TypeCNAME, TypePTR, TypeDNAME:
+		_, err = lowerName(0)
+	case TypeMX:
+		_, err = lowerName(2)
+	case TypeSRV:
+		_, err = lowerName(6)
+	case TypeRRSIG:
+		_, err = lowerName(18)
+	case TypeSOA:
+		var offset int
+		if offset, err = lowerName(0); err == nil {
+			_, err = lowerName(offset)
+		}
+	}
+	return c, err
+}
+
+// uncompressedNameLength returns the length of the uncompressed name at the start of b.
+func uncompressedNameLength(b []byte) (int, bool) {
+	for i := 0; i < len(b) && i < maxEncodedNameLen; i += int(b[i]) + 1 {
+		if b[i] > maxLabelLength {
+			return 0, false
+		}
+		if b[i] == 0 {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,338 @@
+package dnsmsg
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func mustDecodeBase64(t *testing.T, s string) []byte {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVerifierRFC8080(t *testing.T) {
+	// Example from RFC 8080, Section 6.1.
+	key := ResourceDNSKEY{
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: DNSSECAlgorithmED25519,
+		PublicKey: mustDecodeBase64(t, "l02Woi0iS8Aa25FQkUd9RMzZHJpBoRQwAQEX1SxZJA4="),
+	}
+
+	rrsig := ResourceRRSIG{
+		TypeCovered: TypeMX,
+		Algorithm:   DNSSECAlgorithmED25519,
+		Labels:      2,
+		OriginalTTL: 3600,
+		Expiration:  1440021600,
+		Inception:   1438207200,
+		KeyTag:      3613,
+		SignerName:  MustParseName("example.com"),
+		Signature:   mustDecodeBase64(t, "oL9krJun7xfBOIWcGHi7mag5/hdZrKWw15jPGrHpjQeRAvTdszaPD+QLs3fx8A4M3e23mRZ9VrbpMngwcrqNAg=="),
+	}
+
+	if tag := key.KeyTag(); tag != rrsig.KeyTag {
+		t.Fatalf("key.KeyTag() = %v, want: %v", tag, rrsig.KeyTag)
+	}
+
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+	hdr := ResourceHeader{Name: MustParseName("ExAmple.com"), Class: ClassIN, TTL: 7200}
+	if err := b.ResourceMX(hdr, ResourceMX{Pref: 10, MX: MustParseName("MAIL.example.com")}); err != nil {
+		t.Fatalf("b.ResourceMX() unexpected error: %v", err)
+	}
+	// Duplicate resource must be ignored.
+	if err := b.ResourceMX(hdr, ResourceMX{Pref: 10, MX: MustParseName("mail.example.com")}); err != nil {
+		t.Fatalf("b.ResourceMX() unexpected error: %v", err)
+	}
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+
+	var set RRSet
+	for {
+		rhdr, err := p.ResourceHeader()
+		if err != nil {
+			if err == ErrSectionDone {
+				break
+			}
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		if err := set.AppendResource(&p, rhdr); err != nil {
+			t.Fatalf("set.AppendResource() unexpected error: %v", err)
+		}
+	}
+
+	if len(set.RData) != 2 {
+		t.Fatalf("len(set.RData) = %v, want: 2", len(set.RData))
+	}
+
+	validAt := func(unix int64) *Verifier {
+		return &Verifier{Now: func() time.Time { return time.Unix(unix, 0) }}
+	}
+
+	ttl, err := validAt(1439000000).Verify(&set, &rrsig, []ResourceDNSKEY{key})
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if ttl != 3600 {
+		t.Fatalf("Verify() returned TTL = %v, want: 3600", ttl)
+	}
+
+	ttl, err = validAt(int64(rrsig.Expiration)-100).Verify(&set, &rrsig, []ResourceDNSKEY{key})
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if ttl != 100 {
+		t.Fatalf("Verify() returned TTL = %v, want: 100", ttl)
+	}
+
+	if _, err := validAt(int64(rrsig.Expiration)+1).Verify(&set, &rrsig, []ResourceDNSKEY{key}); err != ErrSignatureExpired {
+		t.Fatalf("Verify() unexpected error: %v, want: %v", err, ErrSignatureExpired)
+	}
+	if _, err := validAt(int64(rrsig.Inception)-1).Verify(&set, &rrsig, []ResourceDNSKEY{key}); err != ErrSignatureNotYetValid {
+		t.Fatalf("Verify() unexpected error: %v, want: %v", err, ErrSignatureNotYetValid)
+	}
+
+	revoked := key
+	revoked.Flags |= DNSKEYFlagRevoke
+	if _, err := validAt(1439000000).Verify(&set, &rrsig, []ResourceDNSKEY{revoked}); err != ErrNoMatchingKey {
+		t.Fatalf("Verify() unexpected error: %v, want: %v", err, ErrNoMatchingKey)
+	}
+
+	set.RData[0][1]++
+	if _, err := validAt(1439000000).Verify(&set, &rrsig, []ResourceDNSKEY{key}); err != ErrInvalidSignature {
+		t.Fatalf("Verify() unexpected error: %v, want: %v", err, ErrInvalidSignature)
+	}
+}
+
+func testSignRRSet(t *testing.T, set *RRSet, rrsig *ResourceRRSIG, signer crypto.Signer) {
+	data, err := appendRRSIGSignedData(nil, set, rrsig)
+	if err != nil {
+		t.Fatalf("appendRRSIGSignedData() unexpected error: %v", err)
+	}
+
+	switch rrsig.Algorithm {
+	case DNSSECAlgorithmRSASHA256:
+		h := sha256.Sum256(data)
+		rrsig.Signature, err = signer.Sign(rand.Reader, h[:], crypto.SHA256)
+	case DNSSECAlgorithmRSASHA512:
+		h := sha512.Sum512(data)
+		rrsig.Signature, err = signer.Sign(rand.Reader, h[:], crypto.SHA512)
+	case DNSSECAlgorithmECDSAP256SHA256, DNSSECAlgorithmECDSAP384SHA384:
+		var h []byte
+		size := 32
+		if rrsig.Algorithm == DNSSECAlgorithmECDSAP256SHA256 {
+			s := sha256.Sum256(data)
+			h = s[:]
+		} else {
+			s := sha512.Sum384(data)
+			h = s[:]
+			size = 48
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, signer.(*ecdsa.PrivateKey), h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrsig.Signature = make([]byte, 2*size)
+		r.FillBytes(rrsig.Signature[:size])
+		s.FillBytes(rrsig.Signature[size:])
+	case DNSSECAlgorithmED25519:
+		rrsig.Signature, err = signer.Sign(rand.Reader, data, crypto.Hash(0))
+	default:
+		t.Fatalf("unsupported algorithm: %v", rrsig.Algorithm)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifierAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub := append([]byte{3}, 1, 0, 1)
+	rsaPub = append(rsaPub, rsaKey.N.Bytes()...)
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Pub := make([]byte, 64)
+	p256Key.X.FillBytes(p256Pub[:32])
+	p256Key.Y.FillBytes(p256Pub[32:])
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Pub := make([]byte, 96)
+	p384Key.X.FillBytes(p384Pub[:48])
+	p384Key.Y.FillBytes(p384Pub[48:])
+
+	ed25519Pub, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		alg    DNSSECAlgorithm
+		signer crypto.Signer
+		pub    []byte
+	}{
+		{DNSSECAlgorithmRSASHA256, rsaKey, rsaPub},
+		{DNSSECAlgorithmRSASHA512, rsaKey, rsaPub},
+		{DNSSECAlgorithmECDSAP256SHA256, p256Key, p256Pub},
+		{DNSSECAlgorithmECDSAP384SHA384, p384Key, p384Pub},
+		{DNSSECAlgorithmED25519, ed25519Key, ed25519Pub},
+	}
+
+	now := time.Unix(1700000000, 0)
+	v := Verifier{Now: func() time.Time { return now }}
+
+	for _, tt := range cases {
+		key := ResourceDNSKEY{
+			Flags:     DNSKEYFlagZone,
+			Protocol:  DNSKEYProtocol,
+			Algorithm: tt.alg,
+			PublicKey: tt.pub,
+		}
+
+		set := RRSet{
+			Name:  MustParseName("www.example.com"),
+			Type:  TypeA,
+			Class: ClassIN,
+			TTL:   300,
+			RData: [][]byte{{192, 0, 2, 2}, {192, 0, 2, 1}},
+		}
+
+		rrsig := ResourceRRSIG{
+			TypeCovered: TypeA,
+			Algorithm:   tt.alg,
+			Labels:      3,
+			OriginalTTL: 300,
+			Expiration:  uint32(now.Add(time.Hour).Unix()),
+			Inception:   uint32(now.Add(-time.Hour).Unix()),
+			KeyTag:      key.KeyTag(),
+			SignerName:  MustParseName("example.com"),
+		}
+
+		testSignRRSet(t, &set, &rrsig, tt.signer)
+
+		// Order of resources must not matter.
+		set.RData[0], set.RData[1] = set.RData[1], set.RData[0]
+
+		if _, err := v.Verify(&set, &rrsig, []ResourceDNSKEY{key}); err != nil {
+			t.Errorf("%v: Verify() unexpected error: %v", tt.alg, err)
+		}
+
+		rrsig.Signature[len(rrsig.Signature)-1] ^= 1
+		if _, err := v.Verify(&set, &rrsig, []ResourceDNSKEY{key}); err != ErrInvalidSignature {
+			t.Errorf("%v: Verify() unexpected error: %v, want: %v", tt.alg, err, ErrInvalidSignature)
+		}
+	}
+}
+
+func TestVerifierWildcard(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := ResourceDNSKEY{
+		Flags:     DNSKEYFlagZone,
+		Protocol:  DNSKEYProtocol,
+		Algorithm: DNSSECAlgorithmED25519,
+		PublicKey: pub,
+	}
+
+	now := time.Unix(1700000000, 0)
+	v := Verifier{Now: func() time.Time { return now }}
+
+	rrsig := ResourceRRSIG{
+		TypeCovered: TypeTXT,
+		Algorithm:   DNSSECAlgorithmED25519,
+		Labels:      2,
+		OriginalTTL: 300,
+		Expiration:  uint32(now.Add(time.Hour).Unix()),
+		Inception:   uint32(now.Add(-time.Hour).Unix()),
+		KeyTag:      key.KeyTag(),
+		SignerName:  MustParseName("example.com"),
+	}
+
+	wildcard := RRSet{
+		Name:  MustParseName("*.example.com"),
+		Type:  TypeTXT,
+		Class: ClassIN,
+		TTL:   300,
+		RData: [][]byte{{4, 't', 'e', 's', 't'}},
+	}
+	testSignRRSet(t, &wildcard, &rrsig, priv)
+
+	if _, err := v.Verify(&wildcard, &rrsig, []ResourceDNSKEY{key}); err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+
+	expanded := wildcard
+	expanded.Name = MustParseName("a.b.example.com")
+	if _, err := v.Verify(&expanded, &rrsig, []ResourceDNSKEY{key}); err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+
+	rrsig.Labels = 5
+	if _, err := v.Verify(&expanded, &rrsig, []ResourceDNSKEY{key}); err != errRRSIGMismatch {
+		t.Fatalf("Verify() unexpected error: %v, want: %v", err, errRRSIGMismatch)
+	}
+}
+
+func TestCanonicalRData(t *testing.T) {
+	soa := append(nameAsSlice("NS.Example.com"), nameAsSlice("ADMIN.example.COM")...)
+	soa = append(soa, 'A', 'B', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+	expectSOA := append(nameAsSlice("ns.example.com"), nameAsSlice("admin.example.com")...)
+	expectSOA = append(expectSOA, 'A', 'B', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+	cases := []struct {
+		typ    Type
+		in     []byte
+		expect []byte
+	}{
+		{TypeNS, nameAsSlice("NS.EXAMPLE.COM"), nameAsSlice("ns.example.com")},
+		{TypeMX, append([]byte{'A', 'B'}, nameAsSlice("MX.EXAMPLE.COM")...), append([]byte{'A', 'B'}, nameAsSlice("mx.example.com")...)},
+		{TypeSOA, soa, expectSOA},
+		{TypeTXT, []byte{4, 'T', 'E', 'S', 'T'}, []byte{4, 'T', 'E', 'S', 'T'}},
+	}
+
+	for _, tt := range cases {
+		c, err := canonicalRData(tt.typ, tt.in)
+		if err != nil {
+			t.Errorf("canonicalRData(%v, %v) unexpected error: %v", tt.typ, tt.in, err)
+			continue
+		}
+		if string(c) != string(tt.expect) {
+			t.Errorf("canonicalRData(%v, %v) = %v, want: %v", tt.typ, tt.in, c, tt.expect)
+		}
+	}
+
+	if _, err := canonicalRData(TypeMX, []byte{0, 1, 3, 'a'}); err != errInvalidDNSMessage {
+		t.Errorf("canonicalRData() unexpected error: %v, want: %v", err, errInvalidDNSMessage)
+	}
+}
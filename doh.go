@@ -0,0 +1,249 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DoHMediaType is the media type of DNS messages sent over HTTPS (RFC 8484, Section 6).
+const DoHMediaType = "application/dns-message"
+
+// dohQueryParam is the name of the URL query parameter, used by the GET method (RFC 8484, Section 4.1).
+const dohQueryParam = "dns"
+
+var (
+	errDoHStatus      = errors.New("unexpected DoH response HTTP status")
+	errDoHContentType = errors.New("unexpected DoH response content type")
+	errDoHTooLong     = errors.New("DoH response too long")
+)
+
+// DoHClient is a DNS over HTTPS client (RFC 8484).
+//
+// The zero value of DoHClient (with the URL set) is ready to use.
+type DoHClient struct {
+	// URL is the URL of the DoH endpoint, e.g. "https://dns.example.net/dns-query".
+	URL string
+
+	// HTTPClient is the HTTP client used to send requests.
+	// When nil, [http.DefaultClient] is used.
+	HTTPClient *http.Client
+
+	// UseGET causes the queries to be sent with the GET method, with the query
+	// encoded in the "dns" URL parameter, which is more friendly to HTTP caches.
+	// When false, the POST method is used.
+	UseGET bool
+}
+
+// Exchange sends the query and returns the response.
+// The query must contain exactly one question.
+//
+// The query is sent with the ID set to zero, as recommended by RFC 8484, Section 4.1,
+// so that the identical queries produce identical HTTP requests (cache-friendly).
+// The returned response has the ID of the query.
+func (c *DoHClient) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	q, err := parseClientQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	zeroIDQuery := make([]byte, len(query))
+	copy(zeroIDQuery, query)
+	packUint16(zeroIDQuery, 0)
+	id := q.id
+	q.id = 0
+
+	req, err := c.newRequest(ctx, zeroIDQuery)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", DoHMediaType)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errDoHStatus
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mediaType != DoHMediaType {
+		return nil, errDoHContentType
+	}
+
+	msg, err := io.ReadAll(io.LimitReader(resp.Body, math.MaxUint16+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) > math.MaxUint16 {
+		return nil, errDoHTooLong
+	}
+	if !q.matches(msg) {
+		return nil, errClientNoResponse
+	}
+	packUint16(msg, id)
+	return msg, nil
+}
+
+func (c *DoHClient) newRequest(ctx context.Context, query []byte) (*http.Request, error) {
+	if !c.UseGET {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(query))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", DoHMediaType)
+		return req, nil
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	values := u.Query()
+	values.Set(dohQueryParam, base64.RawURLEncoding.EncodeToString(query))
+	u.RawQuery = values.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// DoHHandler is a DNS over HTTPS server [http.Handler] (RFC 8484).
+// It accepts queries sent with both the GET and POST methods.
+//
+// Queries that cannot be parsed by [Parse], or that are not queries (the QR bit
+// is set) are rejected with the 400 (Bad Request) HTTP status.
+//
+// The Cache-Control max-age of the response is set to the smallest TTL of all
+// resources in the response (RFC 8484, Section 5.1), for negative responses the
+// TTL of the SOA resource is limited by its Minimum field (RFC 2308, Section 5).
+type DoHHandler struct {
+	// Handler is called for each query, it returns the response to the query.
+	// When it returns nil, the 500 (Internal Server Error) HTTP status is sent.
+	Handler func(r *http.Request, query []byte) []byte
+}
+
+func (h *DoHHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query, status := readDoHQuery(r)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	if _, hdr, err := Parse(query); err != nil || !hdr.Flags.Query() {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	resp := h.Handler(r, query)
+	if resp == nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", DoHMediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp)))
+	if maxAge, ok := dohMaxAge(resp); ok {
+		w.Header().Set("Cache-Control", "max-age="+strconv.FormatUint(uint64(maxAge), 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+func readDoHQuery(r *http.Request) ([]byte, int) {
+	switch r.Method {
+	case http.MethodGet:
+		encoded := r.URL.Query().Get(dohQueryParam)
+		if encoded == "" {
+			return nil, http.StatusBadRequest
+		}
+		// The padding must not be used (RFC 8484, Section 6), but be liberal.
+		query, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil {
+			return nil, http.StatusBadRequest
+		}
+		return query, http.StatusOK
+	case http.MethodPost:
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != DoHMediaType {
+			return nil, http.StatusUnsupportedMediaType
+		}
+		query, err := io.ReadAll(io.LimitReader(r.Body, math.MaxUint16+1))
+		if err != nil {
+			return nil, http.StatusBadRequest
+		}
+		if len(query) > math.MaxUint16 {
+			return nil, http.StatusRequestEntityTooLarge
+		}
+		return query, http.StatusOK
+	default:
+		return nil, http.StatusMethodNotAllowed
+	}
+}
+
+// dohMaxAge returns the freshness lifetime of the response, ok is false when the
+// response should not be cached, or there are no resources that the lifetime can
+// be derived from.
+func dohMaxAge(resp []byte) (maxAge uint32, ok bool) {
+	p, hdr, err := Parse(resp)
+	if err != nil {
+		return 0, false
+	}
+	if rcode := hdr.Flags.RCode(); rcode != RCodeSuccess && rcode != RCodeNameError {
+		return 0, false
+	}
+	if err := p.SkipQuestions(); err != nil {
+		return 0, false
+	}
+
+	negative := hdr.ANCount == 0
+	maxAge = math.MaxUint32
+	for _, start := range []func() error{p.StartAnswers, p.StartAuthorities, p.StartAdditionals} {
+		if err := start(); err != nil {
+			return 0, false
+		}
+		for {
+			rhdr, err := p.ResourceHeader()
+			if err != nil {
+				if err == ErrSectionDone {
+					break
+				}
+				return 0, false
+			}
+			if rhdr.Type == TypeOPT {
+				if err := p.SkipResourceData(); err != nil {
+					return 0, false
+				}
+				continue
+			}
+
+			ttl := rhdr.TTL
+			if rhdr.Type == TypeSOA && negative {
+				soa, err := p.ResourceSOA()
+				if err != nil {
+					return 0, false
+				}
+				if soa.Minimum < ttl {
+					ttl = soa.Minimum
+				}
+			} else if err := p.SkipResourceData(); err != nil {
+				return 0, false
+			}
+
+			if ttl < maxAge {
+				maxAge = ttl
+			}
+			ok = true
+		}
+	}
+	return maxAge, ok
+}
@@ -0,0 +1,186 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoHExchange(t *testing.T) {
+	var (
+		gotMethod string
+		gotIDs    []uint16
+	)
+	h := &DoHHandler{
+		Handler: func(r *http.Request, query []byte) []byte {
+			gotMethod = r.Method
+			gotIDs = append(gotIDs, unpackUint16(query))
+			return testClientResponse(query, 0, false, 1)
+		},
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	for _, useGET := range []bool{false, true} {
+		c := DoHClient{URL: s.URL + "/dns-query", UseGET: useGET}
+		resp, err := c.Exchange(context.Background(), testClientQuery(t, 1234, "example.com"))
+		if err != nil {
+			t.Fatalf("c.Exchange() unexpected error: %v", err)
+		}
+		if _, hdr, _ := Parse(resp); hdr.ID != 1234 {
+			t.Fatalf("got response with ID %v, want: 1234", hdr.ID)
+		}
+		if a := testClientAnswer(t, resp); a != 1 {
+			t.Fatalf("got answer from response %v, want: 1", a)
+		}
+
+		wantMethod := http.MethodPost
+		if useGET {
+			wantMethod = http.MethodGet
+		}
+		if gotMethod != wantMethod {
+			t.Fatalf("handler got %v request, want: %v", gotMethod, wantMethod)
+		}
+	}
+
+	for _, id := range gotIDs {
+		if id != 0 {
+			t.Fatalf("handler got query with ID: %v, want: 0", id)
+		}
+	}
+}
+
+func TestDoHClientInvalidResponse(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			http.Error(w, "error", http.StatusBadGateway)
+		case "/content-type":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello"))
+		case "/mismatch":
+			w.Header().Set("Content-Type", DoHMediaType)
+			w.Write(testClientResponse(testClientQuery(t, 0, "other.example.com"), 0, false, 1))
+		}
+	}))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		path string
+		err  error
+	}{
+		{"/status", errDoHStatus},
+		{"/content-type", errDoHContentType},
+		{"/mismatch", errClientNoResponse},
+	} {
+		c := DoHClient{URL: s.URL + tt.path}
+		if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != tt.err {
+			t.Errorf("%v: c.Exchange() unexpected error: %v, want: %v", tt.path, err, tt.err)
+		}
+	}
+}
+
+func TestDoHHandlerInvalidRequests(t *testing.T) {
+	h := &DoHHandler{
+		Handler: func(r *http.Request, query []byte) []byte {
+			return testClientResponse(query, 0, false, 1)
+		},
+	}
+
+	query := testClientQuery(t, 0, "example.com")
+	response := testClientResponse(query, 0, false, 1)
+
+	for _, tt := range []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        []byte
+		status      int
+	}{
+		{"valid GET", http.MethodGet, "/?dns=AAABAAABAAAAAAAAB2V4YW1wbGUDY29tAAABAAE", "", nil, http.StatusOK},
+		{"valid GET padded", http.MethodGet, "/?dns=AAABAAABAAAAAAAAB2V4YW1wbGUDY29tAAABAAE=", "", nil, http.StatusOK},
+		{"GET without dns param", http.MethodGet, "/", "", nil, http.StatusBadRequest},
+		{"GET invalid base64", http.MethodGet, "/?dns=!!!", "", nil, http.StatusBadRequest},
+		{"POST invalid content type", http.MethodPost, "/", "text/plain", query, http.StatusUnsupportedMediaType},
+		{"POST response", http.MethodPost, "/", DoHMediaType, response, http.StatusBadRequest},
+		{"POST invalid message", http.MethodPost, "/", DoHMediaType, []byte{1, 2, 3}, http.StatusBadRequest},
+		{"POST too long", http.MethodPost, "/", DoHMediaType, make([]byte, 65536), http.StatusRequestEntityTooLarge},
+		{"PUT", http.MethodPut, "/", DoHMediaType, query, http.StatusMethodNotAllowed},
+	} {
+		r := httptest.NewRequest(tt.method, tt.target, bytes.NewReader(tt.body))
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%v: got status %v, want: %v", tt.name, w.Code, tt.status)
+		}
+	}
+}
+
+func TestDoHHandlerCacheControl(t *testing.T) {
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}
+
+	positive := func(q Question, b *Builder) {
+		b.StartAnswers()
+		hdr.TTL = 300
+		b.ResourceA(hdr, ResourceA{A: [4]byte{192, 0, 2, 1}})
+		hdr.TTL = 60
+		b.ResourceA(hdr, ResourceA{A: [4]byte{192, 0, 2, 2}})
+		b.StartAuthorities()
+		b.StartAdditionals()
+		b.ResourceOPT(ResourceHeader{Name: Name{Length: 1}, Type: TypeOPT, Class: 1232}, ResourceOPT{})
+	}
+	negative := func(q Question, b *Builder) {
+		b.StartAnswers()
+		b.StartAuthorities()
+		hdr.TTL = 3600
+		b.ResourceSOA(hdr, ResourceSOA{NS: hdr.Name, Mbox: hdr.Name, Minimum: 120})
+	}
+	empty := func(q Question, b *Builder) {}
+
+	for _, tt := range []struct {
+		name         string
+		rcode        RCode
+		build        func(q Question, b *Builder)
+		cacheControl string
+	}{
+		{"positive", RCodeSuccess, positive, "max-age=60"},
+		{"negative", RCodeNameError, negative, "max-age=120"},
+		{"no resources", RCodeSuccess, empty, ""},
+		{"server failure", RCodeServerFail, positive, ""},
+	} {
+		h := &DoHHandler{
+			Handler: func(r *http.Request, query []byte) []byte {
+				p, qhdr, _ := Parse(query)
+				q, _ := p.Question()
+				flags := qhdr.Flags
+				flags.SetResponse()
+				flags.SetRCode(tt.rcode)
+				b := StartBuilder(nil, qhdr.ID, flags)
+				b.Question(q)
+				tt.build(q, &b)
+				return b.Bytes()
+			},
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(testClientQuery(t, 0, "example.com")))
+		r.Header.Set("Content-Type", DoHMediaType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v: got status %v, want: %v", tt.name, w.Code, http.StatusOK)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != tt.cacheControl {
+			t.Errorf("%v: Cache-Control = %q, want: %q", tt.name, cc, tt.cacheControl)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, DoHMediaType) {
+			t.Errorf("%v: Content-Type = %q, want: %q", tt.name, ct, DoHMediaType)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package dnsmsg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// DoQALPN is the ALPN protocol identifier of DNS over QUIC (RFC 9250, Section 4.1.1).
+const DoQALPN = "doq"
+
+// DoQErrorCode is a DNS over QUIC application error code (RFC 9250, Section 4.3),
+// used for closing streams and connections.
+type DoQErrorCode uint64
+
+const (
+	DoQNoError          DoQErrorCode = 0x0
+	DoQInternalError    DoQErrorCode = 0x1
+	DoQProtocolError    DoQErrorCode = 0x2
+	DoQRequestCancelled DoQErrorCode = 0x3
+	DoQExcessiveLoad    DoQErrorCode = 0x4
+	DoQUnspecifiedError DoQErrorCode = 0x5
+	DoQErrorReserved    DoQErrorCode = 0xd098ea5e
+)
+
+func (c DoQErrorCode) String() string {
+	switch c {
+	case DoQNoError:
+		return "DOQ_NO_ERROR"
+	case DoQInternalError:
+		return "DOQ_INTERNAL_ERROR"
+	case DoQProtocolError:
+		return "DOQ_PROTOCOL_ERROR"
+	case DoQRequestCancelled:
+		return "DOQ_REQUEST_CANCELLED"
+	case DoQExcessiveLoad:
+		return "DOQ_EXCESSIVE_LOAD"
+	case DoQUnspecifiedError:
+		return "DOQ_UNSPECIFIED_ERROR"
+	case DoQErrorReserved:
+		return "DOQ_ERROR_RESERVED"
+	default:
+		return "0x" + strconv.FormatUint(uint64(c), 16)
+	}
+}
+
+// DoQStreamError is an error caused by the stream being aborted by the peer,
+// with the Code application error code.
+type DoQStreamError struct {
+	Code DoQErrorCode
+}
+
+func (e *DoQStreamError) Error() string {
+	return "dns over quic stream aborted: " + e.Code.String()
+}
+
+var (
+	errDoQNonZeroID    = errors.New("DoQ message ID must be zero")
+	errDoQTrailingData = errors.New("unexpected data after the DoQ message")
+	errDoQNoResponse   = errors.New("DoQ handler returned no response")
+)
+
+// DoQStream is a bidirectional QUIC stream, it is a minimal interface that allows the DNS over QUIC
+// framing to be used with any QUIC implementation, usually through a small adapter type.
+//
+// When the stream is aborted by the peer, Read and Write should return an error
+// that wraps a [*DoQStreamError], so that the error code can be retrieved with [errors.As].
+type DoQStream interface {
+	// Read reads data from the stream, it returns [io.EOF] after
+	// the peer indicated the end of the stream (STREAM FIN).
+	Read(p []byte) (int, error)
+
+	// Write writes data to the stream.
+	Write(p []byte) (int, error)
+
+	// Close closes the sending side of the stream (sends the STREAM FIN).
+	Close() error
+
+	// CancelRead aborts the receiving side of the stream, with the
+	// code application error code (STOP_SENDING frame).
+	// It must unblock all pending Read calls.
+	CancelRead(code DoQErrorCode)
+
+	// CancelWrite aborts the sending side of the stream, with the
+	// code application error code (RESET_STREAM frame).
+	// It must unblock all pending Write calls.
+	CancelWrite(code DoQErrorCode)
+}
+
+// DoQExchange sends the query over the stream (a new client-initiated bidirectional stream)
+// and returns the response. The query must contain exactly one question and must have
+// the ID set to zero (RFC 9250, Section 4.2.1).
+//
+// Only a single query can be sent over a stream (RFC 9250, Section 4.2), the sending side of
+// the stream is closed after the query is sent. A response, that is not followed by the end of
+// the stream, or that does not match the query, causes the stream to be aborted with the
+// [DoQProtocolError].
+//
+// When ctx is canceled, the stream is aborted with the [DoQRequestCancelled]
+// and DoQExchange returns ctx.Err().
+func DoQExchange(ctx context.Context, stream DoQStream, query []byte) ([]byte, error) {
+	q, err := parseClientQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if q.id != 0 {
+		return nil, errDoQNonZeroID
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	canceled := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.CancelWrite(DoQRequestCancelled)
+			stream.CancelRead(DoQRequestCancelled)
+			close(canceled)
+		case <-done:
+		}
+	}()
+
+	resp, err := doqExchange(stream, query, &q)
+	if err != nil {
+		select {
+		case <-canceled:
+			return nil, ctx.Err()
+		default:
+		}
+	}
+	return resp, err
+}
+
+func doqExchange(stream DoQStream, query []byte, q *clientQuery) ([]byte, error) {
+	if err := NewStreamWriter(stream).WriteMsg(query); err != nil {
+		stream.CancelRead(DoQInternalError)
+		return nil, err
+	}
+	if err := stream.Close(); err != nil {
+		stream.CancelRead(DoQInternalError)
+		return nil, err
+	}
+
+	resp, err := readDoQMsg(stream)
+	if err != nil {
+		return nil, err
+	}
+	if !q.matches(resp) {
+		stream.CancelRead(DoQProtocolError)
+		return nil, errClientNoResponse
+	}
+	return resp, nil
+}
+
+// readDoQMsg reads a single DNS message from the stream, followed by the end of the stream.
+// In case of a protocol violation, the receiving side of the stream is aborted with the [DoQProtocolError].
+func readDoQMsg(stream DoQStream) ([]byte, error) {
+	r := NewStreamReader(stream)
+	msg, err := r.ReadMsg(nil)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			stream.CancelRead(DoQProtocolError)
+		}
+		return nil, err
+	}
+
+	if _, err := r.ReadMsg(nil); err != io.EOF {
+		stream.CancelRead(DoQProtocolError)
+		if err == nil || err == io.ErrUnexpectedEOF {
+			err = errDoQTrailingData
+		}
+		return nil, err
+	}
+
+	if len(msg) < 2 || unpackUint16(msg) != 0 {
+		stream.CancelRead(DoQProtocolError)
+		return nil, errDoQNonZeroID
+	}
+	return msg, nil
+}
+
+// DoQServeStream serves a single query received on the stream (a client-initiated
+// bidirectional stream), handler is called with the query and returns the response
+// to send back. The response must have the ID set to zero.
+//
+// Queries with non-zero ID, that cannot be parsed by [Parse], or that are not followed by
+// the end of the stream cause the stream to be aborted with the [DoQProtocolError]
+// (RFC 9250, Section 4.3.3). When the handler returns nil, or an invalid response,
+// the stream is aborted with the [DoQInternalError].
+func DoQServeStream(stream DoQStream, handler func(query []byte) []byte) error {
+	query, err := readDoQMsg(stream)
+	if err != nil {
+		stream.CancelWrite(DoQProtocolError)
+		return err
+	}
+	if _, hdr, err := Parse(query); err != nil || !hdr.Flags.Query() {
+		if err == nil {
+			err = errInvalidDNSMessage
+		}
+		stream.CancelWrite(DoQProtocolError)
+		return err
+	}
+
+	resp := handler(query)
+	if resp == nil {
+		stream.CancelWrite(DoQInternalError)
+		return errDoQNoResponse
+	}
+	if len(resp) < 2 || unpackUint16(resp) != 0 {
+		stream.CancelWrite(DoQInternalError)
+		return errDoQNonZeroID
+	}
+
+	if err := NewStreamWriter(stream).WriteMsg(resp); err != nil {
+		stream.CancelWrite(DoQInternalError)
+		return err
+	}
+	return stream.Close()
+}
+
+// DoQPipe creates a synchronous, in-memory, bidirectional DoQ stream pair.
+// Data written to one end is read on the other end, it is intended for testing.
+//
+// Aborting the stream with CancelWrite causes Read on the other end to return a [*DoQStreamError],
+// CancelRead causes Write on the other end to return a [*DoQStreamError].
+func DoQPipe() (DoQStream, DoQStream) {
+	r1, w1 := io.Pipe()
+	r2, w2 := io.Pipe()
+	return &doqPipeStream{r: r1, w: w2}, &doqPipeStream{r: r2, w: w1}
+}
+
+type doqPipeStream struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (s *doqPipeStream) Read(p []byte) (int, error)  { return s.r.Read(p) }
+func (s *doqPipeStream) Write(p []byte) (int, error) { return s.w.Write(p) }
+func (s *doqPipeStream) Close() error                { return s.w.Close() }
+
+func (s *doqPipeStream) CancelRead(code DoQErrorCode) {
+	s.r.CloseWithError(&DoQStreamError{Code: code})
+}
+
+func (s *doqPipeStream) CancelWrite(code DoQErrorCode) {
+	s.w.CloseWithError(&DoQStreamError{Code: code})
+}
@@ -0,0 +1,151 @@
+package dnsmsg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDoQExchange(t *testing.T) {
+	client, server := DoQPipe()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- DoQServeStream(server, func(query []byte) []byte {
+			return testClientResponse(query, 0, false, 1)
+		})
+	}()
+
+	resp, err := DoQExchange(context.Background(), client, testClientQuery(t, 0, "example.com"))
+	if err != nil {
+		t.Fatalf("DoQExchange() unexpected error: %v", err)
+	}
+	if a := testClientAnswer(t, resp); a != 1 {
+		t.Fatalf("got answer from response %v, want: 1", a)
+	}
+	if err := <-serveErr; err != nil {
+		t.Fatalf("DoQServeStream() unexpected error: %v", err)
+	}
+}
+
+func TestDoQExchangeNonZeroID(t *testing.T) {
+	client, _ := DoQPipe()
+	if _, err := DoQExchange(context.Background(), client, testClientQuery(t, 1, "example.com")); err != errDoQNonZeroID {
+		t.Fatalf("DoQExchange() unexpected error: %v, want: %v", err, errDoQNonZeroID)
+	}
+}
+
+func testDoQStreamErrorCode(t *testing.T, err error) DoQErrorCode {
+	t.Helper()
+	var streamErr *DoQStreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("unexpected error: %v, want: %T", err, streamErr)
+	}
+	return streamErr.Code
+}
+
+func TestDoQServeStreamProtocolErrors(t *testing.T) {
+	query := testClientQuery(t, 0, "example.com")
+	queryNonZeroID := testClientQuery(t, 1, "example.com")
+
+	for _, tt := range []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"non-zero ID", append(appendUint16(nil, uint16(len(queryNonZeroID))), queryNonZeroID...), errDoQNonZeroID},
+		{"two queries", append(append(appendUint16(nil, uint16(len(query))), query...), append(appendUint16(nil, uint16(len(query))), query...)...), errDoQTrailingData},
+		{"trailing byte", append(append(appendUint16(nil, uint16(len(query))), query...), 1), errDoQTrailingData},
+		{"partial message", append(appendUint16(nil, uint16(len(query))), query[:5]...), io.ErrUnexpectedEOF},
+		{"invalid message", []byte{0, 3, 0, 0, 0}, errInvalidDNSMessage},
+	} {
+		client, server := DoQPipe()
+		go func() {
+			client.Write(tt.data)
+			client.Close()
+		}()
+
+		called := false
+		err := DoQServeStream(server, func(query []byte) []byte {
+			called = true
+			return nil
+		})
+		if err != tt.err {
+			t.Errorf("%v: DoQServeStream() unexpected error: %v, want: %v", tt.name, err, tt.err)
+		}
+		if called {
+			t.Errorf("%v: handler unexpectedly called", tt.name)
+		}
+
+		_, err = client.Read(make([]byte, 1))
+		if code := testDoQStreamErrorCode(t, err); code != DoQProtocolError {
+			t.Errorf("%v: stream aborted with %v, want: %v", tt.name, code, DoQProtocolError)
+		}
+	}
+}
+
+func TestDoQServeStreamNoResponse(t *testing.T) {
+	client, server := DoQPipe()
+	go DoQServeStream(server, func(query []byte) []byte { return nil })
+
+	_, err := DoQExchange(context.Background(), client, testClientQuery(t, 0, "example.com"))
+	if code := testDoQStreamErrorCode(t, err); code != DoQInternalError {
+		t.Fatalf("stream aborted with %v, want: %v", code, DoQInternalError)
+	}
+}
+
+func TestDoQExchangeInvalidResponse(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		resp func(query []byte) []byte
+		err  error
+	}{
+		{"non-zero ID", func(query []byte) []byte { return testClientResponse(query, 1, false, 1) }, errDoQNonZeroID},
+		{"other question", func(query []byte) []byte {
+			return testClientResponse(testClientQuery(t, 0, "other.example.com"), 0, false, 1)
+		}, errClientNoResponse},
+	} {
+		client, server := DoQPipe()
+		go func() {
+			r := NewStreamReader(server)
+			query, err := r.ReadMsg(nil)
+			if err != nil {
+				return
+			}
+			NewStreamWriter(server).WriteMsg(tt.resp(query))
+			server.Close()
+		}()
+
+		if _, err := DoQExchange(context.Background(), client, testClientQuery(t, 0, "example.com")); err != tt.err {
+			t.Errorf("%v: DoQExchange() unexpected error: %v, want: %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestDoQExchangeContextCancel(t *testing.T) {
+	client, server := DoQPipe()
+
+	serverReadErr := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(server)
+		serverReadErr <- err
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := DoQExchange(ctx, client, testClientQuery(t, 0, "example.com")); err != context.Canceled {
+		t.Fatalf("DoQExchange() unexpected error: %v, want: %v", err, context.Canceled)
+	}
+
+	// The query was fully sent, the server observes the end of the stream, but
+	// its response is rejected with the DOQ_REQUEST_CANCELLED error code.
+	if err := <-serverReadErr; err != nil {
+		t.Fatalf("io.ReadAll() unexpected error: %v", err)
+	}
+	_, err := server.Write([]byte{1})
+	if code := testDoQStreamErrorCode(t, err); code != DoQRequestCancelled {
+		t.Fatalf("stream aborted with %v, want: %v", code, DoQRequestCancelled)
+	}
+}
@@ -0,0 +1,339 @@
+package dnsmsg
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DoTALPN is the ALPN protocol identifier of DNS over TLS (RFC 7858).
+const DoTALPN = "dot"
+
+// dotServerIdleTimeout is the time after which the [DoTListener] closes
+// a connection without any queries (RFC 7766, Section 6.2.3).
+const dotServerIdleTimeout = 10 * time.Second
+
+// dotServerWriteTimeout limits the time of writing a single response by the [DoTListener].
+const dotServerWriteTimeout = 10 * time.Second
+
+// dotServerMaxInFlight is the maximum amount of queries handled concurrently on a single
+// connection by the [DoTListener], when reached no more queries are read from the connection
+// until a response is sent (RFC 7766, Section 6.2.1.1).
+const dotServerMaxInFlight = 128
+
+var errDoTPinMismatch = errors.New("no certificate presented by the server matches the pinned SPKI")
+
+// DoTClient is a DNS over TLS client (RFC 7858). A single connection to the server
+// is reused for all queries (pipelined, see [StreamConn]), it is re-established when closed.
+//
+// The zero value of DoTClient (with the Address set) is ready to use.
+// A DoTClient must not be copied after first use.
+type DoTClient struct {
+	// Address is the address (host:port) of the DNS over TLS server.
+	Address string
+
+	// TLSConfig is the TLS configuration used for the connection.
+	// When the ServerName is empty, the host from Address is used.
+	// The NextProtos is set to [DoTALPN], when empty.
+	TLSConfig *tls.Config
+
+	// PinnedSPKI is a set of SHA-256 hashes of the DER-encoded SubjectPublicKeyInfo
+	// (RFC 7858, Section 4.2). When not empty, at least one of the certificates presented
+	// by the server must match one of the pins. The pins are checked in addition to the usual
+	// certificate verification, set TLSConfig.InsecureSkipVerify to rely only on the pins.
+	PinnedSPKI [][sha256.Size]byte
+
+	// Dial is used to dial the TCP connection.
+	// When nil, [net.Dialer.DialContext] is used.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// IdleTimeout is the time after which the connection is closed
+	// when there are no outstanding queries. When zero, the connection
+	// is not closed by the client.
+	IdleTimeout time.Duration
+
+	mu   sync.Mutex
+	conn *StreamConn
+}
+
+// SPKIPin returns the pin of the certificate (SHA-256 hash of its SubjectPublicKeyInfo),
+// that can be used in [DoTClient.PinnedSPKI].
+func SPKIPin(cert []byte) ([sha256.Size]byte, error) {
+	c, err := x509.ParseCertificate(cert)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(c.RawSubjectPublicKeyInfo), nil
+}
+
+// Exchange sends the query and waits for the matching response.
+// The query must contain exactly one question, and must have an ID, that is
+// different from IDs of all other currently outstanding queries.
+//
+// When the reused connection turns out to be closed by the server,
+// the query is sent again over a new connection.
+func (c *DoTClient) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	for {
+		conn, reused, err := c.getConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := conn.Exchange(ctx, query)
+		if err != nil && ctx.Err() == nil && conn.isClosed() {
+			c.dropConn(conn)
+			if reused {
+				continue
+			}
+		}
+		return resp, err
+	}
+}
+
+func (c *DoTClient) getConn(ctx context.Context) (conn *StreamConn, reused bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil && !c.conn.isClosed() {
+		return c.conn, true, nil
+	}
+
+	tlsConn, err := c.dial(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	c.conn = NewStreamConn(tlsConn, c.IdleTimeout)
+	return c.conn, false, nil
+}
+
+func (c *DoTClient) dropConn(conn *StreamConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+}
+
+func (c *DoTClient) dial(ctx context.Context) (*tls.Conn, error) {
+	var config *tls.Config
+	if c.TLSConfig != nil {
+		config = c.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(c.Address)
+		if err != nil {
+			return nil, err
+		}
+		config.ServerName = host
+	}
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{DoTALPN}
+	}
+	if len(c.PinnedSPKI) != 0 {
+		pins := c.PinnedSPKI
+		verify := config.VerifyConnection
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			return verifySPKIPins(cs, pins)
+		}
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if c.Dial != nil {
+		conn, err = c.Dial(ctx, "tcp", c.Address)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", c.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func verifySPKIPins(cs tls.ConnectionState, pins [][sha256.Size]byte) error {
+	for _, cert := range cs.PeerCertificates {
+		pin := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, p := range pins {
+			if p == pin {
+				return nil
+			}
+		}
+	}
+	return errDoTPinMismatch
+}
+
+// Close closes the connection to the server (if any), all
+// outstanding queries return [ErrStreamClosed].
+func (c *DoTClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return nil
+}
+
+// DoTListener is a DNS over TLS server listener (RFC 7858).
+//
+// Queries received on a single connection are handled concurrently (up to 128 at once),
+// the responses are sent as soon as they are ready, possibly out of order (RFC 7766, Section 6.2.1.1).
+type DoTListener struct {
+	l net.Listener
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// ListenDoT announces on the local TCP network address and
+// returns a DNS over TLS listener, see [NewDoTListener].
+func ListenDoT(network, address string, config *tls.Config) (*DoTListener, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewDoTListener(l, config), nil
+}
+
+// NewDoTListener creates a DNS over TLS listener, that accepts connections from l.
+// The config must include at least one certificate, [DoTALPN] is added to the NextProtos.
+func NewDoTListener(l net.Listener, config *tls.Config) *DoTListener {
+	config = config.Clone()
+	hasALPN := false
+	for _, p := range config.NextProtos {
+		if p == DoTALPN {
+			hasALPN = true
+		}
+	}
+	if !hasALPN {
+		config.NextProtos = append(config.NextProtos, DoTALPN)
+	}
+	return &DoTListener{
+		l:     tls.NewListener(l, config),
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// Addr returns the listener's network address.
+func (l *DoTListener) Addr() net.Addr {
+	return l.l.Addr()
+}
+
+// Serve accepts connections and calls handler for each received query,
+// the response returned by the handler is sent back to the client,
+// no response is sent when the handler returns nil.
+//
+// Temporary errors returned by Accept are retried after a delay, other errors
+// end Serve. Serve always returns a non-nil error, after [DoTListener.Close]
+// it returns [net.ErrClosed].
+func (l *DoTListener) Serve(handler func(query []byte) []byte) error {
+	var tempDelay time.Duration
+	for {
+		conn, err := l.l.Accept()
+		if err != nil {
+			l.mu.Lock()
+			closed := l.closed
+			l.mu.Unlock()
+			if closed {
+				return net.ErrClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+		if !l.trackConn(conn) {
+			conn.Close()
+			return net.ErrClosed
+		}
+		go l.serveConn(conn, handler)
+	}
+}
+
+func (l *DoTListener) trackConn(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	return true
+}
+
+func (l *DoTListener) serveConn(conn net.Conn, handler func(query []byte) []byte) {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		conn.Close()
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+	}()
+
+	r := NewStreamReader(conn)
+	w := NewStreamWriter(conn)
+	inFlight := make(chan struct{}, dotServerMaxInFlight)
+	for {
+		inFlight <- struct{}{}
+		conn.SetReadDeadline(time.Now().Add(dotServerIdleTimeout))
+		query, err := r.ReadMsg(nil)
+		if err != nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-inFlight
+				wg.Done()
+			}()
+			if resp := handler(query); resp != nil {
+				conn.SetWriteDeadline(time.Now().Add(dotServerWriteTimeout))
+				if err := w.WriteMsg(resp); err != nil {
+					conn.Close()
+				}
+			}
+		}()
+	}
+}
+
+// Close closes the listener and all active connections.
+func (l *DoTListener) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	return l.l.Close()
+}
@@ -0,0 +1,296 @@
+package dnsmsg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSelfSignedCert creates a self-signed certificate for 127.0.0.1.
+func testSelfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dnsmsg test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+type testCountingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *testCountingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return c, err
+}
+
+func startTestDoTServer(t *testing.T, cert tls.Certificate) (*DoTListener, *testCountingListener) {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &testCountingListener{Listener: inner}
+	l := NewDoTListener(counting, &tls.Config{Certificates: []tls.Certificate{cert}})
+	go l.Serve(func(query []byte) []byte {
+		return testClientResponse(query, 0, false, 1)
+	})
+	t.Cleanup(func() { l.Close() })
+	return l, counting
+}
+
+func testCertPool(t *testing.T, cert tls.Certificate) *x509.CertPool {
+	t.Helper()
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(c)
+	return pool
+}
+
+func TestDoTExchange(t *testing.T) {
+	cert := testSelfSignedCert(t)
+	l, counting := startTestDoTServer(t, cert)
+
+	c := DoTClient{
+		Address:   l.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: testCertPool(t, cert)},
+	}
+	defer c.Close()
+
+	for i := uint16(1); i <= 3; i++ {
+		resp, err := c.Exchange(context.Background(), testClientQuery(t, i, "example.com"))
+		if err != nil {
+			t.Fatalf("c.Exchange() unexpected error: %v", err)
+		}
+		if a := testClientAnswer(t, resp); a != 1 {
+			t.Fatalf("got answer from response %v, want: 1", a)
+		}
+	}
+	if n := counting.accepted.Load(); n != 1 {
+		t.Fatalf("server accepted %v connections, want: 1", n)
+	}
+
+	// The connection is closed by the server, client must reconnect.
+	c.conn.conn.(*tls.Conn).NetConn().Close()
+	for !c.conn.isClosed() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 4, "example.com")); err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+	if n := counting.accepted.Load(); n != 2 {
+		t.Fatalf("server accepted %v connections, want: 2", n)
+	}
+}
+
+func TestDoTALPN(t *testing.T) {
+	cert := testSelfSignedCert(t)
+	l, _ := startTestDoTServer(t, cert)
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+		RootCAs:    testCertPool(t, cert),
+		NextProtos: []string{DoTALPN},
+	})
+	if err != nil {
+		t.Fatalf("tls.Dial() unexpected error: %v", err)
+	}
+	defer conn.Close()
+	if p := conn.ConnectionState().NegotiatedProtocol; p != DoTALPN {
+		t.Fatalf("negotiated protocol: %q, want: %q", p, DoTALPN)
+	}
+}
+
+func TestDoTPinnedSPKI(t *testing.T) {
+	cert := testSelfSignedCert(t)
+	l, _ := startTestDoTServer(t, cert)
+
+	pin, err := SPKIPin(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("SPKIPin() unexpected error: %v", err)
+	}
+
+	c := DoTClient{
+		Address:    l.Addr().String(),
+		TLSConfig:  &tls.Config{InsecureSkipVerify: true},
+		PinnedSPKI: [][sha256.Size]byte{{1, 2, 3}, pin},
+	}
+	defer c.Close()
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+
+	c2 := DoTClient{
+		Address:    l.Addr().String(),
+		TLSConfig:  &tls.Config{InsecureSkipVerify: true},
+		PinnedSPKI: [][sha256.Size]byte{{1, 2, 3}},
+	}
+	defer c2.Close()
+	if _, err := c2.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); !errors.Is(err, errDoTPinMismatch) {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, errDoTPinMismatch)
+	}
+}
+
+func TestDoTUntrustedCertificate(t *testing.T) {
+	l, _ := startTestDoTServer(t, testSelfSignedCert(t))
+
+	c := DoTClient{Address: l.Addr().String()}
+	defer c.Close()
+	var certErr x509.UnknownAuthorityError
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); !errors.As(err, &certErr) {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %T", err, certErr)
+	}
+}
+
+func TestDoTListenerClose(t *testing.T) {
+	cert := testSelfSignedCert(t)
+	l, err := ListenDoT("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("ListenDoT() unexpected error: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- l.Serve(func(query []byte) []byte { return testClientResponse(query, 0, false, 1) })
+	}()
+
+	c := DoTClient{
+		Address:   l.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: testCertPool(t, cert)},
+	}
+	defer c.Close()
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+
+	l.Close()
+	if err := <-done; err != net.ErrClosed {
+		t.Fatalf("l.Serve() unexpected error: %v, want: %v", err, net.ErrClosed)
+	}
+	for !c.conn.isClosed() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDoTListenerMaxInFlight(t *testing.T) {
+	cert := testSelfSignedCert(t)
+	l, err := ListenDoT("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("ListenDoT() unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	go l.Serve(func(query []byte) []byte {
+		calls.Add(1)
+		<-release
+		return testClientResponse(query, 0, false, 1)
+	})
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: testCertPool(t, cert), NextProtos: []string{DoTALPN}})
+	if err != nil {
+		t.Fatalf("tls.Dial() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	const queries = dotServerMaxInFlight + 10
+	w := NewStreamWriter(conn)
+	for i := 0; i < queries; i++ {
+		if err := w.WriteMsg(testClientQuery(t, uint16(i), "example.com")); err != nil {
+			t.Fatalf("w.WriteMsg() unexpected error: %v", err)
+		}
+	}
+
+	for calls.Load() < dotServerMaxInFlight {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != dotServerMaxInFlight {
+		t.Fatalf("handler called %v times, want: %v", n, dotServerMaxInFlight)
+	}
+
+	close(release)
+	r := NewStreamReader(conn)
+	for i := 0; i < queries; i++ {
+		if _, err := r.ReadMsg(nil); err != nil {
+			t.Fatalf("r.ReadMsg() unexpected error: %v", err)
+		}
+	}
+}
+
+type testTemporaryError struct{}
+
+func (testTemporaryError) Error() string   { return "temporary error" }
+func (testTemporaryError) Timeout() bool   { return false }
+func (testTemporaryError) Temporary() bool { return true }
+
+type testTemporaryErrorListener struct {
+	net.Listener
+	errs atomic.Int32
+}
+
+func (l *testTemporaryErrorListener) Accept() (net.Conn, error) {
+	if l.errs.Add(-1) >= 0 {
+		return nil, testTemporaryError{}
+	}
+	return l.Listener.Accept()
+}
+
+func TestDoTListenerTemporaryAcceptError(t *testing.T) {
+	cert := testSelfSignedCert(t)
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tempErrListener := &testTemporaryErrorListener{Listener: inner}
+	tempErrListener.errs.Store(3)
+	l := NewDoTListener(tempErrListener, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer l.Close()
+	go l.Serve(func(query []byte) []byte { return testClientResponse(query, 0, false, 1) })
+
+	c := DoTClient{
+		Address:   l.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: testCertPool(t, cert)},
+	}
+	defer c.Close()
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+	if n := tempErrListener.errs.Load(); n >= 0 {
+		t.Fatalf("%v temporary errors not returned by Accept", n+1)
+	}
+}
@@ -32,6 +32,21 @@ func (e ExtendedRCode) RCode() RCode {
 // ExtendedFlags are an extended flags used in EDNS(0).
 type ExtendedFlags uint16
 
+const extendedFlagDO = 1 << 15
+
+// DNSSECOK reports whether the DO (DNSSEC OK) bit is set (RFC 3225).
+func (f ExtendedFlags) DNSSECOK() bool {
+	return f&extendedFlagDO != 0
+}
+
+// SetDNSSECOK sets the DO (DNSSEC OK) bit to val.
+func (f *ExtendedFlags) SetDNSSECOK(val bool) {
+	*f &= ^ExtendedFlags(extendedFlagDO)
+	if val {
+		*f |= extendedFlagDO
+	}
+}
+
 const (
 	// EDNS0HeaderEncodingLength is a length required to encode a resource with a resource header
 	// created by [EDNS0Header.AsResourceHeader] and zero-length resource data.
@@ -219,8 +234,12 @@ func (b *Builder) ResourceOPT(hdr ResourceHeader, opt ResourceOPT) error {
 // The building section must NOT be set to questions, otherwise it panics.
 func (b *Builder) ResourceOPTBuilder(hdr ResourceHeader) (ResourceOPTBuilder, error) {
 	hdr.Type = TypeOPT
+
+	// The OPT resource can use the space reserved by ReserveOPT.
+	b.maxBufSize += b.optReserve
 	fixup, hdrOffset, c, err := b.appendHeaderWithLengthFixupNoInc(hdr, b.maxBufSize)
 	if err != nil {
+		b.maxBufSize -= b.optReserve
 		return ResourceOPTBuilder{}, err
 	}
 	b.curSection |= sectionDetachedMask
@@ -269,6 +288,7 @@ func (b *ResourceOPTBuilder) callValid() {
 // Attempting to use the ResourceOPTBuilder after calling End might lead to panics.
 func (b *ResourceOPTBuilder) End() {
 	b.callValid()
+	b.b.maxBufSize -= b.b.optReserve
 	b.b.fakeBufSize = math.MaxInt
 	b.b.curSection &= ^sectionDetachedMask
 	b.fixup.fixup(b.b)
@@ -280,6 +300,7 @@ func (b *ResourceOPTBuilder) End() {
 // Attempting to use the ResourceOPTBuilder after calling Remove might lead to panics.
 func (b *ResourceOPTBuilder) Remove() {
 	b.callValid()
+	b.b.maxBufSize -= b.b.optReserve
 	b.b.fakeBufSize = math.MaxInt
 	b.b.curSection &= ^sectionDetachedMask
 	b.b.nb.removeNamesFromCompressionMap(b.b.headerStartOffset, b.hdrOffset)
@@ -117,6 +117,24 @@ func TestEDNS0Header(t *testing.T) {
 	}
 }
 
+func TestExtendedFlagsDNSSECOK(t *testing.T) {
+	f := ExtendedFlags(0b0000_0000_0000_0101)
+	if f.DNSSECOK() {
+		t.Fatalf("%b.DNSSECOK() = true, want: false", f)
+	}
+	f.SetDNSSECOK(true)
+	if f != 0b1000_0000_0000_0101 {
+		t.Fatalf("f.SetDNSSECOK(true); f = %b, want: %b", f, 0b1000_0000_0000_0101)
+	}
+	if !f.DNSSECOK() {
+		t.Fatalf("%b.DNSSECOK() = false, want: true", f)
+	}
+	f.SetDNSSECOK(false)
+	if f != 0b0000_0000_0000_0101 {
+		t.Fatalf("f.SetDNSSECOK(false); f = %b, want: %b", f, 0b0000_0000_0000_0101)
+	}
+}
+
 func TestResourceOPTBuilderAndParser(t *testing.T) {
 	expectPanic := func(name string, f func()) {
 		defer func() {
@@ -0,0 +1,137 @@
+package dnsmsg
+
+// Msg is a DNS message, with all sections fully parsed.
+//
+// Msg is a convenience API built on top of the [Parser] and [Builder], it is
+// easier to use, but it is less efficient, as it needs to allocate all resources.
+type Msg struct {
+	// Header is the header of the message. While packing only the
+	// ID and Flags fields are used, the section counts are derived from
+	// the lengths of the sections.
+	Header Header
+
+	Questions   []Question
+	Answers     []Resource
+	Authorities []Resource
+	Additionals []Resource
+}
+
+// Unpack parses the entire DNS message into m.
+//
+// Resources of types not supported by this package are unpacked as a [RawResource].
+// It errors when msg contains any data after the last resource of the additional section.
+// After a successful Unpack, m does not reference msg.
+func (m *Msg) Unpack(msg []byte) error {
+	msg = append([]byte(nil), msg...)
+
+	p, hdr, err := Parse(msg)
+	if err != nil {
+		return err
+	}
+
+	questions := make([]Question, 0, hdr.QDCount)
+	for {
+		q, err := p.Question()
+		if err != nil {
+			if err == ErrSectionDone {
+				break
+			}
+			return err
+		}
+		questions = append(questions, q)
+	}
+
+	if err := p.StartAnswers(); err != nil {
+		return err
+	}
+	answers, err := unpackSection(&p, hdr.ANCount)
+	if err != nil {
+		return err
+	}
+
+	if err := p.StartAuthorities(); err != nil {
+		return err
+	}
+	authorities, err := unpackSection(&p, hdr.NSCount)
+	if err != nil {
+		return err
+	}
+
+	if err := p.StartAdditionals(); err != nil {
+		return err
+	}
+	additionals, err := unpackSection(&p, hdr.ARCount)
+	if err != nil {
+		return err
+	}
+	if err := p.End(); err != nil {
+		return err
+	}
+
+	*m = Msg{
+		Header:      hdr,
+		Questions:   questions,
+		Answers:     answers,
+		Authorities: authorities,
+		Additionals: additionals,
+	}
+	return nil
+}
+
+func unpackSection(p *Parser, count uint16) ([]Resource, error) {
+	resources := make([]Resource, 0, count)
+	for {
+		hdr, err := p.ResourceHeader()
+		if err != nil {
+			if err == ErrSectionDone {
+				return resources, nil
+			}
+			return nil, err
+		}
+		body, err := p.ResourceBody()
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, Resource{Header: hdr, Body: body})
+	}
+}
+
+// Pack packs the message into the wire format.
+func (m *Msg) Pack() ([]byte, error) {
+	return m.AppendPack(make([]byte, 0, 512))
+}
+
+// AppendPack appends the message in the wire format to buf.
+//
+// The Type of each resource header must be equal to the type of its body.
+func (m *Msg) AppendPack(buf []byte) ([]byte, error) {
+	b := StartBuilder(buf, m.Header.ID, m.Header.Flags)
+	for _, q := range m.Questions {
+		if err := b.Question(q); err != nil {
+			return buf, err
+		}
+	}
+
+	b.StartAnswers()
+	if err := packSection(&b, m.Answers); err != nil {
+		return buf, err
+	}
+	b.StartAuthorities()
+	if err := packSection(&b, m.Authorities); err != nil {
+		return buf, err
+	}
+	b.StartAdditionals()
+	if err := packSection(&b, m.Additionals); err != nil {
+		return buf, err
+	}
+	return b.Bytes(), nil
+}
+
+func packSection(b *Builder, resources []Resource) error {
+	for i := range resources {
+		if err := b.Resource(resources[i].Header, resources[i].Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMsgPackUnpack(t *testing.T) {
+	hdr := func(name string, typ Type) ResourceHeader {
+		return ResourceHeader{Name: MustParseName(name), Type: typ, Class: ClassIN, TTL: 60}
+	}
+
+	msg := Msg{
+		Header:    Header{ID: 1234, Flags: Flags(0x8180)},
+		Questions: []Question{{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN}},
+		Answers: []Resource{
+			{hdr("example.com", TypeA), ResourceA{A: [4]byte{192, 0, 2, 1}}},
+			{hdr("example.com", TypeMX), ResourceMX{Pref: 10, MX: MustParseName("mail.example.com")}},
+			{hdr("example.com", TypeTXT), ResourceTXT{TXT: [][]byte{[]byte("a"), []byte("bc")}}},
+			{hdr("example.com", 65280), RawResource{Type: 65280, Data: []byte{1, 2, 3}}},
+		},
+		Authorities: []Resource{
+			{hdr("example.com", TypeSOA), ResourceSOA{NS: MustParseName("ns1.example.com"), Mbox: MustParseName("admin.example.com"), Serial: 1}},
+		},
+		Additionals: []Resource{
+			{hdr("mail.example.com", TypeAAAA), ResourceAAAA{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}},
+			{ResourceHeader{Name: MustParseName("."), Type: TypeOPT, Class: 1232}, ResourceOPT{Options: []EDNS0Option{&EDNS0Cookie{ClientCookie: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}}},
+		},
+	}
+
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("msg.Pack() unexpected error: %v", err)
+	}
+
+	var got Msg
+	if err := got.Unpack(packed); err != nil {
+		t.Fatalf("got.Unpack() unexpected error: %v", err)
+	}
+
+	if got.Header.ID != msg.Header.ID || got.Header.Flags != msg.Header.Flags ||
+		got.Header.QDCount != 1 || got.Header.ANCount != 4 || got.Header.NSCount != 1 || got.Header.ARCount != 2 {
+		t.Fatalf("unexpected header: %#v", got.Header)
+	}
+	if len(got.Questions) != 1 || got.Questions[0].String() != msg.Questions[0].String() {
+		t.Fatalf("unexpected questions: %v", got.Questions)
+	}
+
+	sections := []struct {
+		name      string
+		got, want []Resource
+	}{
+		{"answers", got.Answers, msg.Answers},
+		{"authorities", got.Authorities, msg.Authorities},
+		{"additionals", got.Additionals, msg.Additionals},
+	}
+	for _, s := range sections {
+		if len(s.got) != len(s.want) {
+			t.Fatalf("%v: got %v resources, want: %v", s.name, len(s.got), len(s.want))
+		}
+		for i := range s.got {
+			got := s.got[i]
+			want := s.want[i]
+			want.Header.Length = got.Header.Length
+			if got.String() != want.String() {
+				t.Errorf("%v: %v: got: %q, want: %q", s.name, i, got.String(), want.String())
+			}
+		}
+	}
+
+	// Unpacked message does not reference the packed message.
+	for i := range packed {
+		packed[i] = 0
+	}
+	if s := got.Answers[2].Body.String(); s != `"a" "bc"` {
+		t.Fatalf("unexpected TXT after modifying the packed message: %q", s)
+	}
+
+	repacked, err := got.AppendPack([]byte{1, 2})
+	if err != nil {
+		t.Fatalf("got.AppendPack() unexpected error: %v", err)
+	}
+	packed, _ = msg.Pack()
+	if !bytes.Equal(repacked[:2], []byte{1, 2}) || !bytes.Equal(repacked[2:], packed) {
+		t.Fatalf("got.AppendPack() = %v, want: %v", repacked[2:], packed)
+	}
+}
+
+func TestMsgPackTypeMismatch(t *testing.T) {
+	msg := Msg{
+		Answers: []Resource{{ResourceHeader{Name: MustParseName("example.com"), Type: TypeAAAA, Class: ClassIN}, ResourceA{}}},
+	}
+	if _, err := msg.Pack(); err != errInvalidOperation {
+		t.Fatalf("msg.Pack() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+
+	msg.Answers[0].Body = nil
+	if _, err := msg.Pack(); err != errInvalidOperation {
+		t.Fatalf("msg.Pack() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+}
+
+func TestMsgUnpackInvalid(t *testing.T) {
+	var msg Msg
+	if err := msg.Unpack([]byte{1, 2, 3}); err != errInvalidDNSMessage {
+		t.Fatalf("msg.Unpack() unexpected error: %v, want: %v", err, errInvalidDNSMessage)
+	}
+
+	// ANCount = 1, without any resources.
+	if err := msg.Unpack([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0}); err == nil {
+		t.Fatal("msg.Unpack() unexpected success")
+	}
+
+	b := StartBuilder(nil, 0, 0)
+	b.Question(Question{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN})
+	b.StartAnswers()
+	b.ResourceA(ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN, TTL: 60}, ResourceA{A: [4]byte{192, 0, 2, 1}})
+	if err := msg.Unpack(append(b.Bytes(), 1, 2)); err != errInvalidDNSMessage {
+		t.Fatalf("msg.Unpack() unexpected error: %v, want: %v", err, errInvalidDNSMessage)
+	}
+}
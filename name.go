@@ -181,6 +181,10 @@ func (n *Name) String() string {
 				b.WriteString("\\.")
 			case v == '\\':
 				b.WriteString("\\\\")
+			case v == ';' || v == '(' || v == ')' || v == '"' || (v == '@' && labelLength == 1):
+				// Special characters of the zone file format (RFC 1035, Section 5.1).
+				b.WriteByte('\\')
+				b.WriteByte(v)
 			case v < '!' || v > '~':
 				b.WriteByte('\\')
 				b.Write(toASCIIDecimal(v))
@@ -214,6 +218,119 @@ func (n *Name) Equal(other *Name) bool {
 	return n.Length == other.Length && caseInsensitiveEqual(n.Name[:n.Length], other.Name[:other.Length])
 }
 
+// Compare compares n and other using the canonical DNS name order (RFC 4034, Section 6.1).
+// The result will be 0 if n and other represent the same name (case-insensitively),
+// -1 if n sorts before other, and +1 if n sorts after other.
+func (n *Name) Compare(other *Name) int {
+	var nOffsetsBuf, otherOffsetsBuf [128]uint8
+	nOffsets := n.labelOffsets(nOffsetsBuf[:0])
+	otherOffsets := other.labelOffsets(otherOffsetsBuf[:0])
+
+	for i, j := len(nOffsets)-1, len(otherOffsets)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		nLabel := n.Name[nOffsets[i]+1 : nOffsets[i]+1+n.Name[nOffsets[i]]]
+		otherLabel := other.Name[otherOffsets[j]+1 : otherOffsets[j]+1+other.Name[otherOffsets[j]]]
+		if c := compareLabels(nLabel, otherLabel); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(nOffsets) < len(otherOffsets):
+		return -1
+	case len(nOffsets) > len(otherOffsets):
+		return 1
+	}
+	return 0
+}
+
+// labelOffsets appends the offsets of all non-root labels of n to dst.
+func (n *Name) labelOffsets(dst []uint8) []uint8 {
+	for i := 0; i < int(n.Length) && n.Name[i] != 0; i += int(n.Name[i]) + 1 {
+		dst = append(dst, uint8(i))
+	}
+	return dst
+}
+
+// labelCount returns the number of non-root labels in n.
+func (n *Name) labelCount() int {
+	count := 0
+	for i := 0; i < int(n.Length) && n.Name[i] != 0; i += int(n.Name[i]) + 1 {
+		count++
+	}
+	return count
+}
+
+// isWildcard reports whether the first label of n is equal to "*".
+func (n *Name) isWildcard() bool {
+	return n.Length > 2 && n.Name[0] == 1 && n.Name[1] == '*'
+}
+
+// isSubdomainOf reports whether n is equal to, or is a subdomain of parent (case-insensitively).
+func (n *Name) isSubdomainOf(parent *Name) bool {
+	for i := 0; i < int(n.Length); i += int(n.Name[i]) + 1 {
+		if int(n.Length)-i == int(parent.Length) {
+			return caseInsensitiveEqual(n.Name[i:n.Length], parent.Name[:parent.Length])
+		}
+		if n.Name[i] == 0 {
+			break
+		}
+	}
+	return false
+}
+
+// parent returns the name created by removing the first label from n.
+// For the root name it returns the root name.
+func (n *Name) parent() Name {
+	if n.Length <= 1 {
+		return Name{Length: 1}
+	}
+	var p Name
+	p.Length = uint8(copy(p.Name[:], n.Name[int(n.Name[0])+1:n.Length]))
+	return p
+}
+
+// lowerASCII converts all ASCII uppercase letters in n to lowercase.
+func (n *Name) lowerASCII() {
+	// Label Lengths are limited to 63, ASCII letters start at 65, so the
+	// label lengths are never modified.
+	lowerASCII(n.Name[:n.Length])
+}
+
+func lowerASCII(b []byte) {
+	const caseDiff = 'a' - 'A'
+	for i, v := range b {
+		if v >= 'A' && v <= 'Z' {
+			b[i] = v + caseDiff
+		}
+	}
+}
+
+func compareLabels(a, b []byte) int {
+	const caseDiff = 'a' - 'A'
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ac, bc := a[i], b[i]
+		if ac >= 'A' && ac <= 'Z' {
+			ac += caseDiff
+		}
+		if bc >= 'A' && bc <= 'Z' {
+			bc += caseDiff
+		}
+		switch {
+		case ac < bc:
+			return -1
+		case ac > bc:
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
 // len(a) must be equal to len(b)
 func caseInsensitiveEqual(a []byte, b []byte) bool {
 	for i := 0; i < len(a); i++ {
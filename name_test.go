@@ -169,6 +169,7 @@ func TestNameString(t *testing.T) {
 		{n: Name{Name: [255]byte{3, 'W', 'w', 'W', 7, 'e', 'X', 'a', 'm', 'p', 'L', 'e', 3, 'c', 'O', 'm', 0}, Length: 13}, str: "WwW.eXampLe.cOm."},
 		{n: Name{Name: [255]byte{2, '~', '!', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}, Length: 13}, str: "~!.example.com."},
 		{n: Name{Name: [255]byte{4, 0x20, 0x7F, '.', '\\', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}, Length: 13}, str: "\\032\\127\\.\\\\.example.com."},
+		{n: Name{Name: [255]byte{4, ';', '(', ')', '"', 1, '@', 2, 'a', '@', 0}, Length: 11}, str: "\\;\\(\\)\\\".\\@.a@."},
 	}
 
 	for _, tt := range cases {
@@ -282,6 +283,66 @@ func TestNameEqual(t *testing.T) {
 	}
 }
 
+func TestNameCompare(t *testing.T) {
+	// Example from RFC 4034, Section 6.1.
+	ordered := []string{
+		".",
+		"example",
+		"a.example",
+		"yljkjljk.a.example",
+		"Z.a.example",
+		"zABC.a.EXAMPLE",
+		"z.example",
+		"\\001.z.example",
+		"*.z.example",
+		"\\200.z.example",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			n1, n2 := MustParseName(ordered[i]), MustParseName(ordered[j])
+			expect := 0
+			if i < j {
+				expect = -1
+			} else if i > j {
+				expect = 1
+			}
+			if c := n1.Compare(&n2); c != expect {
+				t.Errorf("(%v).Compare(%v) = %v, want: %v", ordered[i], ordered[j], c, expect)
+			}
+		}
+	}
+
+	n1, n2 := MustParseName("EXAMPLE.com"), MustParseName("example.COM")
+	if c := n1.Compare(&n2); c != 0 {
+		t.Errorf("(%v).Compare(%v) = %v, want: 0", n1.String(), n2.String(), c)
+	}
+}
+
+func TestNameIsSubdomainOf(t *testing.T) {
+	cases := []struct {
+		n, parent string
+		expect    bool
+	}{
+		{"example.com", ".", true},
+		{".", ".", true},
+		{"example.com", "com", true},
+		{"example.com", "example.com", true},
+		{"www.EXAMPLE.com", "example.COM", true},
+		{"example.com", "www.example.com", false},
+		{"wwwexample.com", "example.com", false},
+		{"example.net", "com", false},
+		{".", "com", false},
+	}
+
+	for _, tt := range cases {
+		n, parent := MustParseName(tt.n), MustParseName(tt.parent)
+		if v := n.isSubdomainOf(&parent); v != tt.expect {
+			t.Errorf("(%v).isSubdomainOf(%v) = %v, want: %v", tt.n, tt.parent, v, tt.expect)
+		}
+	}
+}
+
 func TestNameUnpack(t *testing.T) {
 	a63 := bytes.Repeat([]byte{'a'}, 63)
 	a61 := bytes.Repeat([]byte{'a'}, 61)
@@ -0,0 +1,269 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"math"
+)
+
+var nsec3Base32 = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+// nsec3Hash computes the NSEC3 SHA-1 hash of name (RFC 5155, Section 5).
+func nsec3Hash(name *Name, salt []byte, iterations uint16) []byte {
+	canonical := *name
+	canonical.lowerASCII()
+
+	h := sha1.New()
+	h.Write(canonical.asSlice())
+	h.Write(salt)
+	digest := h.Sum(make([]byte, 0, sha1.Size))
+
+	for i := 0; i < int(iterations); i++ {
+		h.Reset()
+		h.Write(digest)
+		h.Write(salt)
+		digest = h.Sum(digest[:0])
+	}
+
+	return digest
+}
+
+// nsec3OwnerName creates the owner name of a NSEC3 resource, by
+// prepending the base32hex encoded hash as a label to zone.
+func nsec3OwnerName(hash []byte, zone *Name) (Name, bool) {
+	labelLength := nsec3Base32.EncodedLen(len(hash))
+	if labelLength > maxLabelLength || 1+labelLength+int(zone.Length) > maxEncodedNameLen {
+		return Name{}, false
+	}
+
+	var n Name
+	n.Name[0] = uint8(labelLength)
+	nsec3Base32.Encode(n.Name[1:], hash)
+	n.Length = uint8(1 + labelLength + copy(n.Name[1+labelLength:], zone.asSlice()))
+	return n, true
+}
+
+var (
+	errInconsistentNSEC3      = errors.New("NSEC3 resources use different zones or hash parameters")
+	errNoClosestEncloser      = errors.New("no NSEC3 resource matches the closest encloser")
+	errNextCloserNotCovered   = errors.New("no NSEC3 resource covers the next closer name")
+	errWildcardNotCovered     = errors.New("no NSEC3 resource covers the wildcard name")
+	errInvalidClosestEncloser = errors.New("closest encloser is a delegation point or a DNAME owner")
+	errNameNotInZone          = errors.New("name does not belong to the zone of the NSEC3 resources")
+)
+
+// NSEC3HashedOwnerName computes the NSEC3 SHA-1 hash of name (RFC 5155, Section 5) and returns
+// a NSEC3 owner name, created by prepending the base32hex encoded hash as a label to zone.
+func NSEC3HashedOwnerName(name, zone Name, salt []byte, iterations uint16) (Name, error) {
+	if len(salt) > math.MaxUint8 {
+		return Name{}, errInvalidNSEC3
+	}
+	hashed, ok := nsec3OwnerName(nsec3Hash(&name, salt, iterations), &zone)
+	if !ok {
+		return Name{}, errInvalidName
+	}
+	return hashed, nil
+}
+
+// NSEC3Record is a NSEC3 resource with its owner name.
+type NSEC3Record struct {
+	Name  Name
+	NSEC3 ResourceNSEC3
+}
+
+// hash decodes the hash from the first label of the owner name.
+func (r *NSEC3Record) hash() ([]byte, bool) {
+	if r.Name.Length < 2 || r.Name.Name[0] == 0 {
+		return nil, false
+	}
+	var label [maxLabelLength]byte
+	n := copy(label[:], r.Name.Name[1:1+r.Name.Name[0]])
+	lowerASCII(label[:n])
+	hash := make([]byte, nsec3Base32.DecodedLen(n))
+	l, err := nsec3Base32.Decode(hash, label[:n])
+	if err != nil {
+		return nil, false
+	}
+	return hash[:l], true
+}
+
+// NSEC3Set is a set of NSEC3 resources (usually from the authority section
+// of a response), used to prove the non-existence of names (RFC 5155, Section 8).
+//
+// All NSEC3 resources in the set must belong to the same zone
+// and must use the same hash algorithm, salt and iterations.
+type NSEC3Set []NSEC3Record
+
+type nsec3SetParams struct {
+	zone       Name
+	salt       []byte
+	iterations uint16
+	hashes     [][]byte
+}
+
+func (s NSEC3Set) params() (nsec3SetParams, error) {
+	if len(s) == 0 {
+		return nsec3SetParams{}, errInconsistentNSEC3
+	}
+
+	p := nsec3SetParams{
+		zone:       s[0].Name.parent(),
+		salt:       s[0].NSEC3.Salt,
+		iterations: s[0].NSEC3.Iterations,
+		hashes:     make([][]byte, len(s)),
+	}
+
+	for i := range s {
+		r := &s[i]
+		zone := r.Name.parent()
+		if r.NSEC3.HashAlgorithm != NSEC3HashAlgorithmSHA1 || r.NSEC3.Iterations != p.iterations ||
+			!bytes.Equal(r.NSEC3.Salt, p.salt) || !zone.Equal(&p.zone) {
+			return nsec3SetParams{}, errInconsistentNSEC3
+		}
+		hash, ok := r.hash()
+		if !ok || len(hash) != sha1.Size || len(r.NSEC3.NextHashedOwner) != sha1.Size {
+			return nsec3SetParams{}, errInvalidNSEC3
+		}
+		p.hashes[i] = hash
+	}
+
+	return p, nil
+}
+
+func (s NSEC3Set) matching(p *nsec3SetParams, hash []byte) *NSEC3Record {
+	for i := range s {
+		if bytes.Equal(p.hashes[i], hash) {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+func (s NSEC3Set) covering(p *nsec3SetParams, hash []byte) *NSEC3Record {
+	for i := range s {
+		owner, next := p.hashes[i], s[i].NSEC3.NextHashedOwner
+		if bytes.Compare(owner, next) < 0 {
+			if bytes.Compare(owner, hash) < 0 && bytes.Compare(hash, next) < 0 {
+				return &s[i]
+			}
+			continue
+		}
+		// Last NSEC3 in the chain (or the only one).
+		if bytes.Compare(owner, hash) < 0 || bytes.Compare(hash, next) < 0 {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+// Matching returns the NSEC3 resource whose owner name corresponds to the hash of name.
+func (s NSEC3Set) Matching(name Name) (*NSEC3Record, error) {
+	p, err := s.params()
+	if err != nil {
+		return nil, err
+	}
+	if !name.isSubdomainOf(&p.zone) {
+		return nil, errNameNotInZone
+	}
+	return s.matching(&p, nsec3Hash(&name, p.salt, p.iterations)), nil
+}
+
+// Covering returns the NSEC3 resource that covers the hash of name, i.e. the hash
+// is between the hashed owner name and the next hashed owner name of the NSEC3.
+func (s NSEC3Set) Covering(name Name) (*NSEC3Record, error) {
+	p, err := s.params()
+	if err != nil {
+		return nil, err
+	}
+	if !name.isSubdomainOf(&p.zone) {
+		return nil, errNameNotInZone
+	}
+	return s.covering(&p, nsec3Hash(&name, p.salt, p.iterations)), nil
+}
+
+// ClosestEncloserProof is a closest encloser proof, as described in RFC 5155, Section 7.2.1.
+type ClosestEncloserProof struct {
+	// ClosestEncloser is the longest existing ancestor of the name.
+	ClosestEncloser Name
+
+	// NextCloser is the name one label longer than the ClosestEncloser.
+	NextCloser Name
+
+	// ClosestEncloserNSEC3 matches the ClosestEncloser.
+	ClosestEncloserNSEC3 *NSEC3Record
+
+	// NextCloserNSEC3 covers the NextCloser.
+	NextCloserNSEC3 *NSEC3Record
+}
+
+// OptOut reports whether the NSEC3 covering the NextCloser has the Opt-Out flag set,
+// in which case the proof does not deny the existence of insecure delegations.
+func (p *ClosestEncloserProof) OptOut() bool {
+	return p.NextCloserNSEC3.NSEC3.OptOut()
+}
+
+// Wildcard returns the wildcard name at the closest encloser ("*." followed by the ClosestEncloser).
+func (p *ClosestEncloserProof) Wildcard() Name {
+	return wildcardName(&p.ClosestEncloser, p.ClosestEncloser.labelCount())
+}
+
+// ClosestEncloserProof finds the closest encloser proof of name (RFC 5155, Section 8.3).
+// It fails when name itself has a matching NSEC3, when no ancestor of name has a matching NSEC3,
+// when the next closer name is not covered or when the closest encloser is a delegation point
+// (has the NS type, without SOA) or has the DNAME type.
+func (s NSEC3Set) ClosestEncloserProof(name Name) (ClosestEncloserProof, error) {
+	p, err := s.params()
+	if err != nil {
+		return ClosestEncloserProof{}, err
+	}
+	if !name.isSubdomainOf(&p.zone) {
+		return ClosestEncloserProof{}, errNameNotInZone
+	}
+
+	var proof ClosestEncloserProof
+	for candidate := name; ; candidate = candidate.parent() {
+		if m := s.matching(&p, nsec3Hash(&candidate, p.salt, p.iterations)); m != nil {
+			if candidate.Equal(&name) {
+				return ClosestEncloserProof{}, errNoClosestEncloser
+			}
+			proof.ClosestEncloser = candidate
+			proof.ClosestEncloserNSEC3 = m
+			break
+		}
+		if candidate.Equal(&p.zone) {
+			return ClosestEncloserProof{}, errNoClosestEncloser
+		}
+		proof.NextCloser = candidate
+	}
+
+	bitmap := proof.ClosestEncloserNSEC3.NSEC3.TypeBitmap
+	if (bitmap.Contains(TypeNS) && !bitmap.Contains(TypeSOA)) || bitmap.Contains(TypeDNAME) {
+		return ClosestEncloserProof{}, errInvalidClosestEncloser
+	}
+
+	proof.NextCloserNSEC3 = s.covering(&p, nsec3Hash(&proof.NextCloser, p.salt, p.iterations))
+	if proof.NextCloserNSEC3 == nil {
+		return ClosestEncloserProof{}, errNextCloserNotCovered
+	}
+
+	return proof, nil
+}
+
+// NameErrorProof proves that name does not exist (RFC 5155, Section 8.4), it finds
+// the closest encloser proof of name and the NSEC3 that covers the wildcard at the closest encloser.
+func (s NSEC3Set) NameErrorProof(name Name) (ClosestEncloserProof, *NSEC3Record, error) {
+	proof, err := s.ClosestEncloserProof(name)
+	if err != nil {
+		return ClosestEncloserProof{}, nil, err
+	}
+	wildcard, err := s.Covering(proof.Wildcard())
+	if err != nil {
+		return ClosestEncloserProof{}, nil, err
+	}
+	if wildcard == nil {
+		return ClosestEncloserProof{}, nil, errWildcardNotCovered
+	}
+	return proof, wildcard, nil
+}
@@ -0,0 +1,179 @@
+package dnsmsg
+
+import (
+	"testing"
+)
+
+func TestNSEC3Hash(t *testing.T) {
+	// Examples from RFC 5155, Appendix A.
+	salt := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	zone := MustParseName("example")
+
+	cases := []struct {
+		name   string
+		hashed string
+	}{
+		{"example", "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example."},
+		{"a.example", "35mthgpgcu1qg68fab165klnsnk3dpvl.example."},
+		{"ai.example", "gjeqe526plbf1g8mklp59enfd789njgi.example."},
+		{"ns1.example", "2t7b4g4vsa5smi47k61mv5bv1a22bojr.example."},
+		{"*.w.example", "r53bq7cc2uvmubfu5ocmm6pers9tk9en.example."},
+		{"EXAMPLE", "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example."},
+	}
+
+	for _, tt := range cases {
+		name := MustParseName(tt.name)
+		hash := nsec3Hash(&name, salt, 12)
+		owner, ok := nsec3OwnerName(hash, &zone)
+		if !ok {
+			t.Fatalf("nsec3OwnerName() failed")
+		}
+		if s := owner.String(); s != tt.hashed {
+			t.Errorf("hashed owner name of %v = %v, want: %v", tt.name, s, tt.hashed)
+		}
+	}
+}
+
+func TestNSEC3HashedOwnerName(t *testing.T) {
+	hashed, err := NSEC3HashedOwnerName(MustParseName("a.example"), MustParseName("example"), []byte{0xaa, 0xbb, 0xcc, 0xdd}, 12)
+	if err != nil {
+		t.Fatalf("NSEC3HashedOwnerName() unexpected error: %v", err)
+	}
+	if s := hashed.String(); s != "35mthgpgcu1qg68fab165klnsnk3dpvl.example." {
+		t.Fatalf("NSEC3HashedOwnerName() = %v, want: 35mthgpgcu1qg68fab165klnsnk3dpvl.example.", s)
+	}
+
+	if _, err := NSEC3HashedOwnerName(MustParseName("a.example"), MustParseName("example"), make([]byte, 256), 12); err != errInvalidNSEC3 {
+		t.Fatalf("NSEC3HashedOwnerName() unexpected error: %v, want: %v", err, errInvalidNSEC3)
+	}
+}
+
+func testNSEC3Set(t *testing.T) NSEC3Set {
+	signer, _ := testZoneSigner(t)
+	signer.NSEC3 = &NSEC3Params{Iterations: 1, Salt: []byte{1, 2, 3}, OptOut: true}
+	zone, err := signer.Sign(testZone())
+	if err != nil {
+		t.Fatalf("signer.Sign() unexpected error: %v", err)
+	}
+
+	var set NSEC3Set
+	for _, v := range zone {
+		if v.Type != TypeNSEC3 {
+			continue
+		}
+
+		b := StartBuilder(make([]byte, 0, 512), 0, 0)
+		b.StartAnswers()
+		rdb, err := b.RDBuilder(ResourceHeader{Name: v.Name, Type: TypeNSEC3, Class: ClassIN})
+		if err != nil {
+			t.Fatalf("b.RDBuilder() unexpected error: %v", err)
+		}
+		rdb.Bytes(v.RData[0])
+		rdb.End()
+
+		p, _, err := Parse(b.Bytes())
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		p.StartAnswers()
+		if _, err := p.ResourceHeader(); err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		nsec3, err := p.ResourceNSEC3()
+		if err != nil {
+			t.Fatalf("p.ResourceNSEC3() unexpected error: %v", err)
+		}
+		set = append(set, NSEC3Record{Name: v.Name, NSEC3: nsec3})
+	}
+	return set
+}
+
+func TestNSEC3SetProofs(t *testing.T) {
+	set := testNSEC3Set(t)
+
+	for _, name := range []string{"example.com", "www.example.com", "b.example.com", "*.wild.example.com"} {
+		m, err := set.Matching(MustParseName(name))
+		if err != nil {
+			t.Fatalf("set.Matching(%v) unexpected error: %v", name, err)
+		}
+		if m == nil {
+			t.Errorf("set.Matching(%v) = nil, want NSEC3", name)
+		}
+	}
+
+	for _, name := range []string{"nonexistent.example.com", "a.www.example.com"} {
+		m, err := set.Matching(MustParseName(name))
+		if err != nil {
+			t.Fatalf("set.Matching(%v) unexpected error: %v", name, err)
+		}
+		if m != nil {
+			t.Errorf("set.Matching(%v) = %v, want nil", name, m.Name.String())
+		}
+		c, err := set.Covering(MustParseName(name))
+		if err != nil {
+			t.Fatalf("set.Covering(%v) unexpected error: %v", name, err)
+		}
+		if c == nil {
+			t.Errorf("set.Covering(%v) = nil, want NSEC3", name)
+		}
+	}
+
+	proof, wildcard, err := set.NameErrorProof(MustParseName("x.y.www.example.com"))
+	if err != nil {
+		t.Fatalf("set.NameErrorProof() unexpected error: %v", err)
+	}
+	if s := proof.ClosestEncloser.String(); s != "www.example.com." {
+		t.Errorf("proof.ClosestEncloser = %v, want: www.example.com.", s)
+	}
+	if s := proof.NextCloser.String(); s != "y.www.example.com." {
+		t.Errorf("proof.NextCloser = %v, want: y.www.example.com.", s)
+	}
+	if w := proof.Wildcard(); w.String() != "*.www.example.com." {
+		t.Errorf("proof.Wildcard() = %v, want: *.www.example.com.", w.String())
+	}
+	if wildcard == nil {
+		t.Errorf("wildcard NSEC3 = nil")
+	}
+	if !proof.OptOut() {
+		t.Errorf("proof.OptOut() = false, want: true")
+	}
+
+	// The wildcard *.wild.example.com exists.
+	if _, _, err := set.NameErrorProof(MustParseName("x.wild.example.com")); err != errWildcardNotCovered {
+		t.Errorf("set.NameErrorProof() unexpected error: %v, want: %v", err, errWildcardNotCovered)
+	}
+	proof, err = set.ClosestEncloserProof(MustParseName("x.wild.example.com"))
+	if err != nil {
+		t.Fatalf("set.ClosestEncloserProof() unexpected error: %v", err)
+	}
+	if s := proof.ClosestEncloser.String(); s != "wild.example.com." {
+		t.Errorf("proof.ClosestEncloser = %v, want: wild.example.com.", s)
+	}
+
+	// Insecure delegation, excluded from the chain because of Opt-Out.
+	proof, err = set.ClosestEncloserProof(MustParseName("www.insecure.example.com"))
+	if err != nil {
+		t.Fatalf("set.ClosestEncloserProof() unexpected error: %v", err)
+	}
+	if s := proof.ClosestEncloser.String(); s != "example.com." {
+		t.Errorf("proof.ClosestEncloser = %v, want: example.com.", s)
+	}
+	if s := proof.NextCloser.String(); s != "insecure.example.com." {
+		t.Errorf("proof.NextCloser = %v, want: insecure.example.com.", s)
+	}
+
+	if _, err := set.ClosestEncloserProof(MustParseName("www.secure.example.com")); err != errInvalidClosestEncloser {
+		t.Errorf("set.ClosestEncloserProof() unexpected error: %v, want: %v", err, errInvalidClosestEncloser)
+	}
+	if _, err := set.ClosestEncloserProof(MustParseName("www.example.com")); err != errNoClosestEncloser {
+		t.Errorf("set.ClosestEncloserProof() unexpected error: %v, want: %v", err, errNoClosestEncloser)
+	}
+	if _, err := set.ClosestEncloserProof(MustParseName("www.example.net")); err != errNameNotInZone {
+		t.Errorf("set.ClosestEncloserProof() unexpected error: %v, want: %v", err, errNameNotInZone)
+	}
+
+	set[0].NSEC3.Iterations++
+	if _, err := set.ClosestEncloserProof(MustParseName("x.www.example.com")); err != errInconsistentNSEC3 {
+		t.Errorf("set.ClosestEncloserProof() unexpected error: %v, want: %v", err, errInconsistentNSEC3)
+	}
+}
@@ -46,7 +46,7 @@ func Parse(msg []byte) (Parser, Header, error) {
 // After changing the parsing section (using one of the Start* methods described above) the [Parser.ResourceHeader]
 // method in conjunction with resource parsing methods [Parser.ResourceA], [Parser.ResourceAAAA], [Parser.ResourceNS],
 // [Parser.ResourceCNAME], [Parser.ResourceSOA], [Parser.ResourcePTR] [Parser.ResourceMX], [Parser.RawResourceTXT],
-// [Parser.SkipResourceData] or [Parser.RDParser] can be used to parse the resource data.
+// [Parser.ResourceSRV], [Parser.ResourceCAA], [Parser.SkipResourceData] or [Parser.RDParser] can be used to parse the resource data.
 //
 // Parser can be copied to preserve the current parsing state.
 type Parser struct {
@@ -186,7 +186,7 @@ func (m *Parser) Question() (Question, error) {
 //
 // Every call to ResourceHeader must be followed by a appropriate
 // Resource Data parsing method ([Parser.ResourceA], [Parser.ResourceAAAA],
-// [Parser.ResourceCNAME], [Parser.ResourceMX], [Parser.RawResourceTXT]) depending
+// [Parser.ResourceCNAME], [Parser.ResourceMX], [Parser.RawResourceTXT], [Parser.ResourceSRV], [Parser.ResourceCAA]) depending
 // on the returned [ResourceHeader] Type field or skipped by [Parser.SkipResourceData]
 // (even when the [ResourceHeader] Length field is equal to zero).
 //
@@ -408,6 +408,30 @@ func (m *Parser) ResourcePTR() (ResourcePTR, error) {
 	return ResourcePTR{ptr}, nil
 }
 
+// ResourceDNAME parses a single DNAME resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeDNAME].
+func (m *Parser) ResourceDNAME() (ResourceDNAME, error) {
+	if !m.resourceData || m.nextResourceType != TypeDNAME {
+		return ResourceDNAME{}, errInvalidOperation
+	}
+
+	var dname Name
+	offset, err := dname.unpack(m.msg, m.curOffset)
+	if err != nil {
+		return ResourceDNAME{}, err
+	}
+
+	if offset != m.nextResourceDataLength {
+		return ResourceDNAME{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += int(offset)
+	return ResourceDNAME{dname}, nil
+}
+
 // ResourceMX parses a single MX resouce data.
 //
 // This method can only be used after [Parser.ResourceHeader]
@@ -441,6 +465,79 @@ func (m *Parser) ResourceMX() (ResourceMX, error) {
 	}, nil
 }
 
+// ResourceSRV parses a single SRV resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeSRV].
+func (m *Parser) ResourceSRV() (ResourceSRV, error) {
+	if !m.resourceData || m.nextResourceType != TypeSRV {
+		return ResourceSRV{}, errInvalidOperation
+	}
+
+	if len(m.msg)-m.curOffset < 6 {
+		return ResourceSRV{}, errInvalidDNSMessage
+	}
+
+	priority := unpackUint16(m.msg[m.curOffset:])
+	weight := unpackUint16(m.msg[m.curOffset+2:])
+	port := unpackUint16(m.msg[m.curOffset+4:])
+
+	// RFC 2782 forbids compression of the target name, but
+	// we accept it anyway, some servers compress it.
+	var target Name
+	offset, err := target.unpack(m.msg, m.curOffset+6)
+	if err != nil {
+		return ResourceSRV{}, err
+	}
+
+	if m.nextResourceDataLength != offset+6 {
+		return ResourceSRV{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += int(m.nextResourceDataLength)
+	return ResourceSRV{
+		Priority: priority,
+		Weight:   weight,
+		Port:     port,
+		Target:   target,
+	}, nil
+}
+
+// ResourceCAA parses a single CAA resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeCAA].
+func (m *Parser) ResourceCAA() (ResourceCAA, error) {
+	if !m.resourceData || m.nextResourceType != TypeCAA {
+		return ResourceCAA{}, errInvalidOperation
+	}
+
+	if len(m.msg)-m.curOffset < int(m.nextResourceDataLength) || m.nextResourceDataLength < 2 {
+		return ResourceCAA{}, errInvalidDNSMessage
+	}
+
+	rdata := m.msg[m.curOffset : m.curOffset+int(m.nextResourceDataLength)]
+	flags := rdata[0]
+	tagLength := int(rdata[1])
+	if tagLength > len(rdata)-2 {
+		return ResourceCAA{}, errInvalidDNSMessage
+	}
+
+	tag := rdata[2 : 2+tagLength]
+	if !isValidCAATag(tag) {
+		return ResourceCAA{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	m.curOffset += int(m.nextResourceDataLength)
+	return ResourceCAA{
+		Flags: flags,
+		Tag:   tag,
+		Value: rdata[2+tagLength:],
+	}, nil
+}
+
 // RawResourceTXT parses a single TXT resouce data.
 //
 // This method can only be used after [Parser.ResourceHeader]
@@ -479,6 +576,130 @@ func (m *Parser) SkipResourceData() error {
 	return nil
 }
 
+// appendUncompressedResourceData parses the resource data and appends it to dst,
+// names embedded in the resource data of known resource types are decompressed.
+//
+// This method can only be called after calling the [Parser.ResourceHeader] method.
+func (m *Parser) appendUncompressedResourceData(dst []byte) ([]byte, error) {
+	if !m.resourceData {
+		return dst, errInvalidOperation
+	}
+
+	switch m.nextResourceType {
+	case TypeNS:
+		ns, err := m.ResourceNS()
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, ns.NS.asSlice()...), nil
+	case TypeCNAME:
+		cname, err := m.ResourceCNAME()
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, cname.CNAME.asSlice()...), nil
+	case TypePTR:
+		ptr, err := m.ResourcePTR()
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, ptr.PTR.asSlice()...), nil
+	case TypeDNAME:
+		dname, err := m.ResourceDNAME()
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, dname.DNAME.asSlice()...), nil
+	case TypeMX:
+		mx, err := m.ResourceMX()
+		if err != nil {
+			return dst, err
+		}
+		dst = appendUint16(dst, mx.Pref)
+		return append(dst, mx.MX.asSlice()...), nil
+	case TypeSRV:
+		srv, err := m.ResourceSRV()
+		if err != nil {
+			return dst, err
+		}
+		dst = appendUint16(dst, srv.Priority)
+		dst = appendUint16(dst, srv.Weight)
+		dst = appendUint16(dst, srv.Port)
+		return append(dst, srv.Target.asSlice()...), nil
+	case TypeSOA:
+		soa, err := m.ResourceSOA()
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, soa.NS.asSlice()...)
+		dst = append(dst, soa.Mbox.asSlice()...)
+		dst = appendUint32(dst, soa.Serial)
+		dst = appendUint32(dst, soa.Refresh)
+		dst = appendUint32(dst, soa.Retry)
+		dst = appendUint32(dst, soa.Expire)
+		return appendUint32(dst, soa.Minimum), nil
+	case TypeRRSIG:
+		rrsig, err := m.ResourceRRSIG()
+		if err != nil {
+			return dst, err
+		}
+		return appendRRSIGRData(dst, &rrsig, true), nil
+	case TypeNSEC:
+		nsec, err := m.ResourceNSEC()
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, nsec.NextDomain.asSlice()...)
+		return append(dst, nsec.TypeBitmap...), nil
+	case TypeSIG:
+		sig, err := m.ResourceSIG()
+		if err != nil {
+			return dst, err
+		}
+		return appendRRSIGRData(dst, (*ResourceRRSIG)(&sig), true), nil
+	case TypeTSIG:
+		tsig, err := m.ResourceTSIG()
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, tsig.Algorithm.asSlice()...)
+		dst = appendTSIGTimers(dst, &tsig)
+		dst = appendUint16(dst, uint16(len(tsig.MAC)))
+		dst = append(dst, tsig.MAC...)
+		dst = appendUint16(dst, tsig.OriginalID)
+		dst = appendUint16(dst, uint16(tsig.Error))
+		dst = appendUint16(dst, uint16(len(tsig.OtherData)))
+		return append(dst, tsig.OtherData...), nil
+	case TypeSVCB, TypeHTTPS:
+		// The service parameters never contain compressed names, so after validating
+		// the entire resource data, they are copied verbatim.
+		p := *m
+		svcbp, err := p.ResourceSVCBParser()
+		if err != nil {
+			return dst, err
+		}
+		if m.nextResourceType == TypeSVCB {
+			_, err = m.ResourceSVCB()
+		} else {
+			_, err = m.ResourceHTTPS()
+		}
+		if err != nil {
+			return dst, err
+		}
+		dst = appendUint16(dst, svcbp.priority)
+		dst = append(dst, svcbp.target.asSlice()...)
+		return append(dst, m.msg[svcbp.offset:svcbp.maxOffset]...), nil
+	}
+
+	rdata, err := m.rData()
+	if err != nil {
+		return dst, err
+	}
+	m.resourceData = false
+	m.curOffset += int(m.nextResourceDataLength)
+	return append(dst, rdata...), nil
+}
+
 // RDParser is a resource data parser used to parse custom resources.
 type RDParser struct {
 	m         *Parser
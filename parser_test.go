@@ -532,6 +532,18 @@ func TestParserInvalidOperation(t *testing.T) {
 		b.RawResourceTXT(hdr, RawResourceTXT{[]byte{1, 'a', 2, 'b', 'a'}})
 		b.ResourceCNAME(hdr, ResourceCNAME{CNAME: MustParseName("www.example.com")})
 		b.ResourceMX(hdr, ResourceMX{Pref: 100, MX: MustParseName("smtp.example.com")})
+		b.ResourceSRV(hdr, ResourceSRV{Priority: 1, Weight: 2, Port: 3, Target: MustParseName("sip.example.com")})
+		b.ResourceCAA(hdr, ResourceCAA{Flags: 0, Tag: []byte("issue"), Value: []byte("ca.example.net")})
+		b.ResourceSVCB(hdr, ResourceSVCB{Priority: 1, Target: MustParseName("svc.example.com"), Params: []SVCParam{&SVCParamPort{Port: 443}}})
+		b.ResourceHTTPS(hdr, ResourceHTTPS{Priority: 1, Target: MustParseName("."), Params: []SVCParam{&SVCParamALPN{ALPN: [][]byte{[]byte("h2")}}}})
+		b.ResourceDNSKEY(hdr, ResourceDNSKEY{Flags: DNSKEYFlagZone, Protocol: DNSKEYProtocol, Algorithm: DNSSECAlgorithmED25519, PublicKey: make([]byte, 32)})
+		b.ResourceCDNSKEY(hdr, ResourceCDNSKEY{Flags: DNSKEYFlagZone, Protocol: DNSKEYProtocol, Algorithm: DNSSECAlgorithmED25519, PublicKey: make([]byte, 32)})
+		b.ResourceDS(hdr, ResourceDS{KeyTag: 1, Algorithm: DNSSECAlgorithmED25519, DigestType: DigestTypeSHA256, Digest: make([]byte, 32)})
+		b.ResourceCDS(hdr, ResourceCDS{KeyTag: 1, Algorithm: DNSSECAlgorithmED25519, DigestType: DigestTypeSHA256, Digest: make([]byte, 32)})
+		b.ResourceRRSIG(hdr, ResourceRRSIG{TypeCovered: TypeA, Algorithm: DNSSECAlgorithmED25519, Labels: 2, SignerName: MustParseName("example.com"), Signature: make([]byte, 64)})
+		b.ResourceNSEC(hdr, ResourceNSEC{NextDomain: MustParseName("www.example.com"), TypeBitmap: NewTypeBitmap(TypeA, TypeRRSIG, TypeNSEC)})
+		b.ResourceNSEC3(hdr, ResourceNSEC3{HashAlgorithm: NSEC3HashAlgorithmSHA1, NextHashedOwner: make([]byte, 20), TypeBitmap: NewTypeBitmap(TypeA)})
+		b.ResourceNSEC3PARAM(hdr, ResourceNSEC3PARAM{HashAlgorithm: NSEC3HashAlgorithmSHA1, Salt: []byte{1, 2}})
 		b.ResourceOPT(hdr, ResourceOPT{Options: []EDNS0Option{
 			&EDNS0ClientSubnet{Family: AddressFamilyIPv4, SourcePrefixLength: 2, ScopePrefixLength: 3, Address: []byte{192, 0, 2, 1}},
 			&EDNS0Cookie{
@@ -548,7 +560,7 @@ func TestParserInvalidOperation(t *testing.T) {
 		t.Fatalf("Parse() unexpected error: %v", err)
 	}
 
-	knownResourceTypes := []Type{TypeA, TypeAAAA, TypeNS, TypeSOA, TypePTR, TypeTXT, TypeCNAME, TypeMX, TypeOPT}
+	knownResourceTypes := []Type{TypeA, TypeAAAA, TypeNS, TypeSOA, TypePTR, TypeTXT, TypeCNAME, TypeMX, TypeSRV, TypeCAA, TypeSVCB, TypeHTTPS, TypeDNSKEY, TypeCDNSKEY, TypeDS, TypeCDS, TypeRRSIG, TypeNSEC, TypeNSEC3, TypeNSEC3PARAM, TypeSIG, TypeOPT, TypeDNAME}
 	parseResource := func(p *Parser, resType Type) error {
 		switch resType {
 		case TypeA:
@@ -567,8 +579,36 @@ func TestParserInvalidOperation(t *testing.T) {
 			_, err = p.ResourceCNAME()
 		case TypeMX:
 			_, err = p.ResourceMX()
+		case TypeSRV:
+			_, err = p.ResourceSRV()
+		case TypeCAA:
+			_, err = p.ResourceCAA()
+		case TypeSVCB:
+			_, err = p.ResourceSVCB()
+		case TypeHTTPS:
+			_, err = p.ResourceHTTPS()
+		case TypeDNSKEY:
+			_, err = p.ResourceDNSKEY()
+		case TypeCDNSKEY:
+			_, err = p.ResourceCDNSKEY()
+		case TypeDS:
+			_, err = p.ResourceDS()
+		case TypeCDS:
+			_, err = p.ResourceCDS()
+		case TypeRRSIG:
+			_, err = p.ResourceRRSIG()
+		case TypeNSEC:
+			_, err = p.ResourceNSEC()
+		case TypeNSEC3:
+			_, err = p.ResourceNSEC3()
+		case TypeNSEC3PARAM:
+			_, err = p.ResourceNSEC3PARAM()
+		case TypeSIG:
+			_, err = p.ResourceSIG()
 		case TypeOPT:
 			_, err = p.ResourceOPT()
+		case TypeDNAME:
+			_, err = p.ResourceDNAME()
 		default:
 			panic("unknown resource")
 		}
@@ -858,6 +898,34 @@ func FuzzParser(f *testing.F) {
 						_, err = p.ResourcePTR()
 					case TypeMX:
 						_, err = p.ResourceMX()
+					case TypeSRV:
+						_, err = p.ResourceSRV()
+					case TypeCAA:
+						_, err = p.ResourceCAA()
+					case TypeSVCB:
+						_, err = p.ResourceSVCB()
+					case TypeHTTPS:
+						_, err = p.ResourceHTTPS()
+					case TypeDNSKEY:
+						_, err = p.ResourceDNSKEY()
+					case TypeCDNSKEY:
+						_, err = p.ResourceCDNSKEY()
+					case TypeDS:
+						_, err = p.ResourceDS()
+					case TypeCDS:
+						_, err = p.ResourceCDS()
+					case TypeRRSIG:
+						_, err = p.ResourceRRSIG()
+					case TypeNSEC:
+						_, err = p.ResourceNSEC()
+					case TypeNSEC3:
+						_, err = p.ResourceNSEC3()
+					case TypeNSEC3PARAM:
+						_, err = p.ResourceNSEC3PARAM()
+					case TypeTSIG:
+						_, err = p.ResourceTSIG()
+					case TypeSIG:
+						_, err = p.ResourceSIG()
 					case TypeTXT:
 						var txt RawResourceTXT
 						txt, err = p.RawResourceTXT()
@@ -885,3 +953,88 @@ func FuzzParser(f *testing.F) {
 		}
 	})
 }
+
+func TestParserResourceSRVCompressedTarget(t *testing.T) {
+	raw := binary.BigEndian.AppendUint16(make([]byte, 0, 12), 0)
+	raw = binary.BigEndian.AppendUint16(raw, 0)
+	raw = binary.BigEndian.AppendUint16(raw, 0)
+	raw = binary.BigEndian.AppendUint16(raw, 1)
+	raw = binary.BigEndian.AppendUint16(raw, 0)
+	raw = binary.BigEndian.AppendUint16(raw, 0)
+
+	raw = append(raw, []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}...)
+	raw = binary.BigEndian.AppendUint16(raw, uint16(TypeSRV))
+	raw = binary.BigEndian.AppendUint16(raw, uint16(ClassIN))
+	raw = binary.BigEndian.AppendUint32(raw, 3600)
+	raw = binary.BigEndian.AppendUint16(raw, 12)
+	raw = binary.BigEndian.AppendUint16(raw, 10)
+	raw = binary.BigEndian.AppendUint16(raw, 20)
+	raw = binary.BigEndian.AppendUint16(raw, 5060)
+	raw = append(raw, 3, 's', 'i', 'p', 0xC0, 12)
+
+	p, _, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+
+	if _, err := p.ResourceHeader(); err != nil {
+		t.Fatalf("p.ResourceHeader(): unexpected error: %v", err)
+	}
+
+	srv, err := p.ResourceSRV()
+	if err != nil {
+		t.Fatalf("p.ResourceSRV() unexpected error: %v", err)
+	}
+
+	if srv.Priority != 10 || srv.Weight != 20 || srv.Port != 5060 {
+		t.Errorf("p.ResourceSRV() = %#v, want: Priority = 10, Weight = 20, Port = 5060", srv)
+	}
+	expectParserName(t, "p.ResourceSRV().Target", srv.Target, "sip.example.com", true)
+
+	if err := p.End(); err != nil {
+		t.Fatalf("p.End() unexpected error: %v", err)
+	}
+}
+
+func TestParserResourceCAAInvalid(t *testing.T) {
+	cases := [][]byte{
+		{0},
+		{0, 0, 'a'},
+		{0, 5, 'i', 's', 's', 'u'},
+		{0, 3, 'a', '-', 'b'},
+		{0, 16, 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a'},
+	}
+
+	for _, rdata := range cases {
+		b := StartBuilder(make([]byte, 0, 512), 0, 0)
+		b.StartAnswers()
+		rdb, err := b.RDBuilder(ResourceHeader{
+			Name:  MustParseName("example.com"),
+			Type:  TypeCAA,
+			Class: ClassIN,
+		})
+		if err != nil {
+			t.Fatalf("b.RDBuilder() unexpected error: %v", err)
+		}
+		rdb.Bytes(rdata)
+		rdb.End()
+
+		p, _, err := Parse(b.Bytes())
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		if err := p.StartAnswers(); err != nil {
+			t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+		}
+		if _, err := p.ResourceHeader(); err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		if _, err := p.ResourceCAA(); err != errInvalidDNSMessage {
+			t.Errorf("p.ResourceCAA() with resource data %v, unexpected error: %v, want: %v", rdata, err, errInvalidDNSMessage)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package dnsmsg
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file implements the presentation format (RFC 1035, Section 5.1) of the
+// resources, as used in zone files. All String methods of the resources
+// return only the resource data, use [ResourceHeader.String] for the resource header.
+
+// String returns the question in presentation format, e.g. "example.com. IN A".
+func (q Question) String() string {
+	return q.Name.String() + " " + q.Class.String() + " " + q.Type.String()
+}
+
+// String returns the resource header in presentation format, e.g. "example.com. 300 IN A".
+// The Length field is not included.
+func (h ResourceHeader) String() string {
+	b := append([]byte(h.Name.String()), ' ')
+	b = strconv.AppendUint(b, uint64(h.TTL), 10)
+	b = append(b, ' ')
+	b = append(b, h.Class.String()...)
+	b = append(b, ' ')
+	b = append(b, h.Type.String()...)
+	return string(b)
+}
+
+// appendCharacterString appends s to dst as a <character-string> (RFC 1035, Section 5.1),
+// s is quoted when forceQuote is true, or when it is required (e.g. s contains a space).
+func appendCharacterString(dst, s []byte, forceQuote bool) []byte {
+	quote := forceQuote || len(s) == 0
+	for _, v := range s {
+		if v == ' ' || v == ';' || v == '(' || v == ')' {
+			quote = true
+			break
+		}
+	}
+
+	if quote {
+		dst = append(dst, '"')
+	}
+	for _, v := range s {
+		switch {
+		case v == '"' || v == '\\':
+			dst = append(dst, '\\', v)
+		case v < ' ' || v > '~':
+			dst = append(dst, '\\')
+			dst = append(dst, toASCIIDecimal(v)...)
+		default:
+			dst = append(dst, v)
+		}
+	}
+	if quote {
+		dst = append(dst, '"')
+	}
+	return dst
+}
+
+// appendUnknownRData appends rdata to dst using the generic
+// format for unknown resource data (RFC 3597, Section 5).
+func appendUnknownRData(dst, rdata []byte) []byte {
+	dst = append(dst, `\# `...)
+	dst = strconv.AppendUint(dst, uint64(len(rdata)), 10)
+	if len(rdata) != 0 {
+		dst = append(dst, ' ')
+		dst = appendHexUpper(dst, rdata)
+	}
+	return dst
+}
+
+func appendHexUpper(dst, b []byte) []byte {
+	const hexUpper = "0123456789ABCDEF"
+	for _, v := range b {
+		dst = append(dst, hexUpper[v>>4], hexUpper[v&0xF])
+	}
+	return dst
+}
+
+func appendBase64(dst, b []byte) []byte {
+	n := len(dst)
+	dst = append(dst, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+	base64.StdEncoding.Encode(dst[n:], b)
+	return dst
+}
+
+func appendUint(dst []byte, v uint64) []byte {
+	dst = strconv.AppendUint(dst, v, 10)
+	return append(dst, ' ')
+}
+
+// appendDNSSECTime appends t in the YYYYMMDDHHmmSS format (RFC 4034, Section 3.2).
+func appendDNSSECTime(dst []byte, t uint32) []byte {
+	return time.Unix(int64(t), 0).UTC().AppendFormat(dst, "20060102150405")
+}
+
+func (r ResourceA) String() string {
+	return netip.AddrFrom4(r.A).String()
+}
+
+func (r ResourceAAAA) String() string {
+	return netip.AddrFrom16(r.AAAA).String()
+}
+
+func (r ResourceNS) String() string {
+	return r.NS.String()
+}
+
+func (r ResourceCNAME) String() string {
+	return r.CNAME.String()
+}
+
+func (r ResourcePTR) String() string {
+	return r.PTR.String()
+}
+
+func (r ResourceDNAME) String() string {
+	return r.DNAME.String()
+}
+
+func (r ResourceSOA) String() string {
+	b := append([]byte(r.NS.String()), ' ')
+	b = append(b, r.Mbox.String()...)
+	b = append(b, ' ')
+	b = appendUint(b, uint64(r.Serial))
+	b = appendUint(b, uint64(r.Refresh))
+	b = appendUint(b, uint64(r.Retry))
+	b = appendUint(b, uint64(r.Expire))
+	b = strconv.AppendUint(b, uint64(r.Minimum), 10)
+	return string(b)
+}
+
+func (r ResourceMX) String() string {
+	return strconv.FormatUint(uint64(r.Pref), 10) + " " + r.MX.String()
+}
+
+// String returns the resource data in presentation format, every
+// character-string is quoted, e.g. "\"v=spf1 -all\" \"second\"".
+func (r ResourceTXT) String() string {
+	var b []byte
+	for i, txt := range r.TXT {
+		if i != 0 {
+			b = append(b, ' ')
+		}
+		b = appendCharacterString(b, txt, true)
+	}
+	return string(b)
+}
+
+func (r ResourceSRV) String() string {
+	b := appendUint(nil, uint64(r.Priority))
+	b = appendUint(b, uint64(r.Weight))
+	b = appendUint(b, uint64(r.Port))
+	b = append(b, r.Target.String()...)
+	return string(b)
+}
+
+func (r ResourceCAA) String() string {
+	b := appendUint(nil, uint64(r.Flags))
+	b = append(b, r.Tag...)
+	b = append(b, ' ')
+	b = appendCharacterString(b, r.Value, true)
+	return string(b)
+}
+
+// String returns the OPT resource in a human readable form, as there is no standard presentation format
+// for the OPT resource. Options are separated by spaces, supported options are formatted
+// as "ECS=<address>/<source>/<scope>", "COOKIE=<hex>" and "EDE=<code>:<text>".
+func (r ResourceOPT) String() string {
+	var b []byte
+	for i, opt := range r.Options {
+		if i != 0 {
+			b = append(b, ' ')
+		}
+		switch opt := opt.(type) {
+		case *EDNS0ClientSubnet:
+			b = append(b, "ECS="...)
+			b = append(b, opt.String()...)
+		case *EDNS0Cookie:
+			b = append(b, "COOKIE="...)
+			b = append(b, opt.String()...)
+		case *EDNS0ExtendedDNSError:
+			b = append(b, "EDE="...)
+			b = append(b, opt.String()...)
+		}
+	}
+	return string(b)
+}
+
+// String returns the option as "<address>/<source prefix length>/<scope prefix length>".
+func (o *EDNS0ClientSubnet) String() string {
+	var addr string
+	switch {
+	case o.Family == AddressFamilyIPv4 && len(o.Address) <= 4:
+		var ip [4]byte
+		copy(ip[:], o.Address)
+		addr = netip.AddrFrom4(ip).String()
+	case o.Family == AddressFamilyIPv6 && len(o.Address) <= 16:
+		var ip [16]byte
+		copy(ip[:], o.Address)
+		addr = netip.AddrFrom16(ip).String()
+	default:
+		addr = hex.EncodeToString(o.Address)
+	}
+	return addr + "/" + strconv.FormatUint(uint64(o.SourcePrefixLength), 10) + "/" + strconv.FormatUint(uint64(o.ScopePrefixLength), 10)
+}
+
+// String returns the client and server cookie as a hex string.
+func (o *EDNS0Cookie) String() string {
+	return hex.EncodeToString(o.ClientCookie[:]) + hex.EncodeToString(o.ServerCookie[:o.EncodingLength()-len(o.ClientCookie)])
+}
+
+// String returns the option as "<info code>:<extra text>", the extra text is
+// quoted as a character-string, it is omitted when empty.
+func (o *EDNS0ExtendedDNSError) String() string {
+	b := strconv.AppendUint(nil, uint64(o.InfoCode), 10)
+	if len(o.ExtraText) != 0 {
+		b = append(b, ':')
+		b = appendCharacterString(b, o.ExtraText, true)
+	}
+	return string(b)
+}
+
+// String returns the key in presentation format (RFC 9460, Section 2.1),
+// unknown keys are formatted as "keyNNNNN".
+func (k SVCParamKey) String() string {
+	switch k {
+	case SVCParamKeyMandatory:
+		return "mandatory"
+	case SVCParamKeyALPN:
+		return "alpn"
+	case SVCParamKeyNoDefaultALPN:
+		return "no-default-alpn"
+	case SVCParamKeyPort:
+		return "port"
+	case SVCParamKeyIPv4Hint:
+		return "ipv4hint"
+	case SVCParamKeyECH:
+		return "ech"
+	case SVCParamKeyIPv6Hint:
+		return "ipv6hint"
+	default:
+		return "key" + strconv.FormatUint(uint64(k), 10)
+	}
+}
+
+func (r ResourceSVCB) String() string {
+	b := appendUint(nil, uint64(r.Priority))
+	b = append(b, r.Target.String()...)
+	for _, param := range r.Params {
+		b = append(b, ' ')
+		b = appendSVCParam(b, param)
+	}
+	return string(b)
+}
+
+func (r ResourceHTTPS) String() string {
+	return ResourceSVCB(r).String()
+}
+
+func appendSVCParam(dst []byte, param SVCParam) []byte {
+	dst = append(dst, param.svcParamKey().String()...)
+
+	var value []byte
+	switch param := param.(type) {
+	case *SVCParamMandatory:
+		for i, key := range param.Keys {
+			if i != 0 {
+				value = append(value, ',')
+			}
+			value = append(value, key.String()...)
+		}
+	case *SVCParamALPN:
+		for i, alpn := range param.ALPN {
+			if i != 0 {
+				value = append(value, ',')
+			}
+			// Commas and backslashes are escaped, as described in RFC 9460, Appendix A.1.
+			for _, v := range alpn {
+				if v == ',' || v == '\\' {
+					value = append(value, '\\')
+				}
+				value = append(value, v)
+			}
+		}
+	case *SVCParamNoDefaultALPN:
+		return dst
+	case *SVCParamPort:
+		value = strconv.AppendUint(value, uint64(param.Port), 10)
+	case *SVCParamIPv4Hint:
+		for i, hint := range param.Hints {
+			if i != 0 {
+				value = append(value, ',')
+			}
+			value = netip.AddrFrom4(hint).AppendTo(value)
+		}
+	case *SVCParamECH:
+		value = appendBase64(value, param.ECH)
+	case *SVCParamIPv6Hint:
+		for i, hint := range param.Hints {
+			if i != 0 {
+				value = append(value, ',')
+			}
+			value = netip.AddrFrom16(hint).AppendTo(value)
+		}
+	case *SVCParamRaw:
+		value = param.Value
+	}
+
+	dst = append(dst, '=')
+	return appendCharacterString(dst, value, false)
+}
+
+func (r ResourceDNSKEY) String() string {
+	b := appendUint(nil, uint64(r.Flags))
+	b = appendUint(b, uint64(r.Protocol))
+	b = appendUint(b, uint64(r.Algorithm))
+	b = appendBase64(b, r.PublicKey)
+	return string(b)
+}
+
+func (r ResourceCDNSKEY) String() string {
+	return ResourceDNSKEY(r).String()
+}
+
+func (r ResourceDS) String() string {
+	b := appendUint(nil, uint64(r.KeyTag))
+	b = appendUint(b, uint64(r.Algorithm))
+	b = appendUint(b, uint64(r.DigestType))
+	b = appendHexUpper(b, r.Digest)
+	return string(b)
+}
+
+func (r ResourceCDS) String() string {
+	return ResourceDS(r).String()
+}
+
+func (r ResourceRRSIG) String() string {
+	b := append([]byte(r.TypeCovered.String()), ' ')
+	b = appendUint(b, uint64(r.Algorithm))
+	b = appendUint(b, uint64(r.Labels))
+	b = appendUint(b, uint64(r.OriginalTTL))
+	b = appendDNSSECTime(b, r.Expiration)
+	b = append(b, ' ')
+	b = appendDNSSECTime(b, r.Inception)
+	b = append(b, ' ')
+	b = appendUint(b, uint64(r.KeyTag))
+	b = append(b, r.SignerName.String()...)
+	b = append(b, ' ')
+	b = appendBase64(b, r.Signature)
+	return string(b)
+}
+
+func (r ResourceSIG) String() string {
+	return ResourceRRSIG(r).String()
+}
+
+// String returns the types in the bitmap separated by spaces, e.g. "A NS SOA".
+func (b TypeBitmap) String() string {
+	var s []byte
+	for i, t := range b.Types() {
+		if i != 0 {
+			s = append(s, ' ')
+		}
+		s = append(s, t.String()...)
+	}
+	return string(s)
+}
+
+func (r ResourceNSEC) String() string {
+	b := []byte(r.NextDomain.String())
+	if len(r.TypeBitmap) != 0 {
+		b = append(b, ' ')
+		b = append(b, r.TypeBitmap.String()...)
+	}
+	return string(b)
+}
+
+func appendNSEC3Salt(dst, salt []byte) []byte {
+	if len(salt) == 0 {
+		return append(dst, '-')
+	}
+	return appendHexUpper(dst, salt)
+}
+
+func (r ResourceNSEC3) String() string {
+	b := appendUint(nil, uint64(r.HashAlgorithm))
+	b = appendUint(b, uint64(r.Flags))
+	b = appendUint(b, uint64(r.Iterations))
+	b = appendNSEC3Salt(b, r.Salt)
+	b = append(b, ' ')
+	b = append(b, strings.ToUpper(nsec3Base32.EncodeToString(r.NextHashedOwner))...)
+	if len(r.TypeBitmap) != 0 {
+		b = append(b, ' ')
+		b = append(b, r.TypeBitmap.String()...)
+	}
+	return string(b)
+}
+
+func (r ResourceNSEC3PARAM) String() string {
+	b := appendUint(nil, uint64(r.HashAlgorithm))
+	b = appendUint(b, uint64(r.Flags))
+	b = appendUint(b, uint64(r.Iterations))
+	b = appendNSEC3Salt(b, r.Salt)
+	return string(b)
+}
+
+// String returns the TSIG resource data in the format used by BIND, e.g.
+// "hmac-sha256. 1700000000 300 32 <base64 MAC> 1234 0 0".
+func (r ResourceTSIG) String() string {
+	b := append([]byte(r.Algorithm.String()), ' ')
+	b = appendUint(b, r.TimeSigned)
+	b = appendUint(b, uint64(r.Fudge))
+	b = appendUint(b, uint64(len(r.MAC)))
+	if len(r.MAC) != 0 {
+		b = appendBase64(b, r.MAC)
+		b = append(b, ' ')
+	}
+	b = appendUint(b, uint64(r.OriginalID))
+	b = appendUint(b, uint64(r.Error))
+	b = strconv.AppendUint(b, uint64(len(r.OtherData)), 10)
+	if len(r.OtherData) != 0 {
+		b = append(b, ' ')
+		b = appendBase64(b, r.OtherData)
+	}
+	return string(b)
+}
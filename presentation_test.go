@@ -0,0 +1,175 @@
+package dnsmsg
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestResourcePresentation(t *testing.T) {
+	cases := []struct {
+		res    fmt.Stringer
+		expect string
+	}{
+		{ResourceA{A: [4]byte{192, 0, 2, 1}}, "192.0.2.1"},
+		{ResourceAAAA{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}, "2001:db8::1"},
+		{ResourceNS{NS: MustParseName("ns1.example.com")}, "ns1.example.com."},
+		{ResourceCNAME{CNAME: MustParseName("www.example.com")}, "www.example.com."},
+		{ResourcePTR{PTR: MustParseName("host.example.com")}, "host.example.com."},
+		{ResourceMX{Pref: 10, MX: MustParseName("mail.example.com")}, "10 mail.example.com."},
+		{
+			ResourceSOA{
+				NS:      MustParseName("ns1.example.com"),
+				Mbox:    MustParseName("admin.example.com"),
+				Serial:  2023010101,
+				Refresh: 3600,
+				Retry:   600,
+				Expire:  86400,
+				Minimum: 300,
+			},
+			"ns1.example.com. admin.example.com. 2023010101 3600 600 86400 300",
+		},
+		{ResourceTXT{TXT: [][]byte{[]byte("v=spf1 -all"), []byte(`a"b\c`), {0, 200}, {}}}, `"v=spf1 -all" "a\"b\\c" "\000\200" ""`},
+		{ResourceSRV{Priority: 1, Weight: 2, Port: 443, Target: MustParseName("srv.example.com")}, "1 2 443 srv.example.com."},
+		{ResourceCAA{Flags: 128, Tag: []byte("issue"), Value: []byte("ca.example.net")}, `128 issue "ca.example.net"`},
+		{
+			ResourceSVCB{
+				Priority: 1,
+				Target:   MustParseName("svc.example.com"),
+				Params: []SVCParam{
+					&SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyALPN, SVCParamKeyPort}},
+					&SVCParamALPN{ALPN: [][]byte{[]byte("h2"), []byte("h3")}},
+					&SVCParamNoDefaultALPN{},
+					&SVCParamPort{Port: 8443},
+					&SVCParamIPv4Hint{Hints: [][4]byte{{192, 0, 2, 1}, {192, 0, 2, 2}}},
+					&SVCParamECH{ECH: []byte{1, 2, 3}},
+					&SVCParamIPv6Hint{Hints: [][16]byte{{0x20, 0x01, 0x0d, 0xb8, 15: 1}}},
+					&SVCParamRaw{Key: 65000, Value: []byte("a b")},
+				},
+			},
+			`1 svc.example.com. mandatory=alpn,port alpn=h2,h3 no-default-alpn port=8443 ipv4hint=192.0.2.1,192.0.2.2 ech=AQID ipv6hint=2001:db8::1 key65000="a b"`,
+		},
+		{
+			ResourceHTTPS{
+				Priority: 1,
+				Target:   MustParseName("."),
+				Params:   []SVCParam{&SVCParamALPN{ALPN: [][]byte{[]byte("f\\oo,bar"), []byte("h2")}}},
+			},
+			`1 . alpn=f\\\\oo\\,bar,h2`,
+		},
+		{ResourceSVCB{Priority: 0, Target: MustParseName("example.com")}, "0 example.com."},
+		{
+			ResourceDNSKEY{Flags: 257, Protocol: 3, Algorithm: DNSSECAlgorithmED25519, PublicKey: []byte{1, 2, 3, 4}},
+			"257 3 15 AQIDBA==",
+		},
+		{
+			ResourceCDNSKEY{Flags: 0, Protocol: 3, Algorithm: 0, PublicKey: []byte{0}},
+			"0 3 0 AA==",
+		},
+		{
+			ResourceDS{KeyTag: 60485, Algorithm: DNSSECAlgorithmRSASHA1, DigestType: DigestTypeSHA1, Digest: []byte{0x2B, 0xB1, 0x83, 0xAF}},
+			"60485 5 1 2BB183AF",
+		},
+		{
+			ResourceCDS{KeyTag: 1, Algorithm: DNSSECAlgorithmED25519, DigestType: DigestTypeSHA256, Digest: []byte{0xFF}},
+			"1 15 2 FF",
+		},
+		{
+			ResourceRRSIG{
+				TypeCovered: TypeA,
+				Algorithm:   DNSSECAlgorithmRSASHA1,
+				Labels:      3,
+				OriginalTTL: 86400,
+				Expiration:  1083003325,
+				Inception:   1080411325,
+				KeyTag:      2642,
+				SignerName:  MustParseName("example.com"),
+				Signature:   []byte{1, 2, 3},
+			},
+			"A 5 3 86400 20040426181525 20040327181525 2642 example.com. AQID",
+		},
+		{
+			ResourceSIG{Algorithm: DNSSECAlgorithmED25519, Expiration: 1700000300, Inception: 1700000000, KeyTag: 1, SignerName: MustParseName("key.example.com")},
+			"TYPE0 15 0 0 20231114221820 20231114221320 1 key.example.com. ",
+		},
+		{
+			ResourceNSEC{NextDomain: MustParseName("host.example.com"), TypeBitmap: NewTypeBitmap(TypeA, TypeMX, TypeRRSIG, TypeNSEC, 1234)},
+			"host.example.com. A MX RRSIG NSEC TYPE1234",
+		},
+		{ResourceNSEC{NextDomain: MustParseName("example.com")}, "example.com."},
+		{
+			ResourceNSEC3{
+				HashAlgorithm:   NSEC3HashAlgorithmSHA1,
+				Flags:           NSEC3FlagOptOut,
+				Iterations:      12,
+				Salt:            []byte{0xAA, 0xBB, 0xCC, 0xDD},
+				NextHashedOwner: []byte{0x19, 0x93, 0x2d, 0x29, 0xc0, 0xf4, 0x87, 0xa5, 0x2e, 0x99, 0x56, 0x3a, 0x1e, 0x6d, 0x3c, 0xbe, 0xfb, 0x04, 0x17, 0x8f},
+				TypeBitmap:      NewTypeBitmap(TypeA, TypeRRSIG),
+			},
+			"1 1 12 AABBCCDD 369IQAE0UI3QABKPAOT1SR9SNRTG85SF A RRSIG",
+		},
+		{ResourceNSEC3PARAM{HashAlgorithm: NSEC3HashAlgorithmSHA1, Iterations: 0}, "1 0 0 -"},
+		{ResourceNSEC3PARAM{HashAlgorithm: NSEC3HashAlgorithmSHA1, Iterations: 12, Salt: []byte{0xAA, 0xBB}}, "1 0 12 AABB"},
+		{
+			ResourceOPT{Options: []EDNS0Option{
+				&EDNS0ClientSubnet{Family: AddressFamilyIPv4, SourcePrefixLength: 24, Address: []byte{192, 0, 2}},
+				&EDNS0ClientSubnet{Family: AddressFamilyIPv6, SourcePrefixLength: 32, ScopePrefixLength: 16, Address: []byte{0x20, 0x01, 0x0d, 0xb8}},
+				&EDNS0Cookie{ClientCookie: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, ServerCookie: [32]byte{0xAA, 7: 0xBB}},
+				&EDNS0ExtendedDNSError{InfoCode: 18, ExtraText: []byte("prohibited")},
+				&EDNS0ExtendedDNSError{InfoCode: 0},
+			}},
+			`ECS=192.0.2.0/24/0 ECS=2001:db8::/32/16 COOKIE=0102030405060708aa000000000000bb EDE=18:"prohibited" EDE=0`,
+		},
+		{
+			ResourceTSIG{
+				Algorithm:  MustParseName("hmac-sha256"),
+				TimeSigned: 1700000000,
+				Fudge:      300,
+				MAC:        []byte{1, 2, 3},
+				OriginalID: 1234,
+			},
+			"hmac-sha256. 1700000000 300 3 AQID 1234 0 0",
+		},
+		{
+			ResourceTSIG{
+				Algorithm:  MustParseName("hmac-sha256"),
+				TimeSigned: 1700000000,
+				Fudge:      300,
+				Error:      ExtendedRCodeBADTIME,
+				OtherData:  []byte{0, 0, 0x65, 0x53, 0xf1, 0x00},
+			},
+			"hmac-sha256. 1700000000 300 0 0 18 6 AABlU/EA",
+		},
+	}
+
+	for i, tt := range cases {
+		if s := tt.res.String(); s != tt.expect {
+			t.Errorf("%v: (%T).String() = %q, want: %q", i, tt.res, s, tt.expect)
+		}
+	}
+}
+
+func TestResourceHeaderPresentation(t *testing.T) {
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Type: TypeMX, Class: ClassIN, TTL: 300}
+	if s := hdr.String() + " " + (ResourceMX{Pref: 10, MX: MustParseName("mail.example.com")}).String(); s != "example.com. 300 IN MX 10 mail.example.com." {
+		t.Errorf("unexpected resource presentation: %q", s)
+	}
+
+	hdr = ResourceHeader{Name: MustParseName("example.com"), Type: 65280, Class: 3, TTL: 0}
+	if s := hdr.String(); s != "example.com. 0 CLASS3 TYPE65280" {
+		t.Errorf("hdr.String() = %q, want: %q", s, "example.com. 0 CLASS3 TYPE65280")
+	}
+
+	q := Question{Name: MustParseName("example.com"), Type: TypeAAAA, Class: ClassIN}
+	if s := q.String(); s != "example.com. IN AAAA" {
+		t.Errorf("q.String() = %q, want: %q", s, "example.com. IN AAAA")
+	}
+}
+
+func TestUnknownRDataPresentation(t *testing.T) {
+	if s := string(appendUnknownRData(nil, []byte{0x0A, 0x00, 0x00, 0x01})); s != `\# 4 0A000001` {
+		t.Errorf(`appendUnknownRData() = %q, want: %q`, s, `\# 4 0A000001`)
+	}
+	if s := string(appendUnknownRData(nil, nil)); s != `\# 0` {
+		t.Errorf(`appendUnknownRData() = %q, want: %q`, s, `\# 0`)
+	}
+}
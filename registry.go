@@ -0,0 +1,144 @@
+package dnsmsg
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CustomResource is a [ResourceBody] of a resource type not supported by this package,
+// implemented by the application.
+//
+// Custom resource types can be registered by [RegisterResourceType], registered types are
+// parsed by [Parser.ResourceBody] (and so by [Msg.Unpack] and the [ZoneParser]), other
+// types are returned as a [RawResource]. Values implementing CustomResource can be
+// appended by [Builder.Resource] (and so by [Msg.Pack]), even when not registered.
+//
+// The [ZoneParser] accepts the registered name of a custom type, but the resource data
+// must be in the generic format (RFC 3597, Section 5), e.g. "\# 4 0A000001", the presentation
+// format produced by the String method cannot be parsed back.
+type CustomResource interface {
+	ResourceBody
+
+	// Pack appends the resource data to b.
+	Pack(b *RDBuilder) error
+
+	// Unpack parses the resource data from p, p contains the resource data of a single resource.
+	// Unpack must parse the entire resource data, otherwise the resource is considered invalid.
+	Unpack(p *RDParser) error
+}
+
+type registeredResourceType struct {
+	name        string
+	newResource func() CustomResource
+}
+
+var registry struct {
+	mu    sync.RWMutex
+	types map[Type]registeredResourceType
+}
+
+// RegisterResourceType registers a custom resource type with the typ type, named name (used
+// in the presentation format, see [Type.String]). newResource returns a new CustomResource,
+// that is used for parsing resources of that type, it must return a pointer type, the
+// ResourceType method of the returned value must return typ.
+//
+// The name must start with a letter and consist only of letters, digits and hyphens, it must
+// not be in the generic TYPEnnn or CLASSnnn format (RFC 3597, Section 5) and must not be
+// a class name.
+//
+// RegisterResourceType panics when typ is already registered, or is supported by this
+// package, or the name is invalid or already used by another type.
+//
+// RegisterResourceType is intended to be called from package init functions.
+func RegisterResourceType(typ Type, name string, newResource func() CustomResource) {
+	if newResource == nil {
+		panic("dnsmsg: RegisterResourceType: nil newResource")
+	}
+	if !isValidResourceTypeName(name) {
+		panic("dnsmsg: RegisterResourceType: invalid name " + strconv.Quote(name))
+	}
+	if isBuiltinType(typ) {
+		panic("dnsmsg: RegisterResourceType: type " + typ.String() + " is supported by this package")
+	}
+	if t, ok := parseBuiltinZoneType([]byte(name)); ok {
+		panic("dnsmsg: RegisterResourceType: name " + name + " is already used by " + t.String())
+	}
+	if c, ok := parseZoneClass([]byte(name)); ok {
+		panic("dnsmsg: RegisterResourceType: name " + name + " is already used by class " + c.String())
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.types == nil {
+		registry.types = make(map[Type]registeredResourceType)
+	}
+	for t, v := range registry.types {
+		if t == typ {
+			panic("dnsmsg: RegisterResourceType: type " + v.name + " is already registered")
+		}
+		if strings.EqualFold(v.name, name) {
+			panic("dnsmsg: RegisterResourceType: name " + name + " is already registered")
+		}
+	}
+	registry.types[typ] = registeredResourceType{name: name, newResource: newResource}
+}
+
+func lookupResourceType(typ Type) (registeredResourceType, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	t, ok := registry.types[typ]
+	return t, ok
+}
+
+func lookupResourceTypeName(name string) (Type, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	for typ, t := range registry.types {
+		if strings.EqualFold(t.name, name) {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+func isBuiltinType(typ Type) bool {
+	for _, t := range zoneTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidResourceTypeName reports whether name can be used as a name of a custom
+// resource type, so that it is not confused with other fields by the [ZoneParser].
+func isValidResourceTypeName(name string) bool {
+	if name == "" || !isLetter(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isLetter(name[i]) && !isDigit(name[i]) && name[i] != '-' {
+			return false
+		}
+	}
+	for _, prefix := range []string{"TYPE", "CLASS"} {
+		if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) && isDigits(name[len(prefix):]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
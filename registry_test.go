@@ -0,0 +1,147 @@
+package dnsmsg
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testCustomType Type = 65400
+
+type testCustomResource struct {
+	Pref  uint16
+	Name  Name
+	Value uint32
+}
+
+func (r *testCustomResource) ResourceType() Type { return testCustomType }
+
+func (r *testCustomResource) String() string {
+	return strconv.Itoa(int(r.Pref)) + " " + r.Name.String() + " " + strconv.Itoa(int(r.Value))
+}
+
+func (r *testCustomResource) Pack(b *RDBuilder) error {
+	if err := b.Uint16(r.Pref); err != nil {
+		return err
+	}
+	if err := b.Name(r.Name, false); err != nil {
+		return err
+	}
+	return b.Uint32(r.Value)
+}
+
+func (r *testCustomResource) Unpack(p *RDParser) error {
+	var err error
+	if r.Pref, err = p.Uint16(); err != nil {
+		return err
+	}
+	if r.Name, err = p.Name(); err != nil {
+		return err
+	}
+	r.Value, err = p.Uint32()
+	return err
+}
+
+func init() {
+	RegisterResourceType(testCustomType, "TESTRR", func() CustomResource { return new(testCustomResource) })
+}
+
+func TestCustomResourceMsg(t *testing.T) {
+	custom := &testCustomResource{Pref: 10, Name: MustParseName("target.example.com"), Value: 1234}
+	msg := Msg{
+		Answers: []Resource{
+			{ResourceHeader{Name: MustParseName("example.com"), Type: testCustomType, Class: ClassIN, TTL: 60}, custom},
+		},
+	}
+
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("msg.Pack() unexpected error: %v", err)
+	}
+
+	var got Msg
+	if err := got.Unpack(packed); err != nil {
+		t.Fatalf("got.Unpack() unexpected error: %v", err)
+	}
+	if len(got.Answers) != 1 {
+		t.Fatalf("got %v answers, want: 1", len(got.Answers))
+	}
+	body, ok := got.Answers[0].Body.(*testCustomResource)
+	if !ok {
+		t.Fatalf("unexpected body type: %T", got.Answers[0].Body)
+	}
+	if body.Pref != custom.Pref || body.Value != custom.Value || !body.Name.Equal(&custom.Name) {
+		t.Fatalf("unexpected body: %v, want: %v", body, custom)
+	}
+
+	const expect = "example.com. 60 IN TESTRR 10 target.example.com. 1234"
+	if s := got.Answers[0].String(); s != expect {
+		t.Fatalf("got.Answers[0].String() = %q, want: %q", s, expect)
+	}
+
+	// Trailing data in the resource data.
+	b := StartBuilder(nil, 0, 0)
+	b.StartAnswers()
+	b.RawResource(ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}, RawResource{Type: testCustomType, Data: []byte{0, 1, 0, 0, 0, 0, 1, 2}})
+	if err := got.Unpack(b.Bytes()); err != errInvalidDNSMessage {
+		t.Fatalf("got.Unpack() unexpected error: %v, want: %v", err, errInvalidDNSMessage)
+	}
+}
+
+func TestCustomResourceZone(t *testing.T) {
+	const zone = `$ORIGIN example.com.
+$TTL 60
+@	TESTRR	\# 9 000A 01 61 00 000004D2
+@	type65400	\# 9 000B 01 62 00 000004D2
+`
+	res, err := parseTestZone(t, zone, ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := []string{
+		"example.com. 60 IN TESTRR 10 a. 1234",
+		"example.com. 60 IN TESTRR 11 b. 1234",
+	}
+	if len(res) != len(expect) {
+		t.Fatalf("got %v resources, want: %v", len(res), len(expect))
+	}
+	for i, v := range res {
+		if s := v.String(); s != expect[i] {
+			t.Errorf("%v: got: %q, want: %q", i, s, expect[i])
+		}
+	}
+}
+
+func TestRegisterResourceTypePanics(t *testing.T) {
+	newResource := func() CustomResource { return new(testCustomResource) }
+	cases := []struct {
+		typ  Type
+		name string
+		err  string
+	}{
+		{testCustomType, "OTHER", "already registered"},
+		{65401, "testrr", "already registered"},
+		{TypeA, "OTHER", "supported by this package"},
+		{65401, "mx", "already used"},
+		{65401, "TYPE1", "invalid name"},
+		{65401, "", "invalid name"},
+		{65401, "MY RR", "invalid name"},
+		{65401, "MY;RR", "invalid name"},
+		{65401, "1RR", "invalid name"},
+		{65401, "type99999", "invalid name"},
+		{65401, "CLASS1", "invalid name"},
+		{65401, "in", "already used by class"},
+		{65401, "ANY", "already used by class"},
+	}
+	for _, tt := range cases {
+		func() {
+			defer func() {
+				r := recover()
+				if s, _ := r.(string); !strings.Contains(s, tt.err) {
+					t.Errorf("RegisterResourceType(%v, %q) unexpected panic: %v", tt.typ, tt.name, r)
+				}
+			}()
+			RegisterResourceType(tt.typ, tt.name, newResource)
+		}()
+	}
+}
@@ -0,0 +1,344 @@
+package dnsmsg
+
+import "math"
+
+// Resource is a single resource, with a typed resource data.
+type Resource struct {
+	Header ResourceHeader
+	Body   ResourceBody
+}
+
+// String returns the resource in presentation format, e.g. "example.com. 300 IN A 192.0.2.1".
+func (r Resource) String() string {
+	return r.Header.String() + " " + r.Body.String()
+}
+
+// ResourceBody is the resource data of a resource.
+//
+// It is implemented by all Resource* types of this package (like [ResourceA] or [ResourceMX])
+// and by the [RawResource], which is used for resources of types not supported by this package.
+// Applications can implement resource types not supported by this package by the [CustomResource] interface.
+type ResourceBody interface {
+	// ResourceType returns the type of the resource.
+	ResourceType() Type
+
+	// String returns the resource data in presentation format.
+	String() string
+}
+
+// RawResource is a resource data of any type, in the wire format, without interpretation.
+// It is used for resources of types not supported by this package, and for copying
+// resources of any type between messages (see [Parser.RawResource] and [Builder.RawResource]).
+//
+// The presentation format of a RawResource is the generic format
+// for unknown resource data (RFC 3597, Section 5), e.g. "\# 4 0A000001".
+type RawResource struct {
+	Type Type
+	Data []byte
+}
+
+func (r RawResource) ResourceType() Type { return r.Type }
+
+func (r RawResource) String() string {
+	return string(appendUnknownRData(nil, r.Data))
+}
+
+func (ResourceA) ResourceType() Type          { return TypeA }
+func (ResourceAAAA) ResourceType() Type       { return TypeAAAA }
+func (ResourceNS) ResourceType() Type         { return TypeNS }
+func (ResourceCNAME) ResourceType() Type      { return TypeCNAME }
+func (ResourceSOA) ResourceType() Type        { return TypeSOA }
+func (ResourcePTR) ResourceType() Type        { return TypePTR }
+func (ResourceMX) ResourceType() Type         { return TypeMX }
+func (ResourceDNAME) ResourceType() Type      { return TypeDNAME }
+func (ResourceTXT) ResourceType() Type        { return TypeTXT }
+func (ResourceSRV) ResourceType() Type        { return TypeSRV }
+func (ResourceCAA) ResourceType() Type        { return TypeCAA }
+func (ResourceOPT) ResourceType() Type        { return TypeOPT }
+func (ResourceSVCB) ResourceType() Type       { return TypeSVCB }
+func (ResourceHTTPS) ResourceType() Type      { return TypeHTTPS }
+func (ResourceDNSKEY) ResourceType() Type     { return TypeDNSKEY }
+func (ResourceCDNSKEY) ResourceType() Type    { return TypeCDNSKEY }
+func (ResourceDS) ResourceType() Type         { return TypeDS }
+func (ResourceCDS) ResourceType() Type        { return TypeCDS }
+func (ResourceRRSIG) ResourceType() Type      { return TypeRRSIG }
+func (ResourceSIG) ResourceType() Type        { return TypeSIG }
+func (ResourceNSEC) ResourceType() Type       { return TypeNSEC }
+func (ResourceNSEC3) ResourceType() Type      { return TypeNSEC3 }
+func (ResourceNSEC3PARAM) ResourceType() Type { return TypeNSEC3PARAM }
+func (ResourceTSIG) ResourceType() Type       { return TypeTSIG }
+
+// RawResource parses the resource data of any type as a [RawResource].
+//
+// Names embedded in the resource data of known types (like NS, MX, SOA) are decompressed,
+// so that the returned resource data does not depend on the message it was parsed from.
+// The resource data of other types is copied verbatim.
+//
+// The returned RawResource does not reference the underlying message.
+//
+// This method can only be called after calling the [Parser.ResourceHeader] method.
+func (m *Parser) RawResource() (RawResource, error) {
+	typ := m.nextResourceType
+	rdata, err := m.appendUncompressedResourceData(make([]byte, 0, m.nextResourceDataLength))
+	if err != nil {
+		return RawResource{}, err
+	}
+	return RawResource{Type: typ, Data: rdata}, nil
+}
+
+// RawResource appends a single resource, with the resource data copied verbatim from raw.
+// The type of the resource is set to raw.Type, hdr.Type is ignored.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// Note: names embedded in raw.Data must not be compressed, as the compression pointers
+// would point to the wrong offsets in the built message.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) RawResource(hdr ResourceHeader, raw RawResource) error {
+	if len(raw.Data) > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	hdr.Type = raw.Type
+	hdr.Length = uint16(len(raw.Data))
+	if err := b.appendHeader(hdr, b.maxBufSize-len(raw.Data)); err != nil {
+		return err
+	}
+	b.buf = append(b.buf, raw.Data...)
+	return nil
+}
+
+// CopyResource appends the resource, that p is currently positioned at, to the current section.
+// hdr must be the [ResourceHeader] returned by the last call to [Parser.ResourceHeader].
+//
+// Names embedded in the resource data of the NS, CNAME, SOA, PTR and MX resources (the only
+// types whose names may be compressed by the Builder, RFC 3597, Section 4) are decompressed and
+// compressed again against the built message. Names embedded in the resource data of the DNAME,
+// SRV, SIG, RRSIG, NSEC, TSIG, SVCB and HTTPS resources are decompressed, as the [Parser] accepts
+// compressed names in them, see [Parser.RawResource]. The resource data of other types is copied
+// byte-for-byte.
+//
+// On success, the resource data is consumed from p. In case of an error, the resource data
+// might have already been consumed, so it cannot be parsed again.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) CopyResource(hdr ResourceHeader, p *Parser) error {
+	if !p.resourceData || p.nextResourceType != hdr.Type {
+		return errInvalidOperation
+	}
+
+	switch hdr.Type {
+	case TypeNS:
+		ns, err := p.ResourceNS()
+		if err != nil {
+			return err
+		}
+		return b.ResourceNS(hdr, ns)
+	case TypeCNAME:
+		cname, err := p.ResourceCNAME()
+		if err != nil {
+			return err
+		}
+		return b.ResourceCNAME(hdr, cname)
+	case TypeSOA:
+		soa, err := p.ResourceSOA()
+		if err != nil {
+			return err
+		}
+		return b.ResourceSOA(hdr, soa)
+	case TypePTR:
+		ptr, err := p.ResourcePTR()
+		if err != nil {
+			return err
+		}
+		return b.ResourcePTR(hdr, ptr)
+	case TypeMX:
+		mx, err := p.ResourceMX()
+		if err != nil {
+			return err
+		}
+		return b.ResourceMX(hdr, mx)
+	case TypeDNAME:
+		dname, err := p.ResourceDNAME()
+		if err != nil {
+			return err
+		}
+		return b.ResourceDNAME(hdr, dname)
+	}
+
+	rdata, err := p.appendUncompressedResourceData(make([]byte, 0, p.nextResourceDataLength))
+	if err != nil {
+		return err
+	}
+	return b.RawResource(hdr, RawResource{Type: hdr.Type, Data: rdata})
+}
+
+// ResourceBody parses the resource data of the current resource. Resources of types
+// registered by [RegisterResourceType] are parsed by the [CustomResource.Unpack] method,
+// resources of other types not supported by this package are returned as a [RawResource].
+//
+// The returned [ResourceBody] might reference the underlying message.
+//
+// This method can only be called after calling the [Parser.ResourceHeader] method.
+func (m *Parser) ResourceBody() (ResourceBody, error) {
+	if !m.resourceData {
+		return nil, errInvalidOperation
+	}
+
+	var (
+		body ResourceBody
+		err  error
+	)
+
+	switch m.nextResourceType {
+	case TypeA:
+		body, err = m.ResourceA()
+	case TypeAAAA:
+		body, err = m.ResourceAAAA()
+	case TypeNS:
+		body, err = m.ResourceNS()
+	case TypeCNAME:
+		body, err = m.ResourceCNAME()
+	case TypeSOA:
+		body, err = m.ResourceSOA()
+	case TypePTR:
+		body, err = m.ResourcePTR()
+	case TypeMX:
+		body, err = m.ResourceMX()
+	case TypeDNAME:
+		body, err = m.ResourceDNAME()
+	case TypeTXT:
+		var txt RawResourceTXT
+		txt, err = m.RawResourceTXT()
+		body = txt.ToResourceTXT()
+	case TypeSRV:
+		body, err = m.ResourceSRV()
+	case TypeCAA:
+		body, err = m.ResourceCAA()
+	case TypeOPT:
+		body, err = m.ResourceOPT()
+	case TypeSVCB:
+		body, err = m.ResourceSVCB()
+	case TypeHTTPS:
+		body, err = m.ResourceHTTPS()
+	case TypeDNSKEY:
+		body, err = m.ResourceDNSKEY()
+	case TypeCDNSKEY:
+		body, err = m.ResourceCDNSKEY()
+	case TypeDS:
+		body, err = m.ResourceDS()
+	case TypeCDS:
+		body, err = m.ResourceCDS()
+	case TypeRRSIG:
+		body, err = m.ResourceRRSIG()
+	case TypeSIG:
+		body, err = m.ResourceSIG()
+	case TypeNSEC:
+		body, err = m.ResourceNSEC()
+	case TypeNSEC3:
+		body, err = m.ResourceNSEC3()
+	case TypeNSEC3PARAM:
+		body, err = m.ResourceNSEC3PARAM()
+	case TypeTSIG:
+		body, err = m.ResourceTSIG()
+	default:
+		typ := m.nextResourceType
+		var rdp RDParser
+		rdp, err = m.RDParser()
+		if err != nil {
+			break
+		}
+		r, ok := lookupResourceType(typ)
+		if !ok {
+			body = RawResource{Type: typ, Data: rdp.AllBytes()}
+			break
+		}
+		custom := r.newResource()
+		if err = custom.Unpack(&rdp); err == nil {
+			err = rdp.End()
+		}
+		body = custom
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Resource appends a single resource, with the resource data of any type supported by
+// this package, a [RawResource] or a [CustomResource].
+// The Type field of hdr must be equal to the type returned by the ResourceType method of body.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) Resource(hdr ResourceHeader, body ResourceBody) error {
+	if body == nil || hdr.Type != body.ResourceType() {
+		return errInvalidOperation
+	}
+
+	switch body := body.(type) {
+	case ResourceA:
+		return b.ResourceA(hdr, body)
+	case ResourceAAAA:
+		return b.ResourceAAAA(hdr, body)
+	case ResourceNS:
+		return b.ResourceNS(hdr, body)
+	case ResourceCNAME:
+		return b.ResourceCNAME(hdr, body)
+	case ResourceSOA:
+		return b.ResourceSOA(hdr, body)
+	case ResourcePTR:
+		return b.ResourcePTR(hdr, body)
+	case ResourceMX:
+		return b.ResourceMX(hdr, body)
+	case ResourceDNAME:
+		return b.ResourceDNAME(hdr, body)
+	case ResourceTXT:
+		return b.ResourceTXT(hdr, body)
+	case ResourceSRV:
+		return b.ResourceSRV(hdr, body)
+	case ResourceCAA:
+		return b.ResourceCAA(hdr, body)
+	case ResourceOPT:
+		return b.ResourceOPT(hdr, body)
+	case ResourceSVCB:
+		return b.ResourceSVCB(hdr, body)
+	case ResourceHTTPS:
+		return b.ResourceHTTPS(hdr, body)
+	case ResourceDNSKEY:
+		return b.ResourceDNSKEY(hdr, body)
+	case ResourceCDNSKEY:
+		return b.ResourceCDNSKEY(hdr, body)
+	case ResourceDS:
+		return b.ResourceDS(hdr, body)
+	case ResourceCDS:
+		return b.ResourceCDS(hdr, body)
+	case ResourceRRSIG:
+		return b.ResourceRRSIG(hdr, body)
+	case ResourceSIG:
+		return b.ResourceSIG(hdr, body)
+	case ResourceNSEC:
+		return b.ResourceNSEC(hdr, body)
+	case ResourceNSEC3:
+		return b.ResourceNSEC3(hdr, body)
+	case ResourceNSEC3PARAM:
+		return b.ResourceNSEC3PARAM(hdr, body)
+	case ResourceTSIG:
+		return b.resourceTSIG(hdr, &body)
+	case RawResource:
+		return b.RawResource(hdr, body)
+	case CustomResource:
+		rdb, err := b.RDBuilder(hdr)
+		if err != nil {
+			return err
+		}
+		if err := body.Pack(&rdb); err != nil {
+			rdb.Remove()
+			return err
+		}
+		rdb.End()
+		return nil
+	default:
+		return errInvalidOperation
+	}
+}
@@ -0,0 +1,287 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRawResource(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN, TTL: 60}
+	if err := b.ResourceMX(hdr, ResourceMX{Pref: 10, MX: MustParseName("mail.example.com")}); err != nil {
+		t.Fatalf("b.ResourceMX() unexpected error: %v", err)
+	}
+	if err := b.RawResource(hdr, RawResource{Type: 65280, Data: []byte{1, 2, 3}}); err != nil {
+		t.Fatalf("b.RawResource() unexpected error: %v", err)
+	}
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+
+	var raw []RawResource
+	for {
+		_, err := p.ResourceHeader()
+		if err == ErrSectionDone {
+			break
+		}
+		if err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		r, err := p.RawResource()
+		if err != nil {
+			t.Fatalf("p.RawResource() unexpected error: %v", err)
+		}
+		raw = append(raw, r)
+	}
+
+	// MX name is compressed in the message, RawResource decompresses it.
+	mxName := MustParseName("mail.example.com")
+	expectMX := append([]byte{0, 10}, mxName.asSlice()...)
+	if len(raw) != 2 || raw[0].Type != TypeMX || !bytes.Equal(raw[0].Data, expectMX) {
+		t.Fatalf("unexpected raw resources: %v", raw)
+	}
+	if raw[1].Type != 65280 || !bytes.Equal(raw[1].Data, []byte{1, 2, 3}) {
+		t.Fatalf("unexpected raw resource: %v", raw[1])
+	}
+
+	// Copy the resources into a new message, with a different owner name.
+	b2 := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b2.StartAnswers()
+	hdr2 := ResourceHeader{Name: MustParseName("other.example.net"), Type: TypeA, Class: ClassIN, TTL: 60}
+	for _, r := range raw {
+		if err := b2.RawResource(hdr2, r); err != nil {
+			t.Fatalf("b.RawResource() unexpected error: %v", err)
+		}
+	}
+
+	p, _, err = Parse(b2.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	p.StartAnswers()
+	h, err := p.ResourceHeader()
+	if err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+	if h.Type != TypeMX {
+		t.Fatalf("h.Type = %v, want: %v", h.Type, TypeMX)
+	}
+	mx, err := p.ResourceMX()
+	if err != nil {
+		t.Fatalf("p.ResourceMX() unexpected error: %v", err)
+	}
+	if mx.Pref != 10 || !mx.MX.Equal(&mxName) {
+		t.Fatalf("unexpected MX resource: %v", mx)
+	}
+	if _, err := p.RawResource(); err != errInvalidOperation {
+		t.Fatalf("p.RawResource() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+}
+
+func TestBuilderRawResourceTooLong(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+	err := b.RawResource(ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}, RawResource{Type: 65280, Data: make([]byte, 65536)})
+	if err != errResourceTooLong {
+		t.Fatalf("b.RawResource() unexpected error: %v, want: %v", err, errResourceTooLong)
+	}
+
+	b.LimitMessageSize(100)
+	err = b.RawResource(ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}, RawResource{Type: 65280, Data: make([]byte, 100)})
+	if err != ErrTruncated {
+		t.Fatalf("b.RawResource() unexpected error: %v, want: %v", err, ErrTruncated)
+	}
+	if hdr := b.Header(); hdr.ANCount != 0 {
+		t.Fatalf("b.Header().ANCount = %v, want: 0", hdr.ANCount)
+	}
+}
+
+func TestBuilderCopyResource(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.Question(Question{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN})
+	b.StartAnswers()
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN, TTL: 60}
+	b.ResourceNS(hdr, ResourceNS{NS: MustParseName("ns1.example.com")})
+	b.ResourceCNAME(hdr, ResourceCNAME{CNAME: MustParseName("www.example.com")})
+	b.ResourceSOA(hdr, ResourceSOA{NS: MustParseName("ns1.example.com"), Mbox: MustParseName("admin.example.com"), Serial: 1})
+	b.ResourcePTR(hdr, ResourcePTR{PTR: MustParseName("host.example.com")})
+	b.ResourceMX(hdr, ResourceMX{Pref: 10, MX: MustParseName("mail.example.com")})
+	b.ResourceA(hdr, ResourceA{A: [4]byte{192, 0, 2, 1}})
+	b.ResourceSRV(hdr, ResourceSRV{Priority: 1, Weight: 2, Port: 3, Target: MustParseName("srv.example.com")})
+	b.RawResource(hdr, RawResource{Type: 65280, Data: []byte{1, 2, 3}})
+	msg := b.Bytes()
+
+	p, _, err := Parse(msg)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	p.SkipQuestions()
+	p.StartAnswers()
+
+	// A different question moves all names to different offsets.
+	b2 := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b2.Question(Question{Name: MustParseName("other.example.net"), Type: TypeA, Class: ClassIN})
+	b2.StartAnswers()
+	for {
+		hdr, err := p.ResourceHeader()
+		if err == ErrSectionDone {
+			break
+		}
+		if err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		if err := b2.CopyResource(hdr, &p); err != nil {
+			t.Fatalf("b.CopyResource() unexpected error: %v", err)
+		}
+	}
+
+	expect := resourceStrings(t, msg)
+	got := resourceStrings(t, b2.Bytes())
+	if len(got) != len(expect) {
+		t.Fatalf("got %v resources, want: %v", len(got), len(expect))
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			t.Errorf("%v: got: %q, want: %q", i, got[i], expect[i])
+		}
+	}
+
+	p, _, _ = Parse(msg)
+	p.SkipQuestions()
+	p.StartAnswers()
+	hdr, _ = p.ResourceHeader()
+	hdr.Type = TypeA
+	if err := b2.CopyResource(hdr, &p); err != errInvalidOperation {
+		t.Fatalf("b.CopyResource() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+}
+
+func resourceStrings(t *testing.T, msg []byte) []string {
+	t.Helper()
+	p, _, err := Parse(msg)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	p.SkipQuestions()
+	p.StartAnswers()
+
+	var out []string
+	for {
+		hdr, err := p.ResourceHeader()
+		if err == ErrSectionDone {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+		}
+		body, err := p.ResourceBody()
+		if err != nil {
+			t.Fatalf("p.ResourceBody() unexpected error: %v", err)
+		}
+		out = append(out, Resource{Header: hdr, Body: body}.String())
+	}
+}
+
+// testCompressedRDataMsg creates a message with the example.com question and a single answer of
+// type typ, the owner name of the answer and a name embedded in rdata (at the nameOffset) are compressed
+// with a pointer to the question name. It returns the message and the expected uncompressed rdata.
+func testCompressedRDataMsg(typ Type, rdata []byte, nameOffset int) (msg, uncompressed []byte) {
+	name := MustParseName("example.com")
+	rdata = append(append(append([]byte{}, rdata[:nameOffset]...), 0xC0, 12), rdata[nameOffset:]...)
+	uncompressed = append(append(append([]byte{}, rdata[:nameOffset]...), name.asSlice()...), rdata[nameOffset+2:]...)
+
+	msg = appendUint16(nil, 0)
+	msg = appendUint16(msg, 0)
+	msg = appendUint16(msg, 1)
+	msg = appendUint16(msg, 1)
+	msg = appendUint32(msg, 0)
+	msg = append(msg, name.asSlice()...)
+	msg = appendUint16(msg, uint16(TypeA))
+	msg = appendUint16(msg, uint16(ClassIN))
+	msg = append(msg, 0xC0, 12)
+	msg = appendUint16(msg, uint16(typ))
+	msg = appendUint16(msg, uint16(ClassIN))
+	msg = appendUint32(msg, 60)
+	msg = appendUint16(msg, uint16(len(rdata)))
+	return append(msg, rdata...), uncompressed
+}
+
+func TestRawResourceCompressedNames(t *testing.T) {
+	cases := []struct {
+		typ        Type
+		rdata      []byte
+		nameOffset int
+	}{
+		{TypeSIG, []byte{0, 1, 15, 2, 0, 0, 0, 60, 0, 0, 0, 2, 0, 0, 0, 1, 0, 5, 1, 2, 3}, 18},
+		{TypeTSIG, []byte{0, 0, 0, 0, 0, 1, 1, 44, 0, 2, 9, 9, 0, 1, 0, 0, 0, 0}, 0},
+		{TypeSVCB, []byte{0, 1, 0, 3, 0, 2, 1, 187}, 2},
+		{TypeHTTPS, []byte{0, 1, 0, 3, 0, 2, 1, 187}, 2},
+		{TypeDNAME, nil, 0},
+	}
+
+	for _, tt := range cases {
+		msg, uncompressed := testCompressedRDataMsg(tt.typ, tt.rdata, tt.nameOffset)
+		p, _, err := Parse(msg)
+		if err != nil {
+			t.Fatalf("%v: Parse() unexpected error: %v", tt.typ, err)
+		}
+		p.SkipQuestions()
+		p.StartAnswers()
+		if _, err := p.ResourceHeader(); err != nil {
+			t.Fatalf("%v: p.ResourceHeader() unexpected error: %v", tt.typ, err)
+		}
+		raw, err := p.RawResource()
+		if err != nil {
+			t.Fatalf("%v: p.RawResource() unexpected error: %v", tt.typ, err)
+		}
+		if raw.Type != tt.typ || !bytes.Equal(raw.Data, uncompressed) {
+			t.Errorf("%v: unexpected raw resource data: %v, want: %v", tt.typ, raw.Data, uncompressed)
+		}
+	}
+}
+
+func TestBuilderCopyResourceCompressedNames(t *testing.T) {
+	cases := []struct {
+		typ        Type
+		rdata      []byte
+		nameOffset int
+	}{
+		{TypeSRV, []byte{0, 1, 0, 2, 0, 3}, 6},
+		{TypeRRSIG, []byte{0, 1, 15, 2, 0, 0, 0, 60, 0, 0, 0, 2, 0, 0, 0, 1, 0, 5, 1, 2, 3}, 18},
+		{TypeDNAME, nil, 0},
+	}
+
+	for _, tt := range cases {
+		msg, _ := testCompressedRDataMsg(tt.typ, tt.rdata, tt.nameOffset)
+		p, _, err := Parse(msg)
+		if err != nil {
+			t.Fatalf("%v: Parse() unexpected error: %v", tt.typ, err)
+		}
+		p.SkipQuestions()
+		p.StartAnswers()
+		hdr, err := p.ResourceHeader()
+		if err != nil {
+			t.Fatalf("%v: p.ResourceHeader() unexpected error: %v", tt.typ, err)
+		}
+
+		// A different question moves the name to a different offset.
+		b := StartBuilder(make([]byte, 0, 512), 0, 0)
+		b.Question(Question{Name: MustParseName("other.example.net"), Type: TypeA, Class: ClassIN})
+		b.StartAnswers()
+		if err := b.CopyResource(hdr, &p); err != nil {
+			t.Fatalf("%v: b.CopyResource() unexpected error: %v", tt.typ, err)
+		}
+
+		expect := resourceStrings(t, msg)
+		got := resourceStrings(t, b.Bytes())
+		if len(got) != 1 || got[0] != expect[0] {
+			t.Errorf("%v: got: %q, want: %q", tt.typ, got, expect)
+		}
+	}
+}
@@ -0,0 +1,488 @@
+package dnsmsg
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultServerWorkers        = 128
+	defaultServerUDPPayloadSize = 1232
+	defaultServerTCPIdleTimeout = 10 * time.Second
+
+	// maxUDPNoEDNS0Size is the maximum UDP message size, when the
+	// EDNS(0) is not used (RFC 1035, Section 4.2.1).
+	maxUDPNoEDNS0Size = 512
+)
+
+// Request is a DNS request received by the [Server].
+type Request struct {
+	Header   Header
+	Question Question
+
+	// EDNS0 is the EDNS(0) header of the request, it is only valid when HasEDNS0 is true.
+	EDNS0    EDNS0Header
+	HasEDNS0 bool
+
+	// Network is the network that the request was received on: "udp" or "tcp".
+	Network    string
+	RemoteAddr net.Addr
+
+	// Msg is the entire request message.
+	Msg []byte
+}
+
+// Handler responds to a DNS request.
+//
+// ServeDNS should build the response using the [ResponseWriter.Builder], the
+// response is sent after ServeDNS returns. The Request and the ResponseWriter
+// must not be used after ServeDNS returns.
+type Handler interface {
+	ServeDNS(w *ResponseWriter, r *Request)
+}
+
+// HandlerFunc is an adapter that allows the use of ordinary functions as a [Handler].
+type HandlerFunc func(w *ResponseWriter, r *Request)
+
+// ServeDNS calls f(w, r).
+func (f HandlerFunc) ServeDNS(w *ResponseWriter, r *Request) {
+	f(w, r)
+}
+
+// ResponseWriter is used by the [Handler] to build a response.
+type ResponseWriter struct {
+	b Builder
+
+	edns0  bool
+	optHdr EDNS0Header
+	drop   bool
+}
+
+// startResponse prepares w for building a response to the request with header hdr.
+func (w *ResponseWriter) startResponse(hdr Header, q *Question, edns0 bool, optHdr EDNS0Header, sizeLimit int) {
+	var flags Flags
+	flags.SetResponse()
+	flags.SetOpCode(hdr.Flags.OpCode())
+	flags.SetBit(BitRD, hdr.Flags.Bit(BitRD))
+
+	w.b = StartBuilder(make([]byte, 0, 512), hdr.ID, flags)
+	w.edns0 = edns0
+	w.optHdr = optHdr
+	w.drop = false
+
+	w.b.LimitMessageSize(sizeLimit)
+	if edns0 {
+		w.b.ReserveOPT(EDNS0HeaderEncodingLength)
+	}
+
+	if q != nil {
+		// The question always fits, the request had the same question.
+		w.b.Question(*q)
+	}
+}
+
+// Builder returns the Builder of the response. The ID, the question, the RD bit and the
+// OpCode are already copied from the request, the QR bit is set. The size of the message
+// is limited (see [Builder.LimitMessageSize]) to the maximum size that the client accepts:
+// the payload size from the EDNS(0) header (limited by [Server.UDPPayloadSize]) or 512
+// bytes without EDNS(0) over UDP, and 65535 bytes over TCP.
+//
+// When the request contains an EDNS(0) header, the response OPT resource is appended after
+// the handler returns, the space required for it is already reserved (see [Builder.ReserveOPT]),
+// so the handler must not append the OPT resource. The building section can be left at any
+// section, but all resource data builders must be ended before the handler returns.
+//
+// The handler can enable the truncation mode of the Builder ([Builder.EnableTruncation]),
+// so that a response that does not fit is sent with the TC bit set and with complete RRsets.
+func (w *ResponseWriter) Builder() *Builder {
+	return &w.b
+}
+
+// SetRCode sets the RCode of the response. The upper bits of
+// the extended rcode are only sent when the request contains an EDNS(0) header.
+func (w *ResponseWriter) SetRCode(rcode ExtendedRCode) {
+	flags := w.b.Header().Flags
+	flags.SetRCode(rcode.RCode())
+	w.b.SetFlags(flags)
+	w.optHdr.PartialExtendedRCode = rcode.PartialExtendedRCode()
+}
+
+// Drop causes no response to be sent.
+func (w *ResponseWriter) Drop() {
+	w.drop = true
+}
+
+// finish finishes the response and returns it, it returns nil when the response was dropped.
+func (w *ResponseWriter) finish() []byte {
+	if w.drop {
+		return nil
+	}
+	if !w.edns0 {
+		return w.b.Bytes()
+	}
+
+	switch w.b.curSection {
+	case sectionQuestions:
+		w.b.StartAnswers()
+		fallthrough
+	case sectionAnswers:
+		w.b.StartAuthorities()
+		fallthrough
+	case sectionAuthorities:
+		w.b.StartAdditionals()
+	case sectionAdditionals:
+	default:
+		w.b.panicInvalidSection()
+	}
+
+	if err := w.b.ResourceOPT(w.optHdr.AsResourceHeader(), ResourceOPT{}); err != nil {
+		return nil
+	}
+	return w.b.Bytes()
+}
+
+// Server is a DNS server, it serves requests over UDP and TCP using the Handler.
+//
+// Requests are handled by a fixed number of worker goroutines (see [Server.Workers]),
+// each call to [Server.ServeUDP] and [Server.ServeTCP] uses its own workers.
+type Server struct {
+	// Handler handles the requests.
+	Handler Handler
+
+	// Workers is the number of goroutines handling requests, when zero 128 is used.
+	Workers int
+
+	// UDPPayloadSize is the maximum UDP payload size advertised in the EDNS(0) header of
+	// responses, it also limits the size of UDP responses. When zero, 1232 is used.
+	UDPPayloadSize uint16
+
+	// TCPIdleTimeout is the time after which the TCP connection is closed, when no request
+	// was received (RFC 7766, Section 6.2.3). It also limits the time of writing a single
+	// response, the connection is closed when the write does not complete in time.
+	// When zero, 10 seconds is used.
+	TCPIdleTimeout time.Duration
+}
+
+func (s *Server) workers() int {
+	if s.Workers <= 0 {
+		return defaultServerWorkers
+	}
+	return s.Workers
+}
+
+func (s *Server) udpPayloadSize() int {
+	if s.UDPPayloadSize < maxUDPNoEDNS0Size {
+		return defaultServerUDPPayloadSize
+	}
+	return int(s.UDPPayloadSize)
+}
+
+func (s *Server) tcpIdleTimeout() time.Duration {
+	if s.TCPIdleTimeout == 0 {
+		return defaultServerTCPIdleTimeout
+	}
+	return s.TCPIdleTimeout
+}
+
+type serverJob struct {
+	msg        []byte
+	network    string
+	remoteAddr net.Addr
+	// respond is called with the response, or with nil when no response should be sent.
+	respond func(resp []byte)
+}
+
+// startWorkers starts the worker goroutines, they exit after the returned channel is closed.
+func (s *Server) startWorkers(wg *sync.WaitGroup) chan<- serverJob {
+	jobs := make(chan serverJob, s.workers())
+	for i := 0; i < s.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var w ResponseWriter
+			for job := range jobs {
+				job.respond(s.handle(&w, &job))
+			}
+		}()
+	}
+	return jobs
+}
+
+// handle parses the request and calls the handler, it returns the response to send, or nil.
+func (s *Server) handle(w *ResponseWriter, job *serverJob) []byte {
+	p, hdr, err := Parse(job.msg)
+	if err != nil || hdr.Flags.Response() {
+		return nil
+	}
+
+	sizeLimit := math.MaxUint16
+	if job.network == "udp" {
+		sizeLimit = maxUDPNoEDNS0Size
+	}
+
+	req := Request{
+		Header:     hdr,
+		Network:    job.network,
+		RemoteAddr: job.remoteAddr,
+		Msg:        job.msg,
+	}
+
+	formErr := func() []byte {
+		w.startResponse(hdr, nil, req.HasEDNS0, EDNS0Header{Payload: uint16(s.udpPayloadSize())}, sizeLimit)
+		w.SetRCode(ExtendedRCode(RCodeFormatError))
+		return w.finish()
+	}
+
+	if hdr.QDCount != 1 {
+		return formErr()
+	}
+	req.Question, err = p.Question()
+	if err != nil {
+		return formErr()
+	}
+
+	req.EDNS0, req.HasEDNS0, err = parseRequestEDNS0(&p)
+	if err != nil {
+		req.HasEDNS0 = false
+		return formErr()
+	}
+
+	optHdr := EDNS0Header{Payload: uint16(s.udpPayloadSize())}
+	if req.HasEDNS0 {
+		optHdr.ExtendedFlags.SetDNSSECOK(req.EDNS0.ExtendedFlags.DNSSECOK())
+		if job.network == "udp" {
+			sizeLimit = int(req.EDNS0.Payload)
+			if sizeLimit < maxUDPNoEDNS0Size {
+				sizeLimit = maxUDPNoEDNS0Size
+			}
+			if sizeLimit > s.udpPayloadSize() {
+				sizeLimit = s.udpPayloadSize()
+			}
+		}
+	}
+
+	w.startResponse(hdr, &req.Question, req.HasEDNS0, optHdr, sizeLimit)
+
+	if req.HasEDNS0 && req.EDNS0.Version != 0 {
+		// RFC 6891, Section 6.1.3.
+		w.SetRCode(NewExtendedRCode(1, 0)) // BADVERS
+		return w.finish()
+	}
+
+	s.Handler.ServeDNS(w, &req)
+	return w.finish()
+}
+
+var errMultipleOPT = errors.New("multiple OPT resources")
+
+// parseRequestEDNS0 parses the EDNS(0) header from the additional section,
+// the parser must be positioned after the question section.
+func parseRequestEDNS0(p *Parser) (hdr EDNS0Header, found bool, err error) {
+	if err := p.StartAnswers(); err != nil {
+		return EDNS0Header{}, false, err
+	}
+	if err := p.SkipResources(); err != nil {
+		return EDNS0Header{}, false, err
+	}
+	if err := p.StartAuthorities(); err != nil {
+		return EDNS0Header{}, false, err
+	}
+	if err := p.SkipResources(); err != nil {
+		return EDNS0Header{}, false, err
+	}
+	if err := p.StartAdditionals(); err != nil {
+		return EDNS0Header{}, false, err
+	}
+
+	for {
+		rhdr, err := p.ResourceHeader()
+		if err != nil {
+			if err == ErrSectionDone {
+				return hdr, found, nil
+			}
+			return EDNS0Header{}, false, err
+		}
+		if rhdr.Type == TypeOPT {
+			if found {
+				return EDNS0Header{}, false, errMultipleOPT
+			}
+			hdr, err = rhdr.AsEDNS0Header()
+			if err != nil {
+				return EDNS0Header{}, false, err
+			}
+			found = true
+		}
+		if err := p.SkipResourceData(); err != nil {
+			return EDNS0Header{}, false, err
+		}
+	}
+}
+
+// watchContextDone calls f when ctx is done, the returned function
+// must be called to stop watching the context.
+func watchContextDone(ctx context.Context, f func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			f()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ServeUDP serves DNS requests received on conn, until ctx is done.
+//
+// After ctx is done, ServeUDP stops reading new requests, waits for the handlers
+// of all already received requests and returns nil. The conn is not closed.
+func (s *Server) ServeUDP(ctx context.Context, conn net.PacketConn) error {
+	var wg sync.WaitGroup
+	jobs := s.startWorkers(&wg)
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	defer watchContextDone(ctx, func() { conn.SetReadDeadline(time.Unix(1, 0)) })()
+
+	buf := make([]byte, math.MaxUint16)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		jobs <- serverJob{
+			msg:        append([]byte(nil), buf[:n]...),
+			network:    "udp",
+			remoteAddr: addr,
+			respond: func(resp []byte) {
+				if resp != nil {
+					conn.WriteTo(resp, addr)
+				}
+			},
+		}
+	}
+}
+
+// ServeTCP serves DNS requests received on connections accepted by l, until ctx is done.
+// Multiple requests can be received on a single connection, they are handled concurrently,
+// and the responses are sent as soon as they are ready (RFC 7766, Section 6.2.1.1).
+//
+// After ctx is done, ServeTCP closes l, stops reading new requests, aborts responses that are
+// currently being written, waits for the handlers of all already received requests (and for
+// their responses to be written), closes all connections and returns nil.
+func (s *Server) ServeTCP(ctx context.Context, l net.Listener) error {
+	var wg sync.WaitGroup
+	jobs := s.startWorkers(&wg)
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	var (
+		connsWG sync.WaitGroup
+		mu      sync.Mutex
+		conns   = make(map[net.Conn]struct{})
+	)
+	defer connsWG.Wait()
+
+	// serveTCPConn checks ctx after setting a new read deadline, so the
+	// expired deadline is never overwritten after ctx is done.
+	// Writes that start after ctx is done set a new write deadline, so that
+	// responses of the in-flight requests are still sent.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	expireConns := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for conn := range conns {
+			conn.SetDeadline(time.Unix(1, 0))
+		}
+	}
+
+	defer watchContextDone(ctx, func() {
+		l.Close()
+		expireConns()
+	})()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// Stop reading from all connections, otherwise the deferred
+			// connsWG.Wait would wait for their idle timeouts.
+			cancel()
+			expireConns()
+			return err
+		}
+
+		mu.Lock()
+		if ctx.Err() != nil {
+			mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		conns[conn] = struct{}{}
+		mu.Unlock()
+
+		connsWG.Add(1)
+		go func() {
+			defer connsWG.Done()
+			s.serveTCPConn(ctx, conn, jobs)
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) serveTCPConn(ctx context.Context, conn net.Conn, jobs chan<- serverJob) {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		conn.Close()
+	}()
+
+	r := NewStreamReader(conn)
+	w := NewStreamWriter(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.tcpIdleTimeout()))
+		if ctx.Err() != nil {
+			return
+		}
+		msg, err := r.ReadMsg(nil)
+		if err != nil {
+			return
+		}
+
+		wg.Add(1)
+		jobs <- serverJob{
+			msg:        msg,
+			network:    "tcp",
+			remoteAddr: conn.RemoteAddr(),
+			respond: func(resp []byte) {
+				defer wg.Done()
+				if resp == nil {
+					return
+				}
+				// The deadline prevents clients that do not read the
+				// responses from blocking the workers.
+				conn.SetWriteDeadline(time.Now().Add(s.tcpIdleTimeout()))
+				if err := w.WriteMsg(resp); err != nil {
+					conn.Close()
+				}
+			},
+		}
+	}
+}
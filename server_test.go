@@ -0,0 +1,380 @@
+package dnsmsg
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testServerQuery(t *testing.T, id uint16, edns0 *EDNS0Header) []byte {
+	t.Helper()
+	var flags Flags
+	flags.SetBit(BitRD, true)
+	flags.SetOpCode(2)
+	b := StartBuilder(nil, id, flags)
+	if err := b.Question(Question{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN}); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+	if edns0 != nil {
+		b.StartAnswers()
+		b.StartAuthorities()
+		b.StartAdditionals()
+		if err := b.ResourceOPT(edns0.AsResourceHeader(), ResourceOPT{}); err != nil {
+			t.Fatalf("b.ResourceOPT() unexpected error: %v", err)
+		}
+	}
+	return b.Bytes()
+}
+
+type testServerResponse struct {
+	hdr      Header
+	question Question
+	edns0    EDNS0Header
+	hasEDNS0 bool
+}
+
+func parseTestServerResponse(t *testing.T, resp []byte) testServerResponse {
+	t.Helper()
+	p, hdr, err := Parse(resp)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	r := testServerResponse{hdr: hdr}
+	if hdr.QDCount == 1 {
+		if r.question, err = p.Question(); err != nil {
+			t.Fatalf("p.Question() unexpected error: %v", err)
+		}
+	}
+	if r.edns0, r.hasEDNS0, err = parseRequestEDNS0(&p); err != nil {
+		t.Fatalf("parseRequestEDNS0() unexpected error: %v", err)
+	}
+	return r
+}
+
+// testServerFillHandler appends A resources to the answer section until ErrTruncated.
+var testServerFillHandler = HandlerFunc(func(w *ResponseWriter, r *Request) {
+	b := w.Builder()
+	b.StartAnswers()
+	for i := 0; ; i++ {
+		err := b.ResourceA(ResourceHeader{Name: r.Question.Name, Class: ClassIN, TTL: 60}, ResourceA{A: [4]byte{192, 0, 2, byte(i)}})
+		if err == ErrTruncated {
+			return
+		}
+	}
+})
+
+func TestServerHandle(t *testing.T) {
+	s := Server{Handler: testServerFillHandler}
+	var w ResponseWriter
+
+	cases := []struct {
+		name      string
+		network   string
+		edns0     *EDNS0Header
+		maxLength int
+	}{
+		{"udp", "udp", nil, 512},
+		{"udp edns0", "udp", &EDNS0Header{Payload: 1400}, 1232},
+		{"udp edns0 small payload", "udp", &EDNS0Header{Payload: 800}, 800},
+		{"udp edns0 payload below 512", "udp", &EDNS0Header{Payload: 100}, 512},
+		{"tcp", "tcp", nil, 65535},
+	}
+
+	for _, tt := range cases {
+		resp := s.handle(&w, &serverJob{msg: testServerQuery(t, 1234, tt.edns0), network: tt.network})
+		if resp == nil {
+			t.Fatalf("%v: unexpected nil response", tt.name)
+		}
+		if len(resp) > tt.maxLength || len(resp) < tt.maxLength-16 {
+			t.Errorf("%v: unexpected response length: %v, want: %v (or slightly less)", tt.name, len(resp), tt.maxLength)
+		}
+
+		r := parseTestServerResponse(t, resp)
+		if r.hdr.ID != 1234 || !r.hdr.Flags.Response() || !r.hdr.Flags.Bit(BitRD) || r.hdr.Flags.OpCode() != 2 {
+			t.Errorf("%v: unexpected response header: %#v", tt.name, r.hdr)
+		}
+		wantName := MustParseName("example.com")
+		if !r.question.Name.Equal(&wantName) || r.question.Type != TypeA || r.question.Class != ClassIN {
+			t.Errorf("%v: unexpected response question: %#v", tt.name, r.question)
+		}
+		if r.hasEDNS0 != (tt.edns0 != nil) {
+			t.Errorf("%v: response has EDNS(0): %v, want: %v", tt.name, r.hasEDNS0, tt.edns0 != nil)
+		}
+		if r.hasEDNS0 && r.edns0.Payload != 1232 {
+			t.Errorf("%v: response EDNS(0) payload: %v, want: 1232", tt.name, r.edns0.Payload)
+		}
+	}
+}
+
+func TestServerHandleInvalidRequests(t *testing.T) {
+	called := false
+	s := Server{Handler: HandlerFunc(func(w *ResponseWriter, r *Request) { called = true })}
+	var w ResponseWriter
+
+	if resp := s.handle(&w, &serverJob{msg: []byte{1, 2, 3}, network: "udp"}); resp != nil {
+		t.Errorf("unexpected response to an invalid message: %v", resp)
+	}
+
+	response := testServerQuery(t, 1, nil)
+	response[2] |= 0x80 // QR
+	if resp := s.handle(&w, &serverJob{msg: response, network: "udp"}); resp != nil {
+		t.Errorf("unexpected response to a response: %v", resp)
+	}
+
+	noQuestion := StartBuilder(nil, 1, 0)
+	r := parseTestServerResponse(t, s.handle(&w, &serverJob{msg: noQuestion.Bytes(), network: "udp"}))
+	if r.hdr.Flags.RCode() != RCodeFormatError || r.hdr.QDCount != 0 {
+		t.Errorf("unexpected response to a query without a question: %#v", r.hdr)
+	}
+
+	var doFlags ExtendedFlags
+	doFlags.SetDNSSECOK(true)
+	r = parseTestServerResponse(t, s.handle(&w, &serverJob{msg: testServerQuery(t, 1, &EDNS0Header{Payload: 1232, Version: 1, ExtendedFlags: doFlags}), network: "udp"}))
+	if rcode := NewExtendedRCode(r.edns0.PartialExtendedRCode, r.hdr.Flags.RCode()); !r.hasEDNS0 || rcode != 16 {
+		t.Errorf("unexpected rcode for an unsupported EDNS(0) version: %v, want: BADVERS (16)", rcode)
+	}
+	if !r.edns0.ExtendedFlags.DNSSECOK() {
+		t.Errorf("response does not have the DO bit set")
+	}
+
+	if called {
+		t.Errorf("handler unexpectedly called")
+	}
+}
+
+func TestServerHandleDrop(t *testing.T) {
+	s := Server{Handler: HandlerFunc(func(w *ResponseWriter, r *Request) {
+		if r.Header.ID == 1 {
+			w.Drop()
+		}
+	})}
+	var w ResponseWriter
+	if resp := s.handle(&w, &serverJob{msg: testServerQuery(t, 1, nil), network: "udp"}); resp != nil {
+		t.Errorf("unexpected response: %v", resp)
+	}
+	if resp := s.handle(&w, &serverJob{msg: testServerQuery(t, 2, nil), network: "udp"}); resp == nil {
+		t.Errorf("unexpected nil response")
+	}
+}
+
+func TestServerServe(t *testing.T) {
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udpConn.Close()
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	s := Server{
+		Workers: 4,
+		Handler: HandlerFunc(func(w *ResponseWriter, r *Request) {
+			if r.Header.ID == 100 {
+				started <- struct{}{}
+				<-release
+			}
+			b := w.Builder()
+			b.StartAnswers()
+			b.ResourceA(ResourceHeader{Name: r.Question.Name, Class: ClassIN, TTL: 60}, ResourceA{A: [4]byte{192, 0, 2, 1}})
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	serveErrs := make(chan error, 2)
+	wg.Add(2)
+	go func() { defer wg.Done(); serveErrs <- s.ServeUDP(ctx, udpConn) }()
+	go func() { defer wg.Done(); serveErrs <- s.ServeTCP(ctx, tcpListener) }()
+
+	c := Client{Timeout: time.Second}
+	resp, err := c.Exchange(context.Background(), udpConn.LocalAddr().String(), testClientQuery(t, 1, "example.com"))
+	if err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+	if a := testClientAnswer(t, resp); a != 1 {
+		t.Fatalf("got answer from response %v, want: 1", a)
+	}
+
+	conn, err := net.Dial("tcp", tcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewStreamConn(conn, 0)
+	defer sc.Close()
+
+	// The request with ID 100 blocks the handler, the other requests
+	// on the same connection must be handled by other workers.
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := sc.Exchange(context.Background(), testClientQuery(t, 100, "example.com"))
+		slowDone <- err
+	}()
+	<-started
+
+	for i := uint16(1); i <= 3; i++ {
+		resp, err := sc.Exchange(context.Background(), testClientQuery(t, i, "example.com"))
+		if err != nil {
+			t.Fatalf("sc.Exchange() unexpected error: %v", err)
+		}
+		if a := testClientAnswer(t, resp); a != 1 {
+			t.Fatalf("got answer from response %v, want: 1", a)
+		}
+	}
+
+	// Graceful shutdown waits for the in-flight request.
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case err := <-serveErrs:
+		if err != nil {
+			t.Fatalf("Serve() unexpected error: %v", err)
+		}
+	default:
+	}
+	select {
+	case err := <-serveErrs:
+		t.Fatalf("both Serve calls returned (error: %v) before the in-flight request was handled", err)
+	default:
+	}
+
+	close(release)
+	if err := <-slowDone; err != nil {
+		t.Fatalf("sc.Exchange() unexpected error: %v", err)
+	}
+	wg.Wait()
+	close(serveErrs)
+	for err := range serveErrs {
+		if err != nil {
+			t.Fatalf("Serve() unexpected error: %v", err)
+		}
+	}
+}
+
+func TestServerHandleTruncation(t *testing.T) {
+	s := Server{Handler: HandlerFunc(func(w *ResponseWriter, r *Request) {
+		w.Builder().EnableTruncation()
+		testServerFillHandler(w, r)
+	})}
+	var w ResponseWriter
+
+	resp := s.handle(&w, &serverJob{msg: testServerQuery(t, 1, &EDNS0Header{Payload: 1232}), network: "udp"})
+	if len(resp) > 1232 {
+		t.Fatalf("response length: %v, want <= 1232", len(resp))
+	}
+	r := parseTestServerResponse(t, resp)
+	if !r.hdr.Flags.Bit(BitTC) || r.hdr.ANCount != 0 {
+		t.Errorf("unexpected response header: %#v", r.hdr)
+	}
+	if !r.hasEDNS0 {
+		t.Errorf("response does not have the OPT resource")
+	}
+}
+
+func TestServerServeTCPAcceptError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Server{
+		TCPIdleTimeout: time.Minute,
+		Handler: HandlerFunc(func(w *ResponseWriter, r *Request) {
+			b := w.Builder()
+			b.StartAnswers()
+			b.ResourceA(ResourceHeader{Name: r.Question.Name, Class: ClassIN, TTL: 60}, ResourceA{A: [4]byte{192, 0, 2, 1}})
+		}),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.ServeTCP(context.Background(), l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewStreamConn(conn, 0)
+	defer sc.Close()
+	if _, err := sc.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != nil {
+		t.Fatalf("sc.Exchange() unexpected error: %v", err)
+	}
+
+	// Accept fails, ServeTCP must not wait for the idle timeout of the open connection.
+	l.Close()
+	select {
+	case err := <-serveErr:
+		if err == nil {
+			t.Fatal("ServeTCP() unexpected success")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeTCP() did not return after an Accept error")
+	}
+}
+
+func TestServerServeTCPNonReadingClient(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32
+	s := Server{
+		Workers:        1,
+		TCPIdleTimeout: 500 * time.Millisecond,
+		Handler: HandlerFunc(func(w *ResponseWriter, r *Request) {
+			calls.Add(1)
+			testServerFillHandler(w, r)
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.ServeTCP(ctx, l) }()
+
+	// The client sends queries with big responses and never reads them,
+	// so that the write of a response blocks the only worker.
+	nonReading, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nonReading.Close()
+	nonReading.(*net.TCPConn).SetReadBuffer(4096)
+	w := NewStreamWriter(nonReading)
+	for i := 0; i < 256; i++ {
+		if err := w.WriteMsg(testClientQuery(t, uint16(i), "example.com")); err != nil {
+			t.Fatalf("w.WriteMsg() unexpected error: %v", err)
+		}
+	}
+	for last := int32(-1); last != calls.Load(); {
+		last = calls.Load()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewStreamConn(conn, 0)
+	defer sc.Close()
+
+	exchangeCtx, exchangeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer exchangeCancel()
+	if _, err := sc.Exchange(exchangeCtx, testClientQuery(t, 1, "example.com")); err != nil {
+		t.Fatalf("sc.Exchange() unexpected error: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("ServeTCP() unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeTCP() did not return after ctx was canceled")
+	}
+}
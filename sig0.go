@@ -0,0 +1,187 @@
+package dnsmsg
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ResourceSIG is a SIG resource defined in RFC 2535 and RFC 2931, it uses
+// the same resource data format as the [ResourceRRSIG].
+//
+// SIG resources are only used for SIG(0) message signatures, see [SIG0Signer].
+type ResourceSIG ResourceRRSIG
+
+// ResourceSIG appends a single SIG resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The SignerName is never compressed, as required by RFC 2931.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceSIG(hdr ResourceHeader, sig ResourceSIG) error {
+	hdr.Type = TypeSIG
+	return b.resourceRRSIG(hdr, ResourceRRSIG(sig))
+}
+
+// ResourceSIG parses a single SIG resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeSIG].
+func (m *Parser) ResourceSIG() (ResourceSIG, error) {
+	if !m.resourceData || m.nextResourceType != TypeSIG {
+		return ResourceSIG{}, errInvalidOperation
+	}
+	sig, err := m.resourceRRSIG()
+	return ResourceSIG(sig), err
+}
+
+var (
+	// ErrSIG0Missing is returned by [Verifier.VerifySIG0] when the message is not signed.
+	ErrSIG0Missing = errors.New("sig0: message is not signed")
+
+	errSIG0InvalidResource = errors.New("sig0: invalid SIG(0) resource")
+)
+
+// SIG0Signer signs messages using SIG(0) (RFC 2931).
+type SIG0Signer struct {
+	// Key is the private key used for signing, the DNSKEY field
+	// should contain the public key, as published in the KEY resource.
+	Key SigningKey
+
+	// SignerName is the owner name of the KEY resource.
+	SignerName Name
+
+	// Validity is the validity period of the signatures, the inception time is set
+	// to the current time minus Validity and the expiration time to the current time plus Validity.
+	// When zero, 5 minutes is used.
+	Validity time.Duration
+
+	// Now returns the current time. When nil, [time.Now] is used.
+	Now func() time.Time
+
+	// Rand is used as a source of entropy for the signatures.
+	// When nil, [crypto/rand.Reader] is used.
+	Rand io.Reader
+}
+
+func (s *SIG0Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Sign signs the message built by b and appends the SIG resource to the additional section.
+// It changes the building section to additionals (when needed), after calling Sign
+// no other resources should be appended to the message.
+//
+// When signing a response, request should contain the request message (including its SIG(0),
+// if it was signed), as it is covered by the signature (RFC 2931, Section 3.1). Otherwise it should be nil.
+//
+// It returns [ErrTruncated] when the SIG resource does not fit in the size limit set by [Builder.LimitMessageSize].
+func (s *SIG0Signer) Sign(b *Builder, request []byte) error {
+	if !isSupportedAlgorithm(s.Key.DNSKEY.Algorithm) {
+		return ErrUnsupportedAlgorithm
+	}
+
+	b.startTransactionSignature()
+
+	validity := s.Validity
+	if validity == 0 {
+		validity = 5 * time.Minute
+	}
+
+	now := s.now()
+	sig := ResourceSIG{
+		Algorithm:  s.Key.DNSKEY.Algorithm,
+		Expiration: uint32(now.Add(validity).Unix()),
+		Inception:  uint32(now.Add(-validity).Unix()),
+		KeyTag:     s.Key.DNSKEY.KeyTag(),
+		SignerName: s.SignerName,
+	}
+
+	var err error
+	data := appendSIG0SignedData(nil, &sig, request, b.Bytes()[b.headerStartOffset:])
+	sig.Signature, err = s.Key.sign(s.Rand, data)
+	if err != nil {
+		return err
+	}
+
+	return b.ResourceSIG(ResourceHeader{
+		Name:  Name{Length: 1},
+		Class: ClassANY,
+	}, sig)
+}
+
+// VerifySIG0 verifies the SIG(0) signature (RFC 2931) of msg, using one of the provided keys.
+//
+// keys should contain the public keys of the expected signer, only the keys with a matching
+// algorithm and key tag and without the revoke flag are tried. Callers should check
+// that the SignerName of the returned SIG resource is equal to the owner name of the keys.
+//
+// When verifying a response, request should contain the request message
+// (including its SIG(0), if it was signed). Otherwise it should be nil.
+//
+// It returns [ErrSIG0Missing] when msg is not signed.
+func (v *Verifier) VerifySIG0(msg, request []byte, keys []ResourceDNSKEY) (ResourceSIG, error) {
+	sigOffset, sigHdr, p, err := findTransactionSignature(msg, TypeSIG)
+	if err != nil {
+		if err == errTransactionSignatureMissing {
+			return ResourceSIG{}, ErrSIG0Missing
+		}
+		return ResourceSIG{}, err
+	}
+	sig, err := p.ResourceSIG()
+	if err != nil {
+		return ResourceSIG{}, err
+	}
+	if err := p.End(); err != nil {
+		return ResourceSIG{}, err
+	}
+
+	if sigHdr.Name.Length != 1 || sig.TypeCovered != 0 || sig.Labels != 0 || sig.OriginalTTL != 0 {
+		return ResourceSIG{}, errSIG0InvalidResource
+	}
+
+	now := uint32(v.now().Unix())
+	if int32(now-sig.Inception) < 0 {
+		return ResourceSIG{}, ErrSignatureNotYetValid
+	}
+	if int32(sig.Expiration-now) < 0 {
+		return ResourceSIG{}, ErrSignatureExpired
+	}
+
+	if !isSupportedAlgorithm(sig.Algorithm) {
+		return ResourceSIG{}, ErrUnsupportedAlgorithm
+	}
+
+	data := appendSIG0SignedData(nil, &sig, request, stripTransactionSignature(msg, sigOffset))
+
+	matchingKey := false
+	for i := range keys {
+		key := &keys[i]
+		if key.Algorithm != sig.Algorithm || key.Protocol != DNSKEYProtocol ||
+			key.Flags&DNSKEYFlagRevoke != 0 || key.KeyTag() != sig.KeyTag {
+			continue
+		}
+		matchingKey = true
+		if verifySignature(key, data, sig.Signature) {
+			return sig, nil
+		}
+	}
+
+	if !matchingKey {
+		return ResourceSIG{}, ErrNoMatchingKey
+	}
+	return ResourceSIG{}, ErrInvalidSignature
+}
+
+// appendSIG0SignedData appends the data covered by the SIG(0) signature
+// to dst (RFC 2931, Section 3.1), msg is the message without the SIG resource.
+func appendSIG0SignedData(dst []byte, sig *ResourceSIG, request, msg []byte) []byte {
+	dstStart := len(dst)
+	dst = appendRRSIGRData(dst, (*ResourceRRSIG)(sig), false)
+	lowerASCII(dst[dstStart+18:])
+	dst = append(dst, request...)
+	return append(dst, msg...)
+}
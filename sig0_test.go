@@ -0,0 +1,225 @@
+package dnsmsg
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+func TestSIG0SignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub := append([]byte{3}, 1, 0, 1)
+	rsaPub = append(rsaPub, rsaKey.N.Bytes()...)
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Pub := make([]byte, 64)
+	p256Key.X.FillBytes(p256Pub[:32])
+	p256Key.Y.FillBytes(p256Pub[32:])
+
+	ed25519Pub, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		alg    DNSSECAlgorithm
+		signer crypto.Signer
+		pub    []byte
+	}{
+		{DNSSECAlgorithmRSASHA256, rsaKey, rsaPub},
+		{DNSSECAlgorithmECDSAP256SHA256, p256Key, p256Pub},
+		{DNSSECAlgorithmED25519, ed25519Key, ed25519Pub},
+	}
+
+	now := time.Unix(1700000000, 0)
+	v := Verifier{Now: func() time.Time { return now.Add(time.Minute) }}
+
+	for _, tt := range cases {
+		key := ResourceDNSKEY{
+			Protocol:  DNSKEYProtocol,
+			Algorithm: tt.alg,
+			PublicKey: tt.pub,
+		}
+		signer := SIG0Signer{
+			Key:        SigningKey{DNSKEY: key, Signer: tt.signer},
+			SignerName: MustParseName("KEY.example.com"),
+			Now:        func() time.Time { return now },
+		}
+
+		b := testTSIGMessage(t, 1234, 0)
+		if err := signer.Sign(b, nil); err != nil {
+			t.Fatalf("%v: signer.Sign() unexpected error: %v", tt.alg, err)
+		}
+		req := b.Bytes()
+
+		if hdr := b.Header(); hdr.ARCount != 1 {
+			t.Fatalf("%v: b.Header().ARCount = %v, want: 1", tt.alg, hdr.ARCount)
+		}
+
+		p, _, err := Parse(req)
+		if err != nil {
+			t.Fatalf("%v: Parse() unexpected error: %v", tt.alg, err)
+		}
+		p.SkipQuestions()
+		p.StartAnswers()
+		p.StartAuthorities()
+		p.StartAdditionals()
+		hdr, err := p.ResourceHeader()
+		if err != nil {
+			t.Fatalf("%v: p.ResourceHeader() unexpected error: %v", tt.alg, err)
+		}
+		if hdr.Name.Length != 1 || hdr.Type != TypeSIG || hdr.Class != ClassANY || hdr.TTL != 0 {
+			t.Fatalf("%v: unexpected SIG resource header: %#v", tt.alg, hdr)
+		}
+
+		sig, err := v.VerifySIG0(req, nil, []ResourceDNSKEY{key})
+		if err != nil {
+			t.Fatalf("%v: v.VerifySIG0() unexpected error: %v", tt.alg, err)
+		}
+		signerName := MustParseName("key.example.com")
+		if !sig.SignerName.Equal(&signerName) || sig.KeyTag != key.KeyTag() || sig.Algorithm != tt.alg ||
+			sig.Inception != uint32(now.Add(-5*time.Minute).Unix()) || sig.Expiration != uint32(now.Add(5*time.Minute).Unix()) {
+			t.Fatalf("%v: unexpected SIG resource: %#v", tt.alg, sig)
+		}
+
+		// Response, covers the signed request.
+		b = testTSIGMessage(t, 1234, 2)
+		if err := signer.Sign(b, req); err != nil {
+			t.Fatalf("%v: signer.Sign() unexpected error: %v", tt.alg, err)
+		}
+		resp := b.Bytes()
+
+		if _, err := v.VerifySIG0(resp, req, []ResourceDNSKEY{key}); err != nil {
+			t.Fatalf("%v: v.VerifySIG0() unexpected error: %v", tt.alg, err)
+		}
+		if _, err := v.VerifySIG0(resp, nil, []ResourceDNSKEY{key}); err != ErrInvalidSignature {
+			t.Fatalf("%v: v.VerifySIG0() unexpected error: %v, want: %v", tt.alg, err, ErrInvalidSignature)
+		}
+
+		tampered := append([]byte{}, resp...)
+		tampered[1] ^= 1
+		if _, err := v.VerifySIG0(tampered, req, []ResourceDNSKEY{key}); err != ErrInvalidSignature {
+			t.Fatalf("%v: v.VerifySIG0() unexpected error: %v, want: %v", tt.alg, err, ErrInvalidSignature)
+		}
+	}
+}
+
+func TestSIG0VerifyErrors(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := ResourceDNSKEY{
+		Protocol:  DNSKEYProtocol,
+		Algorithm: DNSSECAlgorithmED25519,
+		PublicKey: pub,
+	}
+
+	now := time.Unix(1700000000, 0)
+	signer := SIG0Signer{
+		Key:        SigningKey{DNSKEY: key, Signer: priv},
+		SignerName: MustParseName("key.example.com"),
+		Validity:   time.Minute,
+		Now:        func() time.Time { return now },
+	}
+
+	b := testTSIGMessage(t, 1234, 1)
+	if err := signer.Sign(b, nil); err != nil {
+		t.Fatalf("signer.Sign() unexpected error: %v", err)
+	}
+	msg := b.Bytes()
+
+	v := Verifier{Now: func() time.Time { return now.Add(2 * time.Minute) }}
+	if _, err := v.VerifySIG0(msg, nil, []ResourceDNSKEY{key}); err != ErrSignatureExpired {
+		t.Errorf("v.VerifySIG0() unexpected error: %v, want: %v", err, ErrSignatureExpired)
+	}
+
+	v = Verifier{Now: func() time.Time { return now.Add(-2 * time.Minute) }}
+	if _, err := v.VerifySIG0(msg, nil, []ResourceDNSKEY{key}); err != ErrSignatureNotYetValid {
+		t.Errorf("v.VerifySIG0() unexpected error: %v, want: %v", err, ErrSignatureNotYetValid)
+	}
+
+	v = Verifier{Now: func() time.Time { return now }}
+	revoked := key
+	revoked.Flags |= DNSKEYFlagRevoke
+	if _, err := v.VerifySIG0(msg, nil, []ResourceDNSKEY{revoked}); err != ErrNoMatchingKey {
+		t.Errorf("v.VerifySIG0() unexpected error: %v, want: %v", err, ErrNoMatchingKey)
+	}
+
+	unsigned := testTSIGMessage(t, 1234, 1)
+	if _, err := v.VerifySIG0(unsigned.Bytes(), nil, []ResourceDNSKEY{key}); err != ErrSIG0Missing {
+		t.Errorf("v.VerifySIG0() unexpected error: %v, want: %v", err, ErrSIG0Missing)
+	}
+
+	b = testTSIGMessage(t, 1234, 1)
+	if err := signer.Sign(b, nil); err != nil {
+		t.Fatalf("signer.Sign() unexpected error: %v", err)
+	}
+	if err := b.ResourceA(ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}, ResourceA{}); err != nil {
+		t.Fatalf("b.ResourceA() unexpected error: %v", err)
+	}
+	if _, err := v.VerifySIG0(b.Bytes(), nil, []ResourceDNSKEY{key}); err != errTransactionSignatureNotLast {
+		t.Errorf("v.VerifySIG0() unexpected error: %v, want: %v", err, errTransactionSignatureNotLast)
+	}
+
+	signer.Key.DNSKEY.Algorithm = DNSSECAlgorithmRSAMD5
+	if err := signer.Sign(testTSIGMessage(t, 1234, 0), nil); err != ErrUnsupportedAlgorithm {
+		t.Errorf("signer.Sign() unexpected error: %v, want: %v", err, ErrUnsupportedAlgorithm)
+	}
+}
+
+func TestSIG0Resource(t *testing.T) {
+	sig := ResourceSIG{
+		Algorithm:  DNSSECAlgorithmED25519,
+		Expiration: 1700000300,
+		Inception:  1700000000,
+		KeyTag:     1234,
+		SignerName: MustParseName("key.example.com"),
+		Signature:  []byte{1, 2, 3, 4},
+	}
+
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+	b.StartAuthorities()
+	b.StartAdditionals()
+	if err := b.ResourceSIG(ResourceHeader{Name: MustParseName("."), Class: ClassANY}, sig); err != nil {
+		t.Fatalf("b.ResourceSIG() unexpected error: %v", err)
+	}
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	p.StartAnswers()
+	p.StartAuthorities()
+	p.StartAdditionals()
+	hdr, err := p.ResourceHeader()
+	if err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+	if hdr.Type != TypeSIG {
+		t.Fatalf("hdr.Type = %v, want: %v", hdr.Type, TypeSIG)
+	}
+	if _, err := p.ResourceRRSIG(); err != errInvalidOperation {
+		t.Fatalf("p.ResourceRRSIG() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+	got, err := p.ResourceSIG()
+	if err != nil {
+		t.Fatalf("p.ResourceSIG() unexpected error: %v", err)
+	}
+	if got.Algorithm != sig.Algorithm || got.Expiration != sig.Expiration || got.Inception != sig.Inception ||
+		got.KeyTag != sig.KeyTag || !got.SignerName.Equal(&sig.SignerName) || string(got.Signature) != string(sig.Signature) {
+		t.Fatalf("p.ResourceSIG() = %#v, want: %#v", got, sig)
+	}
+}
@@ -0,0 +1,321 @@
+package dnsmsg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrStreamClosed is returned by [StreamConn.Exchange] when the connection was closed,
+	// either by [StreamConn.Close], after the idle timeout, or because of a read error.
+	ErrStreamClosed = errors.New("dns stream connection closed")
+
+	errStreamMessageTooLong = errors.New("dns message too long for the stream framing")
+	errStreamDuplicateID    = errors.New("query with the same ID is already outstanding")
+)
+
+// streamLengthPrefixLength is the length of the prefix of each DNS message
+// sent over stream transports (RFC 1035, Section 4.2.2, RFC 7766).
+const streamLengthPrefixLength = 2
+
+// StartStreamBuilder creates a new DNS builder, that builds the message with a
+// 2-byte length prefix, used by stream transports (TCP, TLS) (RFC 7766, Section 8).
+// The prefixed message is going to be appended to the provided byte slice (buf).
+//
+// The message size is limited to 65535 bytes (see [Builder.LimitMessageSize]),
+// the length prefix is filled by [Builder.StreamBytes].
+func StartStreamBuilder(buf []byte, id uint16, flags Flags) (b Builder) {
+	b = StartBuilder(append(buf, 0, 0), id, flags)
+	b.stream = true
+	b.LimitMessageSize(math.MaxUint16)
+	return
+}
+
+// StreamBytes returns the built DNS message, with the 2-byte length prefix.
+// Just like [Builder.Bytes] it returns the entire buffer (including the buf passed to [StartStreamBuilder]).
+//
+// This method can only be used when the Builder was created by [StartStreamBuilder], otherwise it panics.
+func (b *Builder) StreamBytes() []byte {
+	if !b.stream {
+		panic("dnsmsg: invalid usage of the Builder: StreamBytes called on a Builder not created by StartStreamBuilder")
+	}
+	msg := b.Bytes()
+	length := len(msg) - b.headerStartOffset
+	if length > math.MaxUint16 {
+		panic("dnsmsg: invalid usage of the Builder: message too long for the stream framing")
+	}
+	packUint16(msg[b.headerStartOffset-streamLengthPrefixLength:], uint16(length))
+	return msg
+}
+
+// StreamWriter writes DNS messages with a 2-byte length prefix (RFC 7766, Section 8).
+// It is safe to use it concurrently from multiple goroutines.
+type StreamWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStreamWriter creates a new [StreamWriter] that writes to w.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w}
+}
+
+// WriteMsg writes a single DNS message, prefixed with its length.
+//
+// The length prefix and the message are written with a single write call
+// when the underlying writer supports it ([net.Buffers]), without copying the message.
+func (w *StreamWriter) WriteMsg(msg []byte) error {
+	if len(msg) > math.MaxUint16 {
+		return errStreamMessageTooLong
+	}
+	var length [streamLengthPrefixLength]byte
+	packUint16(length[:], uint16(len(msg)))
+	bufs := net.Buffers{length[:], msg}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := bufs.WriteTo(w.w)
+	return err
+}
+
+// WritePrefixedMsg writes a single DNS message, that is already prefixed with its
+// length (as returned by [Builder.StreamBytes]).
+func (w *StreamWriter) WritePrefixedMsg(prefixedMsg []byte) error {
+	if len(prefixedMsg) < streamLengthPrefixLength || int(unpackUint16(prefixedMsg)) != len(prefixedMsg)-streamLengthPrefixLength {
+		return errInvalidOperation
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.w.Write(prefixedMsg)
+	return err
+}
+
+// StreamReader reads DNS messages with a 2-byte length prefix (RFC 7766, Section 8).
+type StreamReader struct {
+	r *bufio.Reader
+}
+
+// NewStreamReader creates a new [StreamReader] that reads from r.
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{r: bufio.NewReader(r)}
+}
+
+// ReadMsg reads a single DNS message and appends it to buf.
+//
+// When the stream ends before the message is fully read, it returns [io.ErrUnexpectedEOF],
+// [io.EOF] is only returned when the stream ends at the message boundary.
+func (r *StreamReader) ReadMsg(buf []byte) ([]byte, error) {
+	var length [streamLengthPrefixLength]byte
+	if _, err := io.ReadFull(r.r, length[:]); err != nil {
+		return buf, err
+	}
+
+	msgLength := int(unpackUint16(length[:]))
+	if cap(buf)-len(buf) < msgLength {
+		newBuf := make([]byte, len(buf), len(buf)+msgLength)
+		copy(newBuf, buf)
+		buf = newBuf
+	}
+
+	msg := buf[len(buf) : len(buf)+msgLength]
+	if _, err := io.ReadFull(r.r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf, err
+	}
+	return buf[:len(buf)+msgLength], nil
+}
+
+// StreamConn is a client side DNS stream connection (RFC 7766), it supports pipelining
+// of multiple outstanding queries, with responses matched by their ID and question,
+// so that the responses may arrive in any order (RFC 7766, Section 6.2.1.1).
+//
+// It is safe to use it concurrently from multiple goroutines.
+type StreamConn struct {
+	conn        net.Conn
+	w           *StreamWriter
+	idleTimeout time.Duration
+
+	mu          sync.Mutex
+	outstanding map[uint16]*streamQuery
+	idleTimer   *time.Timer
+	readerStart sync.Once
+	closed      bool
+	err         error
+}
+
+type streamQuery struct {
+	query clientQuery
+	resp  chan []byte
+}
+
+// NewStreamConn creates a new [StreamConn].
+//
+// When idleTimeout is non-zero, the connection is closed after there were no
+// outstanding queries for idleTimeout (RFC 7766, Section 6.2.3).
+func NewStreamConn(conn net.Conn, idleTimeout time.Duration) *StreamConn {
+	c := &StreamConn{
+		conn:        conn,
+		w:           NewStreamWriter(conn),
+		idleTimeout: idleTimeout,
+		outstanding: make(map[uint16]*streamQuery),
+	}
+	c.mu.Lock()
+	c.startIdleTimer()
+	c.mu.Unlock()
+	return c
+}
+
+// Exchange sends the query and waits for the matching response.
+// The query must contain exactly one question, and must have an ID, that is
+// different from IDs of all other currently outstanding queries.
+//
+// Responses that do not match any outstanding query are ignored.
+func (c *StreamConn) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	q, err := parseClientQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	sq := &streamQuery{query: q, resp: make(chan []byte, 1)}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, c.closedErr()
+	}
+	if _, ok := c.outstanding[q.id]; ok {
+		c.mu.Unlock()
+		return nil, errStreamDuplicateID
+	}
+	c.outstanding[q.id] = sq
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+	c.mu.Unlock()
+
+	c.readerStart.Do(func() { go c.reader() })
+
+	if err := c.w.WriteMsg(query); err != nil {
+		c.closeWithError(err)
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-sq.resp:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return nil, c.closedErr()
+		}
+		return resp, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		if c.outstanding[q.id] == sq {
+			c.removeOutstanding(q.id)
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *StreamConn) closedErr() error {
+	if c.err != nil {
+		return c.err
+	}
+	return ErrStreamClosed
+}
+
+// removeOutstanding must be called with c.mu held.
+func (c *StreamConn) removeOutstanding(id uint16) {
+	delete(c.outstanding, id)
+	if len(c.outstanding) == 0 {
+		c.startIdleTimer()
+	}
+}
+
+// startIdleTimer must be called with c.mu held.
+func (c *StreamConn) startIdleTimer() {
+	if c.idleTimeout == 0 {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(c.idleTimeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.idleTimer == t {
+			c.closeLocked(nil)
+		}
+	})
+	c.idleTimer = t
+}
+
+func (c *StreamConn) reader() {
+	r := NewStreamReader(c.conn)
+	for {
+		msg, err := r.ReadMsg(nil)
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+
+		_, hdr, err := Parse(msg)
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if sq, ok := c.outstanding[hdr.ID]; ok && sq.query.matches(msg) {
+			c.removeOutstanding(hdr.ID)
+			sq.resp <- msg
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *StreamConn) closeWithError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked(err)
+}
+
+// closeLocked must be called with c.mu held.
+func (c *StreamConn) closeLocked(err error) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if err != nil && !errors.Is(err, net.ErrClosed) && err != io.EOF {
+		c.err = err
+	}
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+	c.conn.Close()
+	for id, sq := range c.outstanding {
+		close(sq.resp)
+		delete(c.outstanding, id)
+	}
+}
+
+func (c *StreamConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Close closes the connection, all outstanding queries return [ErrStreamClosed].
+func (c *StreamConn) Close() error {
+	c.closeWithError(nil)
+	return nil
+}
@@ -0,0 +1,224 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamBuilder(t *testing.T) {
+	b := StartStreamBuilder([]byte{0xFF}, 1234, 0)
+	if err := b.Question(Question{Name: MustParseName("example.com"), Type: TypeA, Class: ClassIN}); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+
+	prefixed := b.StreamBytes()
+	if prefixed[0] != 0xFF {
+		t.Fatalf("StreamBytes() did not preserve the buffer prefix: %v", prefixed)
+	}
+	msg := prefixed[3:]
+	if int(unpackUint16(prefixed[1:])) != len(msg) {
+		t.Fatalf("unexpected length prefix: %v, want: %v", unpackUint16(prefixed[1:]), len(msg))
+	}
+	if _, _, err := Parse(msg); err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewStreamWriter(&buf)
+	if err := w.WritePrefixedMsg(prefixed[1:]); err != nil {
+		t.Fatalf("w.WritePrefixedMsg() unexpected error: %v", err)
+	}
+	if err := w.WritePrefixedMsg(prefixed[1 : len(prefixed)-1]); err != errInvalidOperation {
+		t.Fatalf("w.WritePrefixedMsg() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+	if err := w.WriteMsg(msg); err != nil {
+		t.Fatalf("w.WriteMsg() unexpected error: %v", err)
+	}
+	if err := w.WriteMsg(make([]byte, 65536)); err != errStreamMessageTooLong {
+		t.Fatalf("w.WriteMsg() unexpected error: %v, want: %v", err, errStreamMessageTooLong)
+	}
+
+	r := NewStreamReader(&buf)
+	for i := 0; i < 2; i++ {
+		got, err := r.ReadMsg([]byte{1})
+		if err != nil {
+			t.Fatalf("r.ReadMsg() unexpected error: %v", err)
+		}
+		if !bytes.Equal(got[1:], msg) || got[0] != 1 {
+			t.Fatalf("r.ReadMsg() = %v, want: %v", got, msg)
+		}
+	}
+	if _, err := r.ReadMsg(nil); err != io.EOF {
+		t.Fatalf("r.ReadMsg() unexpected error: %v, want: %v", err, io.EOF)
+	}
+
+	r = NewStreamReader(bytes.NewReader([]byte{0, 10, 1, 2}))
+	if _, err := r.ReadMsg(nil); err != io.ErrUnexpectedEOF {
+		t.Fatalf("r.ReadMsg() unexpected error: %v, want: %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestStreamBuilderLimit(t *testing.T) {
+	b := StartStreamBuilder(nil, 0, 0)
+	b.StartAnswers()
+	hdr := ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}
+	var err error
+	for err == nil {
+		err = b.RawResource(hdr, RawResource{Type: 65280, Data: make([]byte, 1024)})
+	}
+	if err != ErrTruncated {
+		t.Fatalf("b.RawResource() unexpected error: %v, want: %v", err, ErrTruncated)
+	}
+	if l := len(b.StreamBytes()); l > 65535+2 {
+		t.Fatalf("len(b.StreamBytes()) = %v, want <= %v", l, 65535+2)
+	}
+}
+
+func TestStreamBuilderStreamBytesPanic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("StreamBytes() did not panic on a Builder created by StartBuilder")
+		}
+	}()
+	b := StartBuilder([]byte{0xFF, 0xFF}, 0, 0)
+	b.StreamBytes()
+}
+
+func TestStreamBuilderReset(t *testing.T) {
+	b := StartStreamBuilder(nil, 0, 0)
+	b.Reset([]byte{0xFF}, 1234, 0)
+	prefixed := b.StreamBytes()
+	if prefixed[0] != 0xFF || int(unpackUint16(prefixed[1:])) != headerLen || len(prefixed) != 3+headerLen {
+		t.Fatalf("StreamBytes() = %v, want a 2-byte prefixed header after the 0xFF byte", prefixed)
+	}
+	if _, hdr, err := Parse(prefixed[3:]); err != nil || hdr.ID != 1234 {
+		t.Fatalf("Parse() = (%v, %v), want: (1234, <nil>)", hdr.ID, err)
+	}
+}
+
+func TestStreamConnPipelining(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	// Server collects three queries and answers them in the reverse order,
+	// with an unrelated response first.
+	go func() {
+		r := NewStreamReader(server)
+		w := NewStreamWriter(server)
+		var queries [][]byte
+		for i := 0; i < 3; i++ {
+			q, err := r.ReadMsg(nil)
+			if err != nil {
+				return
+			}
+			queries = append(queries, q)
+		}
+		w.WriteMsg(testClientResponse(queries[0], 0xFFFF, false, 0))
+		for i := len(queries) - 1; i >= 0; i-- {
+			w.WriteMsg(testClientResponse(queries[i], 0, false, byte(unpackUint16(queries[i]))))
+		}
+	}()
+
+	c := NewStreamConn(client, 0)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func(id uint16) {
+			defer wg.Done()
+			resp, err := c.Exchange(context.Background(), testClientQuery(t, id, "example.com"))
+			if err != nil {
+				t.Errorf("c.Exchange() unexpected error: %v", err)
+				return
+			}
+			_, hdr, _ := Parse(resp)
+			if hdr.ID != id {
+				t.Errorf("got response with ID %v, want: %v", hdr.ID, id)
+			}
+			if a := testClientAnswer(t, resp); a != byte(id) {
+				t.Errorf("got answer %v, want: %v", a, id)
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+}
+
+func TestStreamConnDuplicateID(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(io.Discard, server)
+
+	c := NewStreamConn(client, 0)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := c.Exchange(ctx, testClientQuery(t, 1, "example.com"))
+		done <- err
+	}()
+
+	for {
+		c.mu.Lock()
+		n := len(c.outstanding)
+		c.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != errStreamDuplicateID {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, errStreamDuplicateID)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, context.Canceled)
+	}
+}
+
+func TestStreamConnIdleTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		r := NewStreamReader(server)
+		w := NewStreamWriter(server)
+		for {
+			q, err := r.ReadMsg(nil)
+			if err != nil {
+				return
+			}
+			w.WriteMsg(testClientResponse(q, 0, false, 1))
+		}
+	}()
+
+	c := NewStreamConn(client, 20*time.Millisecond)
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != nil {
+		t.Fatalf("c.Exchange() unexpected error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 2, "example.com")); err != ErrStreamClosed {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, ErrStreamClosed)
+	}
+}
+
+func TestStreamConnClose(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(io.Discard, server)
+
+	c := NewStreamConn(client, 0)
+	time.AfterFunc(20*time.Millisecond, func() { c.Close() })
+	if _, err := c.Exchange(context.Background(), testClientQuery(t, 1, "example.com")); err != ErrStreamClosed {
+		t.Fatalf("c.Exchange() unexpected error: %v, want: %v", err, ErrStreamClosed)
+	}
+}
@@ -0,0 +1,788 @@
+package dnsmsg
+
+import (
+	"errors"
+	"math"
+)
+
+// SVCParamKey is a key of a service parameter (SvcParam) of a SVCB or HTTPS resource.
+type SVCParamKey uint16
+
+// Service parameter keys defined in RFC 9460.
+const (
+	SVCParamKeyMandatory     SVCParamKey = 0
+	SVCParamKeyALPN          SVCParamKey = 1
+	SVCParamKeyNoDefaultALPN SVCParamKey = 2
+	SVCParamKeyPort          SVCParamKey = 3
+	SVCParamKeyIPv4Hint      SVCParamKey = 4
+	SVCParamKeyECH           SVCParamKey = 5
+	SVCParamKeyIPv6Hint      SVCParamKey = 6
+)
+
+// SVCParam is a service parameter of a SVCB or HTTPS resource.
+//
+// It is implemented by [SVCParamMandatory], [SVCParamALPN], [SVCParamNoDefaultALPN],
+// [SVCParamPort], [SVCParamIPv4Hint], [SVCParamECH], [SVCParamIPv6Hint] and [SVCParamRaw].
+type SVCParam interface {
+	svcParamKey() SVCParamKey
+}
+
+// SVCParamMandatory is a "mandatory" service parameter.
+//
+// Keys must be sorted in strictly increasing order and must not contain
+// the [SVCParamKeyMandatory] key.
+type SVCParamMandatory struct {
+	Keys []SVCParamKey
+}
+
+func (*SVCParamMandatory) svcParamKey() SVCParamKey { return SVCParamKeyMandatory }
+
+// SVCParamALPN is an "alpn" service parameter.
+type SVCParamALPN struct {
+	ALPN [][]byte
+}
+
+func (*SVCParamALPN) svcParamKey() SVCParamKey { return SVCParamKeyALPN }
+
+// SVCParamNoDefaultALPN is a "no-default-alpn" service parameter.
+type SVCParamNoDefaultALPN struct{}
+
+func (*SVCParamNoDefaultALPN) svcParamKey() SVCParamKey { return SVCParamKeyNoDefaultALPN }
+
+// SVCParamPort is a "port" service parameter.
+type SVCParamPort struct {
+	Port uint16
+}
+
+func (*SVCParamPort) svcParamKey() SVCParamKey { return SVCParamKeyPort }
+
+// SVCParamIPv4Hint is an "ipv4hint" service parameter.
+type SVCParamIPv4Hint struct {
+	Hints [][4]byte
+}
+
+func (*SVCParamIPv4Hint) svcParamKey() SVCParamKey { return SVCParamKeyIPv4Hint }
+
+// SVCParamECH is an "ech" service parameter.
+type SVCParamECH struct {
+	// ECH is an ECHConfigList in wire format.
+	ECH []byte
+}
+
+func (*SVCParamECH) svcParamKey() SVCParamKey { return SVCParamKeyECH }
+
+// SVCParamIPv6Hint is an "ipv6hint" service parameter.
+type SVCParamIPv6Hint struct {
+	Hints [][16]byte
+}
+
+func (*SVCParamIPv6Hint) svcParamKey() SVCParamKey { return SVCParamKeyIPv6Hint }
+
+// SVCParamRaw is a service parameter with a raw (not interpreted) value.
+// It is used for service parameters not supported by this package.
+type SVCParamRaw struct {
+	Key   SVCParamKey
+	Value []byte
+}
+
+func (p *SVCParamRaw) svcParamKey() SVCParamKey { return p.Key }
+
+// ResourceSVCB is a SVCB resource defined in RFC 9460.
+type ResourceSVCB struct {
+	Priority uint16
+
+	// Target is never compressed by the [Builder].
+	Target Name
+
+	// Params must be sorted by the key in strictly increasing order.
+	Params []SVCParam
+}
+
+// ResourceHTTPS is a HTTPS resource defined in RFC 9460, it
+// uses the same resource data format as the [ResourceSVCB].
+type ResourceHTTPS ResourceSVCB
+
+// ResourceSVCB appends a single SVCB resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceSVCB(hdr ResourceHeader, svcb ResourceSVCB) error {
+	svcbb, err := b.ResourceSVCBBuilder(hdr, svcb.Priority, svcb.Target)
+	if err != nil {
+		return err
+	}
+	return svcbb.params(svcb.Params)
+}
+
+// ResourceHTTPS appends a single HTTPS resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceHTTPS(hdr ResourceHeader, https ResourceHTTPS) error {
+	svcbb, err := b.ResourceHTTPSBuilder(hdr, https.Priority, https.Target)
+	if err != nil {
+		return err
+	}
+	return svcbb.params(https.Params)
+}
+
+func (b *ResourceSVCBBuilder) params(params []SVCParam) error {
+	for _, param := range params {
+		var err error
+		switch param := param.(type) {
+		case *SVCParamMandatory:
+			err = b.Mandatory(*param)
+		case *SVCParamALPN:
+			err = b.ALPN(*param)
+		case *SVCParamNoDefaultALPN:
+			err = b.NoDefaultALPN()
+		case *SVCParamPort:
+			err = b.Port(*param)
+		case *SVCParamIPv4Hint:
+			err = b.IPv4Hint(*param)
+		case *SVCParamECH:
+			err = b.ECH(*param)
+		case *SVCParamIPv6Hint:
+			err = b.IPv6Hint(*param)
+		case *SVCParamRaw:
+			err = b.RawParam(param.Key, param.Value)
+		}
+		if err != nil {
+			b.Remove()
+			return err
+		}
+	}
+	if err := b.End(); err != nil {
+		b.Remove()
+		return err
+	}
+	return nil
+}
+
+// ResourceSVCBBuilder creates a new instance of [ResourceSVCBBuilder], used
+// for building a SVCB resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// After creating the [ResourceSVCBBuilder], all resource appending methods shouldn't be used
+// on the Builder until you call [ResourceSVCBBuilder.End] or [ResourceSVCBBuilder.Remove].
+//
+// The target name is never compressed.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceSVCBBuilder(hdr ResourceHeader, priority uint16, target Name) (ResourceSVCBBuilder, error) {
+	hdr.Type = TypeSVCB
+	return b.resourceSVCBBuilder(hdr, priority, target)
+}
+
+// ResourceHTTPSBuilder creates a new instance of [ResourceSVCBBuilder], used
+// for building a HTTPS resource.
+// It errors when the amount of resources in the current section is equal to 65535.
+//
+// After creating the [ResourceSVCBBuilder], all resource appending methods shouldn't be used
+// on the Builder until you call [ResourceSVCBBuilder.End] or [ResourceSVCBBuilder.Remove].
+//
+// The target name is never compressed.
+//
+// The building section must NOT be set to questions, otherwise it panics.
+func (b *Builder) ResourceHTTPSBuilder(hdr ResourceHeader, priority uint16, target Name) (ResourceSVCBBuilder, error) {
+	hdr.Type = TypeHTTPS
+	return b.resourceSVCBBuilder(hdr, priority, target)
+}
+
+func (b *Builder) resourceSVCBBuilder(hdr ResourceHeader, priority uint16, target Name) (ResourceSVCBBuilder, error) {
+	fixup, hdrOffset, c, err := b.appendHeaderWithLengthFixupNoInc(hdr, b.maxBufSize-2)
+	if err != nil {
+		return ResourceSVCBBuilder{}, err
+	}
+	b.buf = appendUint16(b.buf, priority)
+	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize, b.headerStartOffset, target.asSlice(), false)
+	if err != nil {
+		b.nb.removeNamesFromCompressionMap(b.headerStartOffset, hdrOffset)
+		b.buf = b.buf[:hdrOffset]
+		b.truncated(&hdr, err)
+		return ResourceSVCBBuilder{}, err
+	}
+	b.curSection |= sectionDetachedMask
+	b.fakeBufSize = hdrOffset
+	return ResourceSVCBBuilder{
+		b:                 b,
+		count:             c,
+		hdrOffset:         hdrOffset,
+		paramsStartOffset: len(b.buf),
+		fixup:             fixup,
+	}, nil
+}
+
+// ResourceSVCBBuilder is a builder for building service parameters of
+// SVCB and HTTPS resources.
+//
+// Parameters must be appended in strictly increasing order of their keys.
+//
+// Once the entire resource data has been created, the [ResourceSVCBBuilder.End] method needs to be called.
+type ResourceSVCBBuilder struct {
+	_ noCopy
+
+	b                 *Builder
+	count             *uint16
+	hdrOffset         int
+	paramsStartOffset int
+	fixup             headerLengthFixup
+
+	lastKey   SVCParamKey
+	hasParams bool
+
+	// truncated is set when any of the methods returned ErrTruncated.
+	truncated bool
+}
+
+var (
+	errSVCParamKeyOrder       = errors.New("svcb params are not in strictly increasing key order")
+	errInvalidSVCParam        = errors.New("invalid svcb param")
+	errSVCParamMandatoryUnmet = errors.New("svcb param listed as mandatory is missing")
+)
+
+// Length returns the current length of the resource data in bytes.
+func (b *ResourceSVCBBuilder) Length() uint16 {
+	return uint16(b.fixup.rDataLength(b.b))
+}
+
+// End finalizes the resource data building process and reflects the changes made using the ResourceSVCBBuilder in the Builder.
+// This method must be called after writing the entire resource data is done.
+//
+// End returns an error when any of the keys listed in the "mandatory"
+// service parameter was not appended, in such case the resource is not
+// removed, it can be removed by the [ResourceSVCBBuilder.Remove] method.
+//
+// Attempting to use the ResourceSVCBBuilder after a successful call to End might lead to panics.
+func (b *ResourceSVCBBuilder) End() error {
+	if err := b.checkMandatory(); err != nil {
+		return err
+	}
+	b.b.fakeBufSize = math.MaxInt
+	b.b.curSection &= ^sectionDetachedMask
+	b.fixup.fixup(b.b)
+	*b.count++
+	b.b = nil
+	return nil
+}
+
+// checkMandatory verifies that all keys listed in the mandatory
+// param were appended to the resource.
+func (b *ResourceSVCBBuilder) checkMandatory() error {
+	params := b.b.buf[b.paramsStartOffset:]
+	if len(params) == 0 || SVCParamKey(unpackUint16(params)) != SVCParamKeyMandatory {
+		return nil
+	}
+
+	mandatoryLength := int(unpackUint16(params[2:]))
+	mandatory := params[4 : 4+mandatoryLength]
+	if len(mandatory)%2 != 0 {
+		// Might happen when the mandatory param was appended using RawParam.
+		return errInvalidSVCParam
+	}
+	params = params[4+mandatoryLength:]
+
+	for ; len(mandatory) != 0; mandatory = mandatory[2:] {
+		if !svcParamsContainKey(params, SVCParamKey(unpackUint16(mandatory))) {
+			return errSVCParamMandatoryUnmet
+		}
+	}
+	return nil
+}
+
+func svcParamsContainKey(params []byte, key SVCParamKey) bool {
+	for len(params) != 0 {
+		if SVCParamKey(unpackUint16(params)) == key {
+			return true
+		}
+		params = params[4+int(unpackUint16(params[2:])):]
+	}
+	return false
+}
+
+// Remove removes the resource from the message.
+// Attempting to use the ResourceSVCBBuilder after calling Remove might lead to panics.
+func (b *ResourceSVCBBuilder) Remove() {
+	b.b.fakeBufSize = math.MaxInt
+	b.b.curSection &= ^sectionDetachedMask
+	hdr := b.b.resourceHeaderAt(b.hdrOffset)
+	b.b.nb.removeNamesFromCompressionMap(b.b.headerStartOffset, b.hdrOffset)
+	b.b.buf = b.b.buf[:b.hdrOffset]
+	if b.truncated {
+		b.b.truncated(&hdr, ErrTruncated)
+	}
+	b.b = nil
+}
+
+func (b *ResourceSVCBBuilder) appendParamMetadata(key SVCParamKey, encodingLength int) error {
+	if b.hasParams && key <= b.lastKey {
+		return errSVCParamKeyOrder
+	}
+	if encodingLength > math.MaxUint16 || b.fixup.rDataLength(b.b)+encodingLength+4 > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	if len(b.b.buf)+encodingLength+4 > b.b.maxBufSize {
+		b.truncated = true
+		return ErrTruncated
+	}
+	b.b.buf = appendUint16(b.b.buf, uint16(key))
+	b.b.buf = appendUint16(b.b.buf, uint16(encodingLength))
+	b.lastKey = key
+	b.hasParams = true
+	return nil
+}
+
+// Mandatory appends a single "mandatory" service parameter.
+func (b *ResourceSVCBBuilder) Mandatory(param SVCParamMandatory) error {
+	if len(param.Keys) == 0 {
+		return errInvalidSVCParam
+	}
+	for i, key := range param.Keys {
+		if key == SVCParamKeyMandatory || (i != 0 && key <= param.Keys[i-1]) {
+			return errInvalidSVCParam
+		}
+	}
+	if err := b.appendParamMetadata(SVCParamKeyMandatory, len(param.Keys)*2); err != nil {
+		return err
+	}
+	for _, key := range param.Keys {
+		b.b.buf = appendUint16(b.b.buf, uint16(key))
+	}
+	return nil
+}
+
+// ALPN appends a single "alpn" service parameter.
+func (b *ResourceSVCBBuilder) ALPN(param SVCParamALPN) error {
+	if len(param.ALPN) == 0 {
+		return errInvalidSVCParam
+	}
+	length := 0
+	for _, alpn := range param.ALPN {
+		if len(alpn) == 0 || len(alpn) > math.MaxUint8 {
+			return errInvalidSVCParam
+		}
+		length += 1 + len(alpn)
+	}
+	if err := b.appendParamMetadata(SVCParamKeyALPN, length); err != nil {
+		return err
+	}
+	for _, alpn := range param.ALPN {
+		b.b.buf = append(b.b.buf, uint8(len(alpn)))
+		b.b.buf = append(b.b.buf, alpn...)
+	}
+	return nil
+}
+
+// NoDefaultALPN appends a single "no-default-alpn" service parameter.
+//
+// The "alpn" service parameter must be appended before, otherwise an error is returned.
+func (b *ResourceSVCBBuilder) NoDefaultALPN() error {
+	if !b.hasParams || b.lastKey != SVCParamKeyALPN {
+		return errInvalidSVCParam
+	}
+	return b.appendParamMetadata(SVCParamKeyNoDefaultALPN, 0)
+}
+
+// Port appends a single "port" service parameter.
+func (b *ResourceSVCBBuilder) Port(param SVCParamPort) error {
+	if err := b.appendParamMetadata(SVCParamKeyPort, 2); err != nil {
+		return err
+	}
+	b.b.buf = appendUint16(b.b.buf, param.Port)
+	return nil
+}
+
+// IPv4Hint appends a single "ipv4hint" service parameter.
+func (b *ResourceSVCBBuilder) IPv4Hint(param SVCParamIPv4Hint) error {
+	if len(param.Hints) == 0 {
+		return errInvalidSVCParam
+	}
+	if err := b.appendParamMetadata(SVCParamKeyIPv4Hint, len(param.Hints)*4); err != nil {
+		return err
+	}
+	for _, hint := range param.Hints {
+		b.b.buf = append(b.b.buf, hint[:]...)
+	}
+	return nil
+}
+
+// ECH appends a single "ech" service parameter.
+func (b *ResourceSVCBBuilder) ECH(param SVCParamECH) error {
+	if err := b.appendParamMetadata(SVCParamKeyECH, len(param.ECH)); err != nil {
+		return err
+	}
+	b.b.buf = append(b.b.buf, param.ECH...)
+	return nil
+}
+
+// IPv6Hint appends a single "ipv6hint" service parameter.
+func (b *ResourceSVCBBuilder) IPv6Hint(param SVCParamIPv6Hint) error {
+	if len(param.Hints) == 0 {
+		return errInvalidSVCParam
+	}
+	if err := b.appendParamMetadata(SVCParamKeyIPv6Hint, len(param.Hints)*16); err != nil {
+		return err
+	}
+	for _, hint := range param.Hints {
+		b.b.buf = append(b.b.buf, hint[:]...)
+	}
+	return nil
+}
+
+// RawParam appends a single service parameter with a raw value.
+//
+// The value is appended as is, it is not validated in any way, even
+// for keys supported by this package.
+func (b *ResourceSVCBBuilder) RawParam(key SVCParamKey, value []byte) error {
+	if err := b.appendParamMetadata(key, len(value)); err != nil {
+		return err
+	}
+	b.b.buf = append(b.b.buf, value...)
+	return nil
+}
+
+// ResourceSVCB parses a single SVCB resource.
+//
+// Service parameters not supported by this package are
+// returned as [SVCParamRaw].
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeSVCB].
+func (m *Parser) ResourceSVCB() (ResourceSVCB, error) {
+	if !m.resourceData || m.nextResourceType != TypeSVCB {
+		return ResourceSVCB{}, errInvalidOperation
+	}
+	return m.resourceSVCB()
+}
+
+// ResourceHTTPS parses a single HTTPS resource.
+//
+// Service parameters not supported by this package are
+// returned as [SVCParamRaw].
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeHTTPS].
+func (m *Parser) ResourceHTTPS() (ResourceHTTPS, error) {
+	if !m.resourceData || m.nextResourceType != TypeHTTPS {
+		return ResourceHTTPS{}, errInvalidOperation
+	}
+	svcb, err := m.resourceSVCB()
+	return ResourceHTTPS(svcb), err
+}
+
+func (m *Parser) resourceSVCB() (ResourceSVCB, error) {
+	off := m.curOffset
+	svcbp, err := m.ResourceSVCBParser()
+	if err != nil {
+		return ResourceSVCB{}, err
+	}
+	off, m.curOffset = m.curOffset, off
+
+	res := ResourceSVCB{
+		Priority: svcbp.Priority(),
+		Target:   svcbp.Target(),
+	}
+
+	for {
+		key, err := svcbp.Key()
+		if err != nil {
+			if err == ErrSectionDone {
+				break
+			}
+			m.resourceData = true
+			return ResourceSVCB{}, err
+		}
+
+		var param SVCParam
+		switch key {
+		case SVCParamKeyMandatory:
+			var p SVCParamMandatory
+			p, err = svcbp.Mandatory()
+			param = &p
+		case SVCParamKeyALPN:
+			var p SVCParamALPN
+			p, err = svcbp.ALPN()
+			param = &p
+		case SVCParamKeyNoDefaultALPN:
+			err = svcbp.NoDefaultALPN()
+			param = &SVCParamNoDefaultALPN{}
+		case SVCParamKeyPort:
+			var p SVCParamPort
+			p, err = svcbp.Port()
+			param = &p
+		case SVCParamKeyIPv4Hint:
+			var p SVCParamIPv4Hint
+			p, err = svcbp.IPv4Hint()
+			param = &p
+		case SVCParamKeyECH:
+			var p SVCParamECH
+			p, err = svcbp.ECH()
+			param = &p
+		case SVCParamKeyIPv6Hint:
+			var p SVCParamIPv6Hint
+			p, err = svcbp.IPv6Hint()
+			param = &p
+		default:
+			p := SVCParamRaw{Key: key}
+			p.Value, err = svcbp.RawValue()
+			param = &p
+		}
+		if err != nil {
+			m.resourceData = true
+			return ResourceSVCB{}, err
+		}
+		res.Params = append(res.Params, param)
+	}
+
+	m.curOffset = off
+	return res, nil
+}
+
+// ResourceSVCBParser creates a single [ResourceSVCBParser].
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeSVCB] or [TypeHTTPS].
+func (m *Parser) ResourceSVCBParser() (ResourceSVCBParser, error) {
+	if !m.resourceData || (m.nextResourceType != TypeSVCB && m.nextResourceType != TypeHTTPS) {
+		return ResourceSVCBParser{}, errInvalidOperation
+	}
+
+	if len(m.msg)-m.curOffset < int(m.nextResourceDataLength) || m.nextResourceDataLength < 2 {
+		return ResourceSVCBParser{}, errInvalidDNSMessage
+	}
+
+	priority := unpackUint16(m.msg[m.curOffset:])
+
+	var target Name
+	offset, err := target.unpack(m.msg, m.curOffset+2)
+	if err != nil {
+		return ResourceSVCBParser{}, err
+	}
+
+	if int(offset)+2 > int(m.nextResourceDataLength) {
+		return ResourceSVCBParser{}, errInvalidDNSMessage
+	}
+
+	m.resourceData = false
+	paramsOffset := m.curOffset + 2 + int(offset)
+	m.curOffset += int(m.nextResourceDataLength)
+	return ResourceSVCBParser{
+		p:         m,
+		priority:  priority,
+		target:    target,
+		offset:    paramsOffset,
+		maxOffset: m.curOffset,
+	}, nil
+}
+
+// ResourceSVCBParser is an incremental parser of a SVCB or HTTPS resource.
+type ResourceSVCBParser struct {
+	p         *Parser
+	priority  uint16
+	target    Name
+	offset    int
+	maxOffset int
+
+	nextData bool
+	nextKey  SVCParamKey
+	hasKey   bool
+}
+
+// Priority returns the SvcPriority field of the resource.
+func (p *ResourceSVCBParser) Priority() uint16 {
+	return p.priority
+}
+
+// Target returns the TargetName field of the resource.
+func (p *ResourceSVCBParser) Target() Name {
+	return p.target
+}
+
+// Key parses the key of the next service parameter.
+// Returns [ErrSectionDone] when no more parameters are available to parse.
+//
+// Parameter keys that are not in strictly increasing order cause an error.
+func (p *ResourceSVCBParser) Key() (SVCParamKey, error) {
+	if p.nextData {
+		return 0, errInvalidOperation
+	}
+	if p.offset == p.maxOffset {
+		return 0, ErrSectionDone
+	}
+	if p.maxOffset-p.offset < 4 {
+		return 0, errInvalidDNSMessage
+	}
+	key := SVCParamKey(unpackUint16(p.p.msg[p.offset:]))
+	if p.hasKey && key <= p.nextKey {
+		return 0, errInvalidDNSMessage
+	}
+	if p.maxOffset-p.offset-4 < int(unpackUint16(p.p.msg[p.offset+2:])) {
+		return 0, errInvalidDNSMessage
+	}
+	p.nextKey = key
+	p.hasKey = true
+	p.offset += 2
+	p.nextData = true
+	return key, nil
+}
+
+func (p *ResourceSVCBParser) value(key SVCParamKey) ([]byte, error) {
+	if !p.nextData || p.nextKey != key {
+		return nil, errInvalidOperation
+	}
+	return p.rawValue(), nil
+}
+
+func (p *ResourceSVCBParser) rawValue() []byte {
+	length := int(unpackUint16(p.p.msg[p.offset:]))
+	raw := p.p.msg[p.offset+2 : p.offset+2+length]
+	p.offset += 2 + length
+	p.nextData = false
+	return raw
+}
+
+// Skip skips the parameter value.
+func (p *ResourceSVCBParser) Skip() error {
+	if !p.nextData {
+		return errInvalidOperation
+	}
+	p.rawValue()
+	return nil
+}
+
+// RawValue returns the raw (not interpreted) parameter value, it can be used for any key.
+//
+// The returned slice references the underlying message pased to [Parse].
+func (p *ResourceSVCBParser) RawValue() ([]byte, error) {
+	if !p.nextData {
+		return nil, errInvalidOperation
+	}
+	return p.rawValue(), nil
+}
+
+// Mandatory parses a single [SVCParamMandatory] parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyMandatory] key.
+func (p *ResourceSVCBParser) Mandatory() (SVCParamMandatory, error) {
+	raw, err := p.value(SVCParamKeyMandatory)
+	if err != nil {
+		return SVCParamMandatory{}, err
+	}
+	if len(raw) == 0 || len(raw)%2 != 0 {
+		return SVCParamMandatory{}, errInvalidDNSMessage
+	}
+	keys := make([]SVCParamKey, 0, len(raw)/2)
+	for ; len(raw) != 0; raw = raw[2:] {
+		key := SVCParamKey(unpackUint16(raw))
+		if key == SVCParamKeyMandatory || (len(keys) != 0 && key <= keys[len(keys)-1]) {
+			return SVCParamMandatory{}, errInvalidDNSMessage
+		}
+		keys = append(keys, key)
+	}
+	return SVCParamMandatory{Keys: keys}, nil
+}
+
+// ALPN parses a single [SVCParamALPN] parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyALPN] key.
+func (p *ResourceSVCBParser) ALPN() (SVCParamALPN, error) {
+	raw, err := p.value(SVCParamKeyALPN)
+	if err != nil {
+		return SVCParamALPN{}, err
+	}
+	if len(raw) == 0 {
+		return SVCParamALPN{}, errInvalidDNSMessage
+	}
+	var alpn [][]byte
+	for len(raw) != 0 {
+		length := int(raw[0])
+		if length == 0 || length > len(raw)-1 {
+			return SVCParamALPN{}, errInvalidDNSMessage
+		}
+		alpn = append(alpn, raw[1:1+length])
+		raw = raw[1+length:]
+	}
+	return SVCParamALPN{ALPN: alpn}, nil
+}
+
+// NoDefaultALPN parses a single "no-default-alpn" parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyNoDefaultALPN] key.
+func (p *ResourceSVCBParser) NoDefaultALPN() error {
+	raw, err := p.value(SVCParamKeyNoDefaultALPN)
+	if err != nil {
+		return err
+	}
+	if len(raw) != 0 {
+		return errInvalidDNSMessage
+	}
+	return nil
+}
+
+// Port parses a single [SVCParamPort] parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyPort] key.
+func (p *ResourceSVCBParser) Port() (SVCParamPort, error) {
+	raw, err := p.value(SVCParamKeyPort)
+	if err != nil {
+		return SVCParamPort{}, err
+	}
+	if len(raw) != 2 {
+		return SVCParamPort{}, errInvalidDNSMessage
+	}
+	return SVCParamPort{Port: unpackUint16(raw)}, nil
+}
+
+// IPv4Hint parses a single [SVCParamIPv4Hint] parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyIPv4Hint] key.
+func (p *ResourceSVCBParser) IPv4Hint() (SVCParamIPv4Hint, error) {
+	raw, err := p.value(SVCParamKeyIPv4Hint)
+	if err != nil {
+		return SVCParamIPv4Hint{}, err
+	}
+	if len(raw) == 0 || len(raw)%4 != 0 {
+		return SVCParamIPv4Hint{}, errInvalidDNSMessage
+	}
+	hints := make([][4]byte, 0, len(raw)/4)
+	for ; len(raw) != 0; raw = raw[4:] {
+		hints = append(hints, [4]byte(raw))
+	}
+	return SVCParamIPv4Hint{Hints: hints}, nil
+}
+
+// ECH parses a single [SVCParamECH] parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyECH] key.
+func (p *ResourceSVCBParser) ECH() (SVCParamECH, error) {
+	raw, err := p.value(SVCParamKeyECH)
+	if err != nil {
+		return SVCParamECH{}, err
+	}
+	return SVCParamECH{ECH: raw}, nil
+}
+
+// IPv6Hint parses a single [SVCParamIPv6Hint] parameter.
+//
+// Note: This function should only be called when the [ResourceSVCBParser.Key]
+// method returns a [SVCParamKeyIPv6Hint] key.
+func (p *ResourceSVCBParser) IPv6Hint() (SVCParamIPv6Hint, error) {
+	raw, err := p.value(SVCParamKeyIPv6Hint)
+	if err != nil {
+		return SVCParamIPv6Hint{}, err
+	}
+	if len(raw) == 0 || len(raw)%16 != 0 {
+		return SVCParamIPv6Hint{}, errInvalidDNSMessage
+	}
+	hints := make([][16]byte, 0, len(raw)/16)
+	for ; len(raw) != 0; raw = raw[16:] {
+		hints = append(hints, [16]byte(raw))
+	}
+	return SVCParamIPv6Hint{Hints: hints}, nil
+}
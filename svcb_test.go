@@ -0,0 +1,380 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestResourceSVCBBuilderAndParser(t *testing.T) {
+	svcb := ResourceSVCB{
+		Priority: 1,
+		Target:   MustParseName("svc.example.com"),
+		Params: []SVCParam{
+			&SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyALPN, SVCParamKeyPort}},
+			&SVCParamALPN{ALPN: [][]byte{[]byte("h2"), []byte("h3")}},
+			&SVCParamNoDefaultALPN{},
+			&SVCParamPort{Port: 8443},
+			&SVCParamIPv4Hint{Hints: [][4]byte{{192, 0, 2, 1}, {192, 0, 2, 2}}},
+			&SVCParamECH{ECH: []byte{1, 2, 3, 4, 5}},
+			&SVCParamIPv6Hint{Hints: [][16]byte{netip.MustParseAddr("2001:db8::1").As16()}},
+			&SVCParamRaw{Key: 65000, Value: []byte("custom")},
+		},
+	}
+
+	hdr := ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Class: ClassIN,
+		TTL:   3600,
+	}
+
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+	if err := b.ResourceSVCB(hdr, svcb); err != nil {
+		t.Fatalf("b.ResourceSVCB() unexpected error: %v", err)
+	}
+	if err := b.ResourceHTTPS(hdr, ResourceHTTPS(svcb)); err != nil {
+		t.Fatalf("b.ResourceHTTPS() unexpected error: %v", err)
+	}
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+
+	rhdr, err := p.ResourceHeader()
+	if err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+	if rhdr.Type != TypeSVCB {
+		t.Fatalf("rhdr.Type = %v, want: %v", rhdr.Type, TypeSVCB)
+	}
+	if _, err := p.ResourceHTTPS(); err != errInvalidOperation {
+		t.Fatalf("p.ResourceHTTPS() unexpected error: %v, want: %v", err, errInvalidOperation)
+	}
+	resSVCB, err := p.ResourceSVCB()
+	if err != nil {
+		t.Fatalf("p.ResourceSVCB() unexpected error: %v", err)
+	}
+	expectSVCB(t, "p.ResourceSVCB()", resSVCB, svcb)
+
+	rhdr, err = p.ResourceHeader()
+	if err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+	if rhdr.Type != TypeHTTPS {
+		t.Fatalf("rhdr.Type = %v, want: %v", rhdr.Type, TypeHTTPS)
+	}
+	resHTTPS, err := p.ResourceHTTPS()
+	if err != nil {
+		t.Fatalf("p.ResourceHTTPS() unexpected error: %v", err)
+	}
+	expectSVCB(t, "p.ResourceHTTPS()", ResourceSVCB(resHTTPS), svcb)
+
+	if err := p.End(); err != nil {
+		t.Fatalf("p.End() unexpected error: %v", err)
+	}
+
+	// The target name must not be compressed.
+	msg := b.Bytes()
+	target := nameAsSlice("svc.example.com")
+	if bytes.Count(msg, target) != 2 {
+		t.Fatalf("target name compressed in: %v", msg)
+	}
+}
+
+func expectSVCB(t *testing.T, prefix string, got, expect ResourceSVCB) {
+	if got.Priority != expect.Priority {
+		t.Errorf("%v.Priority = %v, want: %v", prefix, got.Priority, expect.Priority)
+	}
+	if !bytes.Equal(got.Target.asSlice(), expect.Target.asSlice()) {
+		t.Errorf("%v.Target = %v, want: %v", prefix, got.Target.String(), expect.Target.String())
+	}
+	if !reflect.DeepEqual(got.Params, expect.Params) {
+		t.Errorf("%v.Params = %#v, want: %#v", prefix, got.Params, expect.Params)
+	}
+}
+
+func TestResourceSVCBBuilderValidation(t *testing.T) {
+	hdr := ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Class: ClassIN,
+		TTL:   3600,
+	}
+
+	cases := []struct {
+		name   string
+		params []SVCParam
+		err    error
+	}{
+		{
+			name: "not increasing",
+			params: []SVCParam{
+				&SVCParamPort{Port: 443},
+				&SVCParamALPN{ALPN: [][]byte{[]byte("h2")}},
+			},
+			err: errSVCParamKeyOrder,
+		},
+		{
+			name: "duplicate",
+			params: []SVCParam{
+				&SVCParamPort{Port: 443},
+				&SVCParamPort{Port: 8443},
+			},
+			err: errSVCParamKeyOrder,
+		},
+		{
+			name: "mandatory key missing",
+			params: []SVCParam{
+				&SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyALPN, SVCParamKeyIPv4Hint}},
+				&SVCParamALPN{ALPN: [][]byte{[]byte("h2")}},
+				&SVCParamPort{Port: 443},
+			},
+			err: errSVCParamMandatoryUnmet,
+		},
+		{
+			name: "mandatory contains mandatory",
+			params: []SVCParam{
+				&SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyMandatory, SVCParamKeyPort}},
+				&SVCParamPort{Port: 443},
+			},
+			err: errInvalidSVCParam,
+		},
+		{
+			name: "mandatory not sorted",
+			params: []SVCParam{
+				&SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyPort, SVCParamKeyALPN}},
+				&SVCParamALPN{ALPN: [][]byte{[]byte("h2")}},
+				&SVCParamPort{Port: 443},
+			},
+			err: errInvalidSVCParam,
+		},
+		{
+			name: "raw mandatory key missing",
+			params: []SVCParam{
+				&SVCParamMandatory{Keys: []SVCParamKey{65000}},
+				&SVCParamRaw{Key: 65001},
+			},
+			err: errSVCParamMandatoryUnmet,
+		},
+		{
+			name:   "no-default-alpn without alpn",
+			params: []SVCParam{&SVCParamNoDefaultALPN{}},
+			err:    errInvalidSVCParam,
+		},
+		{
+			name:   "empty alpn id",
+			params: []SVCParam{&SVCParamALPN{ALPN: [][]byte{[]byte("h2"), {}}}},
+			err:    errInvalidSVCParam,
+		},
+		{
+			name:   "empty ipv4hint",
+			params: []SVCParam{&SVCParamIPv4Hint{}},
+			err:    errInvalidSVCParam,
+		},
+		{
+			name: "valid",
+			params: []SVCParam{
+				&SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyPort, 65000}},
+				&SVCParamPort{Port: 443},
+				&SVCParamRaw{Key: 65000},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		b := StartBuilder(make([]byte, 0, 512), 0, 0)
+		b.StartAnswers()
+		err := b.ResourceSVCB(hdr, ResourceSVCB{Priority: 1, Target: MustParseName("."), Params: tt.params})
+		if err != tt.err {
+			t.Errorf("%v: b.ResourceSVCB() unexpected error: %v, want: %v", tt.name, err, tt.err)
+		}
+		expectCount := uint16(0)
+		if tt.err == nil {
+			expectCount = 1
+		}
+		if b.Header().ANCount != expectCount {
+			t.Errorf("%v: b.Header().ANCount = %v, want: %v", tt.name, b.Header().ANCount, expectCount)
+		}
+		if expectCount == 0 && b.Length() != headerLen {
+			t.Errorf("%v: b.Length() = %v, want: %v", tt.name, b.Length(), headerLen)
+		}
+	}
+}
+
+func TestResourceSVCBBuilderIncremental(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+
+	svcbb, err := b.ResourceHTTPSBuilder(ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Class: ClassIN,
+		TTL:   3600,
+	}, 1, MustParseName("."))
+	if err != nil {
+		t.Fatalf("b.ResourceHTTPSBuilder() unexpected error: %v", err)
+	}
+
+	if len(b.Bytes()) != headerLen || b.Header() != *new(Header) {
+		t.Fatalf("changes caused by ResourceSVCBBuilder visible before End()")
+	}
+
+	if err := svcbb.Mandatory(SVCParamMandatory{Keys: []SVCParamKey{SVCParamKeyPort}}); err != nil {
+		t.Fatalf("svcbb.Mandatory() unexpected error: %v", err)
+	}
+
+	if err := svcbb.End(); err != errSVCParamMandatoryUnmet {
+		t.Fatalf("svcbb.End() unexpected error: %v, want: %v", err, errSVCParamMandatoryUnmet)
+	}
+
+	if err := svcbb.RawParam(SVCParamKeyALPN, []byte{2, 'h', '2'}); err != nil {
+		t.Fatalf("svcbb.RawParam() unexpected error: %v", err)
+	}
+
+	if err := svcbb.Port(SVCParamPort{Port: 443}); err != nil {
+		t.Fatalf("svcbb.Port() unexpected error: %v", err)
+	}
+
+	if err := svcbb.RawParam(SVCParamKeyPort, []byte{1, 2}); err != errSVCParamKeyOrder {
+		t.Fatalf("svcbb.RawParam() unexpected error: %v, want: %v", err, errSVCParamKeyOrder)
+	}
+
+	if err := svcbb.RawParam(65535, []byte{1, 2}); err != nil {
+		t.Fatalf("svcbb.RawParam() unexpected error: %v", err)
+	}
+
+	if err := svcbb.End(); err != nil {
+		t.Fatalf("svcbb.End() unexpected error: %v", err)
+	}
+
+	if b.Header().ANCount != 1 {
+		t.Fatalf("b.Header().ANCount = %v, want: 1", b.Header().ANCount)
+	}
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+	if _, err := p.ResourceHeader(); err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+
+	svcbp, err := p.ResourceSVCBParser()
+	if err != nil {
+		t.Fatalf("p.ResourceSVCBParser() unexpected error: %v", err)
+	}
+
+	if svcbp.Priority() != 1 {
+		t.Fatalf("svcbp.Priority() = %v, want: 1", svcbp.Priority())
+	}
+	expectParserName(t, "svcbp.Target()", svcbp.Target(), ".", false)
+
+	expectKeys := []SVCParamKey{SVCParamKeyMandatory, SVCParamKeyALPN, SVCParamKeyPort, 65535}
+	for i, expectKey := range expectKeys {
+		key, err := svcbp.Key()
+		if err != nil {
+			t.Fatalf("%v: svcbp.Key() unexpected error: %v", i, err)
+		}
+		if key != expectKey {
+			t.Fatalf("%v: svcbp.Key() = %v, want: %v", i, key, expectKey)
+		}
+		if _, err := svcbp.Key(); err != errInvalidOperation {
+			t.Fatalf("%v: svcbp.Key() unexpected error: %v, want: %v", i, err, errInvalidOperation)
+		}
+
+		switch key {
+		case SVCParamKeyALPN:
+			if _, err := svcbp.Port(); err != errInvalidOperation {
+				t.Fatalf("svcbp.Port() unexpected error: %v, want: %v", err, errInvalidOperation)
+			}
+			alpn, err := svcbp.ALPN()
+			if err != nil {
+				t.Fatalf("svcbp.ALPN() unexpected error: %v", err)
+			}
+			if len(alpn.ALPN) != 1 || string(alpn.ALPN[0]) != "h2" {
+				t.Fatalf("svcbp.ALPN() = %q, want: [h2]", alpn.ALPN)
+			}
+		case SVCParamKeyPort:
+			port, err := svcbp.Port()
+			if err != nil {
+				t.Fatalf("svcbp.Port() unexpected error: %v", err)
+			}
+			if port.Port != 443 {
+				t.Fatalf("svcbp.Port() = %v, want: 443", port.Port)
+			}
+		case 65535:
+			raw, err := svcbp.RawValue()
+			if err != nil {
+				t.Fatalf("svcbp.RawValue() unexpected error: %v", err)
+			}
+			if !bytes.Equal(raw, []byte{1, 2}) {
+				t.Fatalf("svcbp.RawValue() = %v, want: %v", raw, []byte{1, 2})
+			}
+		default:
+			if err := svcbp.Skip(); err != nil {
+				t.Fatalf("svcbp.Skip() unexpected error: %v", err)
+			}
+		}
+	}
+
+	if _, err := svcbp.Key(); err != ErrSectionDone {
+		t.Fatalf("svcbp.Key() unexpected error: %v, want: %v", err, ErrSectionDone)
+	}
+
+	if err := p.End(); err != nil {
+		t.Fatalf("p.End() unexpected error: %v", err)
+	}
+}
+
+func TestParserResourceSVCBInvalidKeyOrder(t *testing.T) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+
+	rdb, err := b.RDBuilder(ResourceHeader{
+		Name:  MustParseName("example.com"),
+		Type:  TypeSVCB,
+		Class: ClassIN,
+	})
+	if err != nil {
+		t.Fatalf("b.RDBuilder() unexpected error: %v", err)
+	}
+	rdb.Uint16(1)
+	rdb.Name(MustParseName("."), false)
+	rdb.Uint16(uint16(SVCParamKeyPort))
+	rdb.Uint16(2)
+	rdb.Uint16(443)
+	rdb.Uint16(uint16(SVCParamKeyALPN))
+	rdb.Uint16(3)
+	rdb.Bytes([]byte{2, 'h', '2'})
+	rdb.End()
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if err := p.StartAnswers(); err != nil {
+		t.Fatalf("p.StartAnswers() unexpected error: %v", err)
+	}
+	if _, err := p.ResourceHeader(); err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+
+	if _, err := p.ResourceSVCB(); err != errInvalidDNSMessage {
+		t.Fatalf("p.ResourceSVCB() unexpected error: %v, want: %v", err, errInvalidDNSMessage)
+	}
+
+	if err := p.SkipResourceData(); err != nil {
+		t.Fatalf("p.SkipResourceData() unexpected error: %v", err)
+	}
+
+	if err := p.End(); err != nil {
+		t.Fatalf("p.End() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,485 @@
+package dnsmsg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"math"
+	"time"
+)
+
+// TSIGAlgorithm is a HMAC algorithm used by TSIG.
+type TSIGAlgorithm uint8
+
+const (
+	TSIGAlgorithmHMACSHA256 TSIGAlgorithm = iota + 1
+	TSIGAlgorithmHMACSHA384
+	TSIGAlgorithmHMACSHA512
+)
+
+var (
+	tsigHMACSHA256Name = MustParseName("hmac-sha256")
+	tsigHMACSHA384Name = MustParseName("hmac-sha384")
+	tsigHMACSHA512Name = MustParseName("hmac-sha512")
+)
+
+// Name returns the algorithm name, as used in the Algorithm field of the TSIG resource.
+func (a TSIGAlgorithm) Name() Name {
+	switch a {
+	case TSIGAlgorithmHMACSHA256:
+		return tsigHMACSHA256Name
+	case TSIGAlgorithmHMACSHA384:
+		return tsigHMACSHA384Name
+	case TSIGAlgorithmHMACSHA512:
+		return tsigHMACSHA512Name
+	}
+	return Name{}
+}
+
+func (a TSIGAlgorithm) hash() func() hash.Hash {
+	switch a {
+	case TSIGAlgorithmHMACSHA256:
+		return sha256.New
+	case TSIGAlgorithmHMACSHA384:
+		return sha512.New384
+	case TSIGAlgorithmHMACSHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+// Extended RCodes used by TSIG (RFC 8945, Section 3).
+const (
+	ExtendedRCodeBADSIG   ExtendedRCode = 16
+	ExtendedRCodeBADKEY   ExtendedRCode = 17
+	ExtendedRCodeBADTIME  ExtendedRCode = 18
+	ExtendedRCodeBADTRUNC ExtendedRCode = 22
+)
+
+// ResourceTSIG is a TSIG resource defined in RFC 8945.
+type ResourceTSIG struct {
+	// Algorithm is never compressed by the [Builder],
+	// but the [Parser] accepts compressed names.
+	Algorithm Name
+
+	// TimeSigned is a 48-bit unsigned integer, seconds since the Unix epoch.
+	TimeSigned uint64
+	Fudge      uint16
+	MAC        []byte
+	OriginalID uint16
+	Error      ExtendedRCode
+	OtherData  []byte
+}
+
+var (
+	// ErrTSIGBadSig is returned by [TSIG.Verify] when the MAC of the TSIG resource
+	// is invalid, or when the message contains a TSIG with the BADSIG error.
+	ErrTSIGBadSig = errors.New("tsig: bad signature")
+
+	// ErrTSIGBadKey is returned by [TSIG.Verify] when the message was signed with an
+	// unknown key or algorithm, or when the message contains a TSIG with the BADKEY error.
+	ErrTSIGBadKey = errors.New("tsig: bad key")
+
+	// ErrTSIGBadTime is returned by [TSIG.Verify] when the time signed is outside of
+	// the allowed fudge, or when the message contains a TSIG with the BADTIME error.
+	ErrTSIGBadTime = errors.New("tsig: bad time")
+
+	// ErrTSIGMissing is returned by [TSIG.Verify] when the message is not signed.
+	ErrTSIGMissing = errors.New("tsig: message is not signed")
+
+	errTSIGTooManyUnsigned = errors.New("tsig: too many unsigned messages")
+
+	errTransactionSignatureMissing         = errors.New("message is not signed")
+	errTransactionSignatureNotLast         = errors.New("transaction signature is not the last resource in the additional section")
+	errTransactionSignatureInvalidResource = errors.New("invalid transaction signature resource header")
+)
+
+// ResourceTSIG parses a single TSIG resouce data.
+//
+// This method can only be used after [Parser.ResourceHeader]
+// returns a [ResourceHeader] with a Type field equal to [TypeTSIG].
+func (m *Parser) ResourceTSIG() (ResourceTSIG, error) {
+	if !m.resourceData || m.nextResourceType != TypeTSIG {
+		return ResourceTSIG{}, errInvalidOperation
+	}
+
+	rdata, err := m.rData()
+	if err != nil {
+		return ResourceTSIG{}, err
+	}
+
+	var tsig ResourceTSIG
+	nameLength, err := tsig.Algorithm.unpack(m.msg, m.curOffset)
+	if err != nil {
+		return ResourceTSIG{}, err
+	}
+
+	if int(nameLength) > len(rdata) {
+		return ResourceTSIG{}, errInvalidDNSMessage
+	}
+	rdata = rdata[nameLength:]
+
+	if len(rdata) < 10 {
+		return ResourceTSIG{}, errInvalidDNSMessage
+	}
+	tsig.TimeSigned = uint64(unpackUint16(rdata))<<32 | uint64(unpackUint32(rdata[2:]))
+	tsig.Fudge = unpackUint16(rdata[6:])
+	macLength := int(unpackUint16(rdata[8:]))
+	rdata = rdata[10:]
+
+	if len(rdata) < macLength+6 {
+		return ResourceTSIG{}, errInvalidDNSMessage
+	}
+	tsig.MAC = rdata[:macLength]
+	rdata = rdata[macLength:]
+	tsig.OriginalID = unpackUint16(rdata)
+	tsig.Error = ExtendedRCode(unpackUint16(rdata[2:]))
+	otherLength := int(unpackUint16(rdata[4:]))
+	rdata = rdata[6:]
+
+	if len(rdata) != otherLength {
+		return ResourceTSIG{}, errInvalidDNSMessage
+	}
+	tsig.OtherData = rdata
+
+	m.resourceData = false
+	m.curOffset += int(m.nextResourceDataLength)
+	return tsig, nil
+}
+
+func (b *Builder) resourceTSIG(hdr ResourceHeader, tsig *ResourceTSIG) error {
+	if int(tsig.Algorithm.Length)+16+len(tsig.MAC)+len(tsig.OtherData) > math.MaxUint16 {
+		return errResourceTooLong
+	}
+	f, hdrOffset, err := b.appendHeaderWithLengthFixup(hdr, b.maxBufSize)
+	if err != nil {
+		return err
+	}
+	b.buf, err = b.nb.appendName(b.buf, b.maxBufSize-16-len(tsig.MAC)-len(tsig.OtherData), b.headerStartOffset, tsig.Algorithm.asSlice(), false)
+	if err != nil {
+		return b.removeResourceHeader(hdrOffset, err)
+	}
+	b.buf = appendTSIGTimers(b.buf, tsig)
+	b.buf = appendUint16(b.buf, uint16(len(tsig.MAC)))
+	b.buf = append(b.buf, tsig.MAC...)
+	b.buf = appendUint16(b.buf, tsig.OriginalID)
+	b.buf = appendUint16(b.buf, uint16(tsig.Error))
+	b.buf = appendUint16(b.buf, uint16(len(tsig.OtherData)))
+	b.buf = append(b.buf, tsig.OtherData...)
+	f.fixup(b)
+	return nil
+}
+
+func appendTSIGTimers(dst []byte, tsig *ResourceTSIG) []byte {
+	dst = appendUint16(dst, uint16(tsig.TimeSigned>>32))
+	dst = appendUint32(dst, uint32(tsig.TimeSigned))
+	return appendUint16(dst, tsig.Fudge)
+}
+
+// TSIGKey is a shared secret key used by TSIG.
+type TSIGKey struct {
+	Name      Name
+	Algorithm TSIGAlgorithm
+	Secret    []byte
+}
+
+// TSIG signs and verifies messages of a single DNS transaction using TSIG (RFC 8945).
+//
+// The TSIG keeps track of the MAC of the last signed or verified message, so the same TSIG
+// should be used to sign a request and verify the response to that request (or verify a
+// request and sign the response). Multiple responses to a single request (multi-message
+// TCP streams, like zone transfers) are also supported, by calling Sign or Verify for each response.
+// The first message of a transaction is always signed or verified without a previous MAC.
+type TSIG struct {
+	Key TSIGKey
+
+	// Fudge is the permitted time difference (in seconds), used by Sign.
+	// When zero, 300 seconds is used.
+	Fudge uint16
+
+	// Now returns the current time. When nil, [time.Now] is used.
+	Now func() time.Time
+
+	prevMAC        []byte
+	signedMessages int
+	unsigned       []byte
+	unsignedCount  int
+}
+
+func (t *TSIG) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
+
+// MAC returns the MAC of the last signed or verified message.
+func (t *TSIG) MAC() []byte {
+	return t.prevMAC
+}
+
+func (t *TSIG) mac(msg []byte, tsig *ResourceTSIG) []byte {
+	h := hmac.New(t.Key.Algorithm.hash(), t.Key.Secret)
+
+	var buf [2 + 255 + 8]byte
+	if t.signedMessages != 0 {
+		h.Write(appendUint16(buf[:0], uint16(len(t.prevMAC))))
+		h.Write(t.prevMAC)
+	}
+
+	h.Write(t.unsigned)
+	h.Write(msg)
+
+	if t.signedMessages >= 2 {
+		// Subsequent messages of a multi-message transaction, only the timers are included.
+		h.Write(appendTSIGTimers(buf[:0], tsig))
+		return h.Sum(nil)
+	}
+
+	name := t.Key.Name
+	name.lowerASCII()
+	h.Write(name.asSlice())
+	h.Write(appendUint32(appendUint16(buf[:0], uint16(ClassANY)), 0))
+
+	alg := tsig.Algorithm
+	alg.lowerASCII()
+	h.Write(alg.asSlice())
+
+	h.Write(appendTSIGTimers(buf[:0], tsig))
+	h.Write(appendUint16(buf[:0], uint16(tsig.Error)))
+	h.Write(appendUint16(buf[:0], uint16(len(tsig.OtherData))))
+	h.Write(tsig.OtherData)
+	return h.Sum(nil)
+}
+
+// Sign signs the message built by b and appends the TSIG resource to the additional section.
+// It changes the building section to additionals (when needed), after calling Sign
+// no other resources should be appended to the message.
+//
+// It returns [ErrTruncated] when the TSIG resource does not fit in the size limit set by [Builder.LimitMessageSize].
+func (t *TSIG) Sign(b *Builder) error {
+	if t.Key.Algorithm.hash() == nil {
+		return ErrTSIGBadKey
+	}
+
+	b.startTransactionSignature()
+
+	fudge := t.Fudge
+	if fudge == 0 {
+		fudge = 300
+	}
+
+	tsig := ResourceTSIG{
+		Algorithm:  t.Key.Algorithm.Name(),
+		TimeSigned: uint64(t.now().Unix()) & (1<<48 - 1),
+		Fudge:      fudge,
+		OriginalID: b.hdr.ID,
+	}
+
+	tsig.MAC = t.mac(b.Bytes()[b.headerStartOffset:], &tsig)
+
+	err := b.resourceTSIG(ResourceHeader{
+		Name:  t.Key.Name,
+		Type:  TypeTSIG,
+		Class: ClassANY,
+	}, &tsig)
+	if err != nil {
+		return err
+	}
+
+	t.prevMAC = tsig.MAC
+	t.signedMessages++
+	t.unsigned = t.unsigned[:0]
+	t.unsignedCount = 0
+	return nil
+}
+
+// Verify verifies the TSIG resource of msg.
+//
+// In multi-message transactions, messages (except the first two: request
+// and the first response) might be unsigned, Verify accepts up to 99 unsigned
+// messages in a row, the next signed message covers all of them.
+func (t *TSIG) Verify(msg []byte) error {
+	if t.Key.Algorithm.hash() == nil {
+		return ErrTSIGBadKey
+	}
+
+	tsigOffset, tsigHdr, p, err := findTransactionSignature(msg, TypeTSIG)
+	if err != nil {
+		if err == errTransactionSignatureMissing {
+			if t.signedMessages >= 2 {
+				return t.Unsigned(msg)
+			}
+			return ErrTSIGMissing
+		}
+		return err
+	}
+	tsig, err := p.ResourceTSIG()
+	if err != nil {
+		return err
+	}
+	if err := p.End(); err != nil {
+		return err
+	}
+
+	if len(tsig.MAC) == 0 {
+		switch tsig.Error {
+		case ExtendedRCodeBADKEY:
+			return ErrTSIGBadKey
+		case ExtendedRCodeBADTIME:
+			return ErrTSIGBadTime
+		}
+		return ErrTSIGBadSig
+	}
+
+	expectAlg := t.Key.Algorithm.Name()
+	if !tsigHdr.Name.Equal(&t.Key.Name) || !tsig.Algorithm.Equal(&expectAlg) {
+		return ErrTSIGBadKey
+	}
+
+	stripped := stripTransactionSignature(msg, tsigOffset)
+	packUint16(stripped, tsig.OriginalID)
+
+	expectMAC := t.mac(stripped, &tsig)
+
+	// RFC 8945, Section 5.2.2.1.
+	minLength := len(expectMAC) / 2
+	if minLength < 10 {
+		minLength = 10
+	}
+	if len(tsig.MAC) > len(expectMAC) || len(tsig.MAC) < minLength {
+		return ErrTSIGBadSig
+	}
+	if !hmac.Equal(expectMAC[:len(tsig.MAC)], tsig.MAC) {
+		return ErrTSIGBadSig
+	}
+
+	switch tsig.Error {
+	case 0:
+	case ExtendedRCodeBADTIME:
+		return ErrTSIGBadTime
+	case ExtendedRCodeBADKEY:
+		return ErrTSIGBadKey
+	default:
+		return ErrTSIGBadSig
+	}
+
+	now := uint64(t.now().Unix())
+	if (now > tsig.TimeSigned && now-tsig.TimeSigned > uint64(tsig.Fudge)) ||
+		(tsig.TimeSigned > now && tsig.TimeSigned-now > uint64(tsig.Fudge)) {
+		return ErrTSIGBadTime
+	}
+
+	t.prevMAC = append(t.prevMAC[:0], tsig.MAC...)
+	t.signedMessages++
+	t.unsigned = t.unsigned[:0]
+	t.unsignedCount = 0
+	return nil
+}
+
+// Unsigned records an unsigned message of a multi-message transaction,
+// the next signed message (by Sign or Verify) covers all unsigned messages.
+// Only messages after the first response can be unsigned, up to 99 in a row.
+//
+// Verify calls Unsigned automatically for unsigned messages, so this method is only
+// needed when sending unsigned messages (without calling [TSIG.Sign]).
+func (t *TSIG) Unsigned(msg []byte) error {
+	if t.signedMessages < 2 {
+		return ErrTSIGMissing
+	}
+	if t.unsignedCount == 99 {
+		return errTSIGTooManyUnsigned
+	}
+	t.unsigned = append(t.unsigned, msg...)
+	t.unsignedCount++
+	return nil
+}
+
+// startTransactionSignature changes the building section to additionals (when needed),
+// so that a transaction signature (TSIG or SIG(0)) can be appended.
+func (b *Builder) startTransactionSignature() {
+	switch b.curSection {
+	case sectionQuestions:
+		b.StartAnswers()
+		fallthrough
+	case sectionAnswers:
+		b.StartAuthorities()
+		fallthrough
+	case sectionAuthorities:
+		b.StartAdditionals()
+	case sectionAdditionals:
+	default:
+		b.panicInvalidSection()
+	}
+}
+
+// findTransactionSignature finds the last resource in the additional section of msg with the typ
+// type (TSIG or SIG(0)), it returns the offset of the resource header and the Parser positioned
+// at the resource data of that resource.
+func findTransactionSignature(msg []byte, typ Type) (int, ResourceHeader, Parser, error) {
+	p, hdr, err := Parse(msg)
+	if err != nil {
+		return 0, ResourceHeader{}, Parser{}, err
+	}
+	if err := p.SkipQuestions(); err != nil {
+		return 0, ResourceHeader{}, Parser{}, err
+	}
+	for _, nextSection := range []func() error{p.StartAnswers, p.StartAuthorities} {
+		if err := nextSection(); err != nil {
+			return 0, ResourceHeader{}, Parser{}, err
+		}
+		for {
+			rhdr, err := p.ResourceHeader()
+			if err != nil {
+				if err == ErrSectionDone {
+					break
+				}
+				return 0, ResourceHeader{}, Parser{}, err
+			}
+			if rhdr.Type == typ {
+				return 0, ResourceHeader{}, Parser{}, errTransactionSignatureNotLast
+			}
+			if err := p.SkipResourceData(); err != nil {
+				return 0, ResourceHeader{}, Parser{}, err
+			}
+		}
+	}
+
+	if err := p.StartAdditionals(); err != nil {
+		return 0, ResourceHeader{}, Parser{}, err
+	}
+
+	for i := 0; ; i++ {
+		offset := p.curOffset
+		rhdr, err := p.ResourceHeader()
+		if err != nil {
+			if err == ErrSectionDone {
+				return 0, ResourceHeader{}, Parser{}, errTransactionSignatureMissing
+			}
+			return 0, ResourceHeader{}, Parser{}, err
+		}
+		if rhdr.Type != typ {
+			if err := p.SkipResourceData(); err != nil {
+				return 0, ResourceHeader{}, Parser{}, err
+			}
+			continue
+		}
+		if i != int(hdr.ARCount)-1 {
+			return 0, ResourceHeader{}, Parser{}, errTransactionSignatureNotLast
+		}
+		if rhdr.Class != ClassANY || rhdr.TTL != 0 {
+			return 0, ResourceHeader{}, Parser{}, errTransactionSignatureInvalidResource
+		}
+		return offset, rhdr, p, nil
+	}
+}
+
+// stripTransactionSignature returns a copy of msg[:offset], with the ARCOUNT decremented by one.
+func stripTransactionSignature(msg []byte, offset int) []byte {
+	stripped := make([]byte, offset)
+	copy(stripped, msg)
+	packUint16(stripped[10:], unpackUint16(stripped[10:])-1)
+	return stripped
+}
@@ -0,0 +1,197 @@
+package dnsmsg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+	"time"
+)
+
+func testTSIGMessage(t *testing.T, id uint16, answers int) *Builder {
+	b := new(Builder)
+	*b = StartBuilder(make([]byte, 0, 512), id, 0)
+	if err := b.Question(Question{Name: MustParseName("example.com"), Type: TypeSOA, Class: ClassIN}); err != nil {
+		t.Fatalf("b.Question() unexpected error: %v", err)
+	}
+	b.StartAnswers()
+	for i := 0; i < answers; i++ {
+		if err := b.ResourceA(ResourceHeader{Name: MustParseName("www.example.com"), Class: ClassIN, TTL: 60}, ResourceA{A: [4]byte{192, 0, 2, byte(i)}}); err != nil {
+			t.Fatalf("b.ResourceA() unexpected error: %v", err)
+		}
+	}
+	return b
+}
+
+func TestTSIGSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	key := TSIGKey{
+		Name:      MustParseName("key.example.com"),
+		Algorithm: TSIGAlgorithmHMACSHA256,
+		Secret:    []byte("secret key"),
+	}
+
+	client := TSIG{Key: key, Now: func() time.Time { return now }}
+	server := TSIG{Key: key, Now: func() time.Time { return now.Add(10 * time.Second) }}
+
+	b := testTSIGMessage(t, 1234, 0)
+	if err := client.Sign(b); err != nil {
+		t.Fatalf("client.Sign() unexpected error: %v", err)
+	}
+	req := b.Bytes()
+
+	if hdr := b.Header(); hdr.ARCount != 1 {
+		t.Fatalf("b.Header().ARCount = %v, want: 1", hdr.ARCount)
+	}
+
+	// Independently compute the MAC of the request.
+	unsigned := testTSIGMessage(t, 1234, 0)
+	h := hmac.New(sha256.New, key.Secret)
+	h.Write(unsigned.Bytes())
+	h.Write(nameAsSlice("key.example.com"))
+	h.Write([]byte{0, 255, 0, 0, 0, 0})
+	h.Write(nameAsSlice("hmac-sha256"))
+	h.Write([]byte{0, 0})
+	h.Write(appendUint32(nil, uint32(now.Unix())))
+	h.Write([]byte{1, 44, 0, 0, 0, 0})
+	if expect := h.Sum(nil); !hmac.Equal(expect, client.MAC()) {
+		t.Fatalf("client.MAC() = %v, want: %v", client.MAC(), expect)
+	}
+
+	p, _, err := Parse(req)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	p.SkipQuestions()
+	p.StartAnswers()
+	p.StartAuthorities()
+	p.StartAdditionals()
+	hdr, err := p.ResourceHeader()
+	if err != nil {
+		t.Fatalf("p.ResourceHeader() unexpected error: %v", err)
+	}
+	if hdr.Type != TypeTSIG || hdr.Class != ClassANY || hdr.TTL != 0 {
+		t.Fatalf("unexpected TSIG resource header: %#v", hdr)
+	}
+	tsig, err := p.ResourceTSIG()
+	if err != nil {
+		t.Fatalf("p.ResourceTSIG() unexpected error: %v", err)
+	}
+	expectTSIG := ResourceTSIG{
+		Algorithm:  MustParseName("hmac-sha256"),
+		TimeSigned: uint64(now.Unix()),
+		Fudge:      300,
+		MAC:        client.MAC(),
+		OriginalID: 1234,
+	}
+	equalRData(t, "p.ResourceTSIG()", expectTSIG, tsig)
+
+	// Changed ID (e.g. by a forwarder) must not affect the MAC.
+	packUint16(req, 4321)
+	if err := server.Verify(req); err != nil {
+		t.Fatalf("server.Verify() unexpected error: %v", err)
+	}
+
+	// Multi-message response, with one unsigned message.
+	for i := 0; i < 4; i++ {
+		b := testTSIGMessage(t, 1234, i+1)
+		if i == 2 {
+			if err := server.Unsigned(b.Bytes()); err != nil {
+				t.Fatalf("%v: server.Unsigned() unexpected error: %v", i, err)
+			}
+			if err := client.Verify(b.Bytes()); err != nil {
+				t.Fatalf("%v: client.Verify() of unsigned message unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err := server.Sign(b); err != nil {
+			t.Fatalf("%v: server.Sign() unexpected error: %v", i, err)
+		}
+		if err := client.Verify(b.Bytes()); err != nil {
+			t.Fatalf("%v: client.Verify() unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestTSIGVerifyErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	key := TSIGKey{
+		Name:      MustParseName("key.example.com"),
+		Algorithm: TSIGAlgorithmHMACSHA512,
+		Secret:    []byte("secret key"),
+	}
+
+	sign := func() []byte {
+		client := TSIG{Key: key, Fudge: 10, Now: func() time.Time { return now }}
+		b := testTSIGMessage(t, 1, 2)
+		if err := client.Sign(b); err != nil {
+			t.Fatalf("client.Sign() unexpected error: %v", err)
+		}
+		return b.Bytes()
+	}
+
+	verifyAt := func(k TSIGKey, at time.Time, msg []byte) error {
+		server := TSIG{Key: k, Now: func() time.Time { return at }}
+		return server.Verify(msg)
+	}
+
+	if err := verifyAt(key, now.Add(11*time.Second), sign()); err != ErrTSIGBadTime {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGBadTime)
+	}
+	if err := verifyAt(key, now.Add(-11*time.Second), sign()); err != ErrTSIGBadTime {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGBadTime)
+	}
+	if err := verifyAt(key, now.Add(10*time.Second), sign()); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+
+	otherKey := key
+	otherKey.Secret = []byte("other secret")
+	if err := verifyAt(otherKey, now, sign()); err != ErrTSIGBadSig {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGBadSig)
+	}
+
+	otherKey = key
+	otherKey.Name = MustParseName("other.example.com")
+	if err := verifyAt(otherKey, now, sign()); err != ErrTSIGBadKey {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGBadKey)
+	}
+
+	otherKey = key
+	otherKey.Algorithm = TSIGAlgorithmHMACSHA384
+	if err := verifyAt(otherKey, now, sign()); err != ErrTSIGBadKey {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGBadKey)
+	}
+
+	msg := sign()
+	msg[3] ^= 1
+	if err := verifyAt(key, now, msg); err != ErrTSIGBadSig {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGBadSig)
+	}
+
+	if err := verifyAt(key, now, testTSIGMessage(t, 1, 2).Bytes()); err != ErrTSIGMissing {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, ErrTSIGMissing)
+	}
+
+	// TSIG followed by another resource.
+	b := testTSIGMessage(t, 1, 0)
+	client := TSIG{Key: key, Now: func() time.Time { return now }}
+	if err := client.Sign(b); err != nil {
+		t.Fatalf("client.Sign() unexpected error: %v", err)
+	}
+	b.ResourceA(ResourceHeader{Name: MustParseName("example.com"), Class: ClassIN}, ResourceA{})
+	if err := verifyAt(key, now, b.Bytes()); err != errTransactionSignatureNotLast {
+		t.Errorf("Verify() unexpected error: %v, want: %v", err, errTransactionSignatureNotLast)
+	}
+}
+
+func TestTSIGSignTruncated(t *testing.T) {
+	b := testTSIGMessage(t, 1, 0)
+	b.LimitMessageSize(b.Length() + 20)
+	tsig := TSIG{Key: TSIGKey{Name: MustParseName("key"), Algorithm: TSIGAlgorithmHMACSHA256, Secret: []byte("a")}}
+	if err := tsig.Sign(b); err != ErrTruncated {
+		t.Fatalf("tsig.Sign() unexpected error: %v, want: %v", err, ErrTruncated)
+	}
+	if hdr := b.Header(); hdr.ARCount != 0 {
+		t.Fatalf("b.Header().ARCount = %v, want: 0", hdr.ARCount)
+	}
+}
@@ -23,25 +23,73 @@ func (t Type) String() string {
 		return "MX"
 	case TypeTXT:
 		return "TXT"
+	case TypeSIG:
+		return "SIG"
 	case TypeAAAA:
 		return "AAAA"
+	case TypeSRV:
+		return "SRV"
+	case TypeDNAME:
+		return "DNAME"
+	case TypeSVCB:
+		return "SVCB"
+	case TypeHTTPS:
+		return "HTTPS"
+	case TypeCAA:
+		return "CAA"
+	case TypeTSIG:
+		return "TSIG"
 	case TypeOPT:
 		return "OPT"
+	case TypeDS:
+		return "DS"
+	case TypeRRSIG:
+		return "RRSIG"
+	case TypeNSEC:
+		return "NSEC"
+	case TypeDNSKEY:
+		return "DNSKEY"
+	case TypeNSEC3:
+		return "NSEC3"
+	case TypeNSEC3PARAM:
+		return "NSEC3PARAM"
+	case TypeCDS:
+		return "CDS"
+	case TypeCDNSKEY:
+		return "CDNSKEY"
 	default:
-		return "0x" + strconv.FormatInt(int64(t), 16)
+		if r, ok := lookupResourceType(t); ok {
+			return r.name
+		}
+		return "TYPE" + strconv.FormatInt(int64(t), 10)
 	}
 }
 
 const (
-	TypeA     Type = 1
-	TypeNS    Type = 2
-	TypeCNAME Type = 5
-	TypeSOA   Type = 6
-	TypePTR   Type = 12
-	TypeMX    Type = 15
-	TypeTXT   Type = 16
-	TypeAAAA  Type = 28
-	TypeOPT   Type = 41
+	TypeA          Type = 1
+	TypeNS         Type = 2
+	TypeCNAME      Type = 5
+	TypeSOA        Type = 6
+	TypePTR        Type = 12
+	TypeMX         Type = 15
+	TypeTXT        Type = 16
+	TypeSIG        Type = 24
+	TypeAAAA       Type = 28
+	TypeSRV        Type = 33
+	TypeDNAME      Type = 39
+	TypeOPT        Type = 41
+	TypeDS         Type = 43
+	TypeRRSIG      Type = 46
+	TypeNSEC       Type = 47
+	TypeDNSKEY     Type = 48
+	TypeNSEC3      Type = 50
+	TypeNSEC3PARAM Type = 51
+	TypeCDS        Type = 59
+	TypeCDNSKEY    Type = 60
+	TypeSVCB       Type = 64
+	TypeHTTPS      Type = 65
+	TypeTSIG       Type = 250
+	TypeCAA        Type = 257
 )
 
 type Class uint16
@@ -50,13 +98,16 @@ func (t Class) String() string {
 	switch t {
 	case ClassIN:
 		return "IN"
+	case ClassANY:
+		return "ANY"
 	default:
-		return "0x" + strconv.FormatInt(int64(t), 16)
+		return "CLASS" + strconv.FormatInt(int64(t), 10)
 	}
 }
 
 const (
-	ClassIN Class = 1
+	ClassIN  Class = 1
+	ClassANY Class = 255
 )
 
 type Bit uint8
@@ -92,6 +143,8 @@ func (r RCode) String() string {
 		return "not implemented"
 	case RCodeRefused:
 		return "refused"
+	case RCodeYXDomain:
+		return "name exists"
 	default:
 		return "0x" + strconv.FormatInt(int64(r), 16)
 	}
@@ -104,6 +157,7 @@ const (
 	RCodeNameError
 	RCodeNotImpl
 	RCodeRefused
+	RCodeYXDomain
 )
 
 type Flags uint16
@@ -225,6 +279,10 @@ type ResourcePTR struct {
 	PTR Name
 }
 
+type ResourceDNAME struct {
+	DNAME Name
+}
+
 type ResourceMX struct {
 	MX   Name
 	Pref uint16
@@ -243,7 +301,7 @@ type RawResourceTXT struct {
 func (r RawResourceTXT) ToResourceTXT() ResourceTXT {
 	var txts [][]byte
 	for i := 0; i < len(r.TXT); i += int(r.TXT[i]) + 1 {
-		txts = append(txts, r.TXT[i:i+int(r.TXT[i])])
+		txts = append(txts, r.TXT[i+1:i+1+int(r.TXT[i])])
 	}
 	return ResourceTXT{TXT: txts}
 }
@@ -261,7 +319,7 @@ func (txt RawResourceTXT) isValid() bool {
 func (r RawResourceTXT) concatLength() int {
 	length := 0
 	for i := 0; i < len(r.TXT); i += int(r.TXT[i]) + 1 {
-		length += len(r.TXT[i : i+int(r.TXT[i])])
+		length += int(r.TXT[i])
 	}
 	return length
 }
@@ -269,7 +327,7 @@ func (r RawResourceTXT) concatLength() int {
 func (r RawResourceTXT) Concat() []byte {
 	buf := make([]byte, 0, r.concatLength())
 	for i := 0; i < len(r.TXT); i += int(r.TXT[i]) + 1 {
-		buf = append(buf, r.TXT[i:i+int(r.TXT[i])]...)
+		buf = append(buf, r.TXT[i+1:i+1+int(r.TXT[i])]...)
 	}
 	return buf
 }
@@ -278,7 +336,7 @@ func (r RawResourceTXT) String() string {
 	var b strings.Builder
 	b.Grow(r.concatLength())
 	for i := 0; i < len(r.TXT); i += int(r.TXT[i]) + 1 {
-		b.Write(r.TXT[i : i+int(r.TXT[i])])
+		b.Write(r.TXT[i+1 : i+1+int(r.TXT[i])])
 	}
 	return b.String()
 }
@@ -287,6 +345,53 @@ type ResourceAAAA struct {
 	AAAA [16]byte
 }
 
+// ResourceSRV is a SRV resource defined in RFC 2782.
+type ResourceSRV struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+
+	// Target is never compressed by the [Builder] (as required by RFC 2782),
+	// but the [Parser] accepts compressed targets.
+	Target Name
+}
+
+// ResourceCAA is a CAA resource defined in RFC 8659.
+type ResourceCAA struct {
+	Flags uint8
+
+	// Tag must consist of 1 to 15 ASCII letters and digits.
+	Tag   []byte
+	Value []byte
+}
+
+const caaFlagIssuerCritical = 1 << 7
+
+// IssuerCritical reports whether the Issuer Critical flag is set.
+func (r *ResourceCAA) IssuerCritical() bool {
+	return r.Flags&caaFlagIssuerCritical != 0
+}
+
+// SetIssuerCritical sets the Issuer Critical flag to val.
+func (r *ResourceCAA) SetIssuerCritical(val bool) {
+	r.Flags &= ^uint8(caaFlagIssuerCritical)
+	if val {
+		r.Flags |= caaFlagIssuerCritical
+	}
+}
+
+func isValidCAATag(tag []byte) bool {
+	if len(tag) == 0 || len(tag) > 15 {
+		return false
+	}
+	for _, c := range tag {
+		if !isDigit(c) && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
 type noCopy struct{}
 
 func (*noCopy) Lock()   {}
@@ -0,0 +1,33 @@
+package dnsmsg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRawResourceTXT(t *testing.T) {
+	txt := RawResourceTXT{TXT: []byte{3, 'a', 'b', 'c', 0, 2, 'd', 'e'}}
+
+	rtxt := txt.ToResourceTXT()
+	expect := [][]byte{[]byte("abc"), {}, []byte("de")}
+	if len(rtxt.TXT) != len(expect) {
+		t.Fatalf("ToResourceTXT() = %q, want: %q", rtxt.TXT, expect)
+	}
+	for i := range expect {
+		if !bytes.Equal(rtxt.TXT[i], expect[i]) {
+			t.Fatalf("ToResourceTXT() = %q, want: %q", rtxt.TXT, expect)
+		}
+	}
+
+	if l := txt.concatLength(); l != 5 {
+		t.Fatalf("concatLength() = %v, want: %v", l, 5)
+	}
+
+	if c := txt.Concat(); !bytes.Equal(c, []byte("abcde")) {
+		t.Fatalf("Concat() = %q, want: %q", c, "abcde")
+	}
+
+	if s := txt.String(); s != "abcde" {
+		t.Fatalf("String() = %q, want: %q", s, "abcde")
+	}
+}
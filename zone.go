@@ -0,0 +1,1551 @@
+package dnsmsg
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZoneParseError is returned by the [ZoneParser], it describes
+// the location of the error in the zone file.
+type ZoneParseError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ZoneParseError) Error() string {
+	file := e.File
+	if file == "" {
+		file = "<zone>"
+	}
+	return file + ":" + strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Column) + ": " + e.Err.Error()
+}
+
+func (e *ZoneParseError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errZoneUnbalancedParentheses = errors.New("unbalanced parentheses")
+	errZoneUnterminatedQuote     = errors.New("unterminated quoted string")
+	errZoneInvalidEscape         = errors.New("invalid escape sequence")
+	errZoneUnknownDirective      = errors.New("unknown directive")
+	errZoneIncludeNotAllowed     = errors.New("$INCLUDE directive is not allowed")
+	errZoneIncludeTooDeep        = errors.New("too many nested $INCLUDE directives")
+	errZoneNoOrigin              = errors.New("relative name without an origin")
+	errZoneNoOwner               = errors.New("no previous owner name")
+	errZoneNoTTL                 = errors.New("no TTL specified")
+	errZoneMissingType           = errors.New("missing resource type")
+	errZoneUnknownType           = errors.New("unknown resource type")
+	errZoneUnknownClass          = errors.New("unknown class")
+	errZoneMetaType              = errors.New("resource type not allowed in zone files")
+	errZoneUnknownTypeRData      = errors.New("unknown resource type requires the generic resource data format")
+	errZoneMissingRData          = errors.New("missing resource data")
+	errZoneTrailingRData         = errors.New("unexpected data after the resource data")
+	errZoneInvalidTTL            = errors.New("invalid TTL")
+	errZoneInvalidValue          = errors.New("invalid value")
+	errZoneInvalidAddress        = errors.New("invalid IP address")
+	errZoneInvalidCharString     = errors.New("character-string too long")
+	errZoneInvalidBase64         = errors.New("invalid base64 data")
+	errZoneInvalidHex            = errors.New("invalid hex data")
+	errZoneInvalidTime           = errors.New("invalid time")
+	errZoneInvalidRDataLength    = errors.New("resource data length does not match the length of the generic resource data")
+	errZoneInvalidSVCParam       = errors.New("invalid service parameter")
+	errZoneDuplicateSVCParam     = errors.New("duplicate service parameter")
+	errZoneInvalidGenerate       = errors.New("invalid $GENERATE directive")
+	errZoneGenerateTooLarge      = errors.New("$GENERATE range too large")
+)
+
+// ZoneParserConfig is a configuration of the [ZoneParser].
+type ZoneParserConfig struct {
+	// File is the name of the zone file, used in errors.
+	File string
+
+	// Origin is the initial origin, used to complete relative names.
+	// When zero, relative names are not allowed until an $ORIGIN directive.
+	Origin Name
+
+	// Class is the class of resources without an explicitly stated class,
+	// that are not preceded by any other resource. When zero, [ClassIN] is used.
+	Class Class
+
+	// Open opens files included by the $INCLUDE directive.
+	// When nil, the $INCLUDE directive is not allowed.
+	Open func(name string) (io.ReadCloser, error)
+}
+
+const maxZoneIncludeDepth = 16
+
+// maxZoneGenerateRange is the maximum difference between the start and
+// the stop values of a $GENERATE range.
+const maxZoneGenerateRange = 65535
+
+// ZoneParser parses zone files (RFC 1035, Section 5), including the
+// $ORIGIN, $TTL (RFC 2308), $INCLUDE and $GENERATE (BIND extension) directives,
+// and the generic resource data format for unknown types (RFC 3597, Section 5).
+//
+// Relative names are completed with the current origin, the "@" name represents the origin.
+// Resources without an owner name use the owner name of the previous resource. Resources without
+// a TTL use the TTL from the $TTL directive, otherwise the TTL of the previous resource.
+// Resources without a class use the class of the previous resource.
+type ZoneParser struct {
+	config ZoneParserConfig
+	r      io.Reader
+	err    error
+
+	files []*zoneLexer
+	gen   *zoneGenerator
+
+	defaultTTL    uint32
+	hasDefaultTTL bool
+
+	lastOwner  Name
+	lastTTL    uint32
+	hasLastTTL bool
+	lastClass  Class
+}
+
+// NewZoneParser creates a new [ZoneParser], that parses the zone file from r.
+func NewZoneParser(r io.Reader, config ZoneParserConfig) *ZoneParser {
+	if config.Class == 0 {
+		config.Class = ClassIN
+	}
+	return &ZoneParser{
+		config:    config,
+		r:         r,
+		lastClass: config.Class,
+	}
+}
+
+// Next parses the next resource from the zone file.
+// It returns [io.EOF] when there are no more resources.
+//
+// Syntax errors are returned as a [*ZoneParseError], after an error
+// Next always returns the same error.
+//
+// The returned [Resource] can be appended to a [Builder] by using the
+// Builder method that corresponds to the type of the Body.
+func (z *ZoneParser) Next() (Resource, error) {
+	if z.err != nil {
+		return Resource{}, z.err
+	}
+	res, err := z.next()
+	if err != nil {
+		z.err = err
+	}
+	return res, err
+}
+
+func (z *ZoneParser) next() (Resource, error) {
+	if z.r != nil {
+		data, err := io.ReadAll(z.r)
+		if err != nil {
+			return Resource{}, err
+		}
+		z.r = nil
+		z.files = append(z.files, newZoneLexer(z.config.File, data, z.config.Origin))
+	}
+
+	for {
+		if z.gen != nil {
+			lex, ok := z.gen.next()
+			if !ok {
+				z.gen = nil
+				continue
+			}
+			entry, err := lex.nextEntry()
+			if err != nil {
+				return Resource{}, err
+			}
+			if len(entry.tokens) == 0 {
+				return Resource{}, lex.errorf(0, 0, errZoneInvalidGenerate)
+			}
+			return z.resource(lex, &entry)
+		}
+
+		if len(z.files) == 0 {
+			return Resource{}, io.EOF
+		}
+
+		lex := z.files[len(z.files)-1]
+		entry, err := lex.nextEntry()
+		if err != nil {
+			return Resource{}, err
+		}
+
+		if len(entry.tokens) == 0 {
+			z.files = z.files[:len(z.files)-1]
+			continue
+		}
+
+		if first := entry.tokens[0]; !entry.leadingBlank && !first.quoted && len(first.text) != 0 && first.text[0] == '$' {
+			if err := z.directive(lex, entry.tokens); err != nil {
+				return Resource{}, err
+			}
+			continue
+		}
+
+		return z.resource(lex, &entry)
+	}
+}
+
+func (z *ZoneParser) directive(lex *zoneLexer, tokens []zoneToken) error {
+	directive := tokens[0]
+	switch strings.ToUpper(string(directive.text)) {
+	case "$ORIGIN":
+		if len(tokens) != 2 {
+			return lex.tokenError(&directive, errZoneInvalidValue)
+		}
+		origin, err := lex.parseName(&tokens[1])
+		if err != nil {
+			return err
+		}
+		lex.origin = origin
+		return nil
+	case "$TTL":
+		if len(tokens) != 2 {
+			return lex.tokenError(&directive, errZoneInvalidTTL)
+		}
+		ttl, ok := parseZoneTTL(tokens[1].text)
+		if !ok {
+			return lex.tokenError(&tokens[1], errZoneInvalidTTL)
+		}
+		z.defaultTTL = ttl
+		z.hasDefaultTTL = true
+		return nil
+	case "$INCLUDE":
+		if len(tokens) != 2 && len(tokens) != 3 {
+			return lex.tokenError(&directive, errZoneInvalidValue)
+		}
+		if z.config.Open == nil {
+			return lex.tokenError(&directive, errZoneIncludeNotAllowed)
+		}
+		if len(z.files) >= maxZoneIncludeDepth {
+			return lex.tokenError(&directive, errZoneIncludeTooDeep)
+		}
+
+		origin := lex.origin
+		if len(tokens) == 3 {
+			var err error
+			origin, err = lex.parseName(&tokens[2])
+			if err != nil {
+				return err
+			}
+		}
+
+		fileName, err := unescapeCharacterString(tokens[1].text)
+		if err != nil {
+			return lex.tokenError(&tokens[1], err)
+		}
+
+		f, err := z.config.Open(string(fileName))
+		if err != nil {
+			return lex.tokenError(&tokens[1], err)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return lex.tokenError(&tokens[1], err)
+		}
+
+		z.files = append(z.files, newZoneLexer(string(fileName), data, origin))
+		return nil
+	case "$GENERATE":
+		gen, err := newZoneGenerator(lex, tokens)
+		if err != nil {
+			return err
+		}
+		z.gen = gen
+		return nil
+	default:
+		return lex.tokenError(&directive, errZoneUnknownDirective)
+	}
+}
+
+func (z *ZoneParser) resource(lex *zoneLexer, entry *zoneEntry) (Resource, error) {
+	tokens := entry.tokens
+
+	var hdr ResourceHeader
+	if entry.leadingBlank {
+		if z.lastOwner.Length == 0 {
+			return Resource{}, lex.tokenError(&tokens[0], errZoneNoOwner)
+		}
+		hdr.Name = z.lastOwner
+	} else {
+		var err error
+		hdr.Name, err = lex.parseName(&tokens[0])
+		if err != nil {
+			return Resource{}, err
+		}
+		tokens = tokens[1:]
+	}
+
+	hasTTL, hasClass, hasType := false, false, false
+	for len(tokens) != 0 && !hasType {
+		tok := &tokens[0]
+		tokens = tokens[1:]
+
+		if !tok.quoted && !hasTTL && len(tok.text) != 0 && isDigit(tok.text[0]) {
+			ttl, ok := parseZoneTTL(tok.text)
+			if !ok {
+				return Resource{}, lex.tokenError(tok, errZoneInvalidTTL)
+			}
+			hdr.TTL = ttl
+			hasTTL = true
+			continue
+		}
+
+		if class, ok := parseZoneClass(tok.text); ok && !tok.quoted && !hasClass {
+			hdr.Class = class
+			hasClass = true
+			continue
+		}
+
+		typ, ok := parseZoneType(tok.text)
+		if !ok || tok.quoted {
+			if _, ok := parseZoneClass(tok.text); ok || (len(tok.text) != 0 && isDigit(tok.text[0])) {
+				return Resource{}, lex.tokenError(tok, errZoneMissingType)
+			}
+			return Resource{}, lex.tokenError(tok, errZoneUnknownType)
+		}
+		if typ == TypeOPT || typ == TypeTSIG {
+			return Resource{}, lex.tokenError(tok, errZoneMetaType)
+		}
+		hdr.Type = typ
+		hasType = true
+	}
+
+	if !hasType {
+		return Resource{}, lex.errorf(entry.endLine, entry.endColumn, errZoneMissingType)
+	}
+
+	rd := zoneRData{lex: lex, tokens: tokens, entry: entry}
+	body, err := rd.body(hdr.Type)
+	if err != nil {
+		return Resource{}, err
+	}
+
+	if !hasClass {
+		hdr.Class = z.lastClass
+	}
+
+	if !hasTTL {
+		switch {
+		case z.hasDefaultTTL:
+			hdr.TTL = z.defaultTTL
+		case z.hasLastTTL:
+			hdr.TTL = z.lastTTL
+		case hdr.Type == TypeSOA:
+			hdr.TTL = body.(ResourceSOA).Minimum
+		default:
+			return Resource{}, lex.tokenError(&entry.tokens[0], errZoneNoTTL)
+		}
+	}
+
+	z.lastOwner = hdr.Name
+	z.lastTTL = hdr.TTL
+	z.hasLastTTL = true
+	z.lastClass = hdr.Class
+
+	return Resource{Header: hdr, Body: body}, nil
+}
+
+// zoneTypes are the types supported by the [ZoneParser].
+var zoneTypes = []Type{
+	TypeA, TypeNS, TypeCNAME, TypeSOA, TypePTR, TypeMX, TypeTXT, TypeSIG, TypeAAAA, TypeSRV,
+	TypeDNAME, TypeOPT, TypeDS, TypeRRSIG, TypeNSEC, TypeDNSKEY, TypeNSEC3, TypeNSEC3PARAM, TypeCDS,
+	TypeCDNSKEY, TypeSVCB, TypeHTTPS, TypeTSIG, TypeCAA,
+}
+
+func parseZoneType(s []byte) (Type, bool) {
+	if t, ok := parseBuiltinZoneType(s); ok {
+		return t, true
+	}
+	return lookupResourceTypeName(string(s))
+}
+
+func parseBuiltinZoneType(s []byte) (Type, bool) {
+	for _, t := range zoneTypes {
+		if strings.EqualFold(string(s), t.String()) {
+			return t, true
+		}
+	}
+	if len(s) > 4 && strings.EqualFold(string(s[:4]), "TYPE") {
+		v, err := strconv.ParseUint(string(s[4:]), 10, 16)
+		if err == nil {
+			return Type(v), true
+		}
+	}
+	return 0, false
+}
+
+func parseZoneClass(s []byte) (Class, bool) {
+	switch {
+	case strings.EqualFold(string(s), "IN"):
+		return ClassIN, true
+	case strings.EqualFold(string(s), "ANY"):
+		return ClassANY, true
+	case len(s) > 5 && strings.EqualFold(string(s[:5]), "CLASS"):
+		v, err := strconv.ParseUint(string(s[5:]), 10, 16)
+		if err == nil {
+			return Class(v), true
+		}
+	}
+	return 0, false
+}
+
+// parseZoneTTL parses a TTL, either as a decimal number of seconds, or as a sequence
+// of numbers with units (s, m, h, d, w), e.g. "1h30m" (BIND extension).
+func parseZoneTTL(s []byte) (uint32, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+
+	var total, cur uint64
+	digits := false
+	for i, c := range s {
+		if isDigit(c) {
+			cur = cur*10 + uint64(c-'0')
+			if cur > math.MaxUint32 {
+				return 0, false
+			}
+			digits = true
+			continue
+		}
+
+		if !digits {
+			return 0, false
+		}
+
+		var mul uint64
+		switch c | 0x20 {
+		case 's':
+			mul = 1
+		case 'm':
+			mul = 60
+		case 'h':
+			mul = 60 * 60
+		case 'd':
+			mul = 24 * 60 * 60
+		case 'w':
+			mul = 7 * 24 * 60 * 60
+		default:
+			return 0, false
+		}
+
+		total += cur * mul
+		if total > math.MaxUint32 {
+			return 0, false
+		}
+		cur = 0
+		digits = false
+
+		if i == len(s)-1 {
+			return uint32(total), true
+		}
+	}
+
+	total += cur
+	if total > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32(total), true
+}
+
+// unescapeCharacterString decodes the escape sequences (\X and \DDD) of a character-string.
+func unescapeCharacterString(s []byte) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return nil, errZoneInvalidEscape
+		}
+		if isDigit(s[i]) {
+			if len(s)-i < 3 || !isDigit(s[i+1]) || !isDigit(s[i+2]) {
+				return nil, errZoneInvalidEscape
+			}
+			v, ok := decodeDDD([3]byte(s[i:]))
+			if !ok {
+				return nil, errZoneInvalidEscape
+			}
+			out = append(out, v)
+			i += 2
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return out, nil
+}
+
+type zoneToken struct {
+	// text is the raw token text, escape sequences are not decoded,
+	// quotes are removed.
+	text   []byte
+	quoted bool
+
+	line, column int
+}
+
+type zoneEntry struct {
+	tokens       []zoneToken
+	leadingBlank bool
+
+	// position right after the last token.
+	endLine, endColumn int
+}
+
+type zoneLexer struct {
+	file   string
+	data   []byte
+	offset int
+
+	line, column int
+	origin       Name
+
+	// When fixed is true, all errors are reported at the fixedLine and fixedColumn,
+	// used by lexers that parse the text produced by the $GENERATE directive.
+	fixed                  bool
+	fixedLine, fixedColumn int
+}
+
+func newZoneLexer(file string, data []byte, origin Name) *zoneLexer {
+	return &zoneLexer{
+		file:   file,
+		data:   data,
+		line:   1,
+		column: 1,
+		origin: origin,
+	}
+}
+
+func (l *zoneLexer) errorf(line, column int, err error) error {
+	if l.fixed {
+		line, column = l.fixedLine, l.fixedColumn
+	}
+	return &ZoneParseError{File: l.file, Line: line, Column: column, Err: err}
+}
+
+func (l *zoneLexer) tokenError(tok *zoneToken, err error) error {
+	return l.errorf(tok.line, tok.column, err)
+}
+
+func (l *zoneLexer) advance() {
+	if l.data[l.offset] == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	l.offset++
+}
+
+// nextEntry reads the next non-empty entry (a logical line, that might span multiple lines when
+// parentheses are used). It returns an entry without tokens at the end of the input.
+func (l *zoneLexer) nextEntry() (zoneEntry, error) {
+	var (
+		entry     zoneEntry
+		parens    int
+		parenLine int
+		parenCol  int
+	)
+
+	for l.offset < len(l.data) {
+		c := l.data[l.offset]
+		switch c {
+		case '\n':
+			l.advance()
+			if parens == 0 {
+				if len(entry.tokens) != 0 {
+					return entry, nil
+				}
+				entry.leadingBlank = false
+			}
+		case ' ', '\t', '\r':
+			if l.column == 1 && len(entry.tokens) == 0 && parens == 0 {
+				entry.leadingBlank = true
+			}
+			l.advance()
+		case ';':
+			for l.offset < len(l.data) && l.data[l.offset] != '\n' {
+				l.advance()
+			}
+		case '(':
+			if parens == 0 {
+				parenLine, parenCol = l.line, l.column
+			}
+			parens++
+			l.advance()
+		case ')':
+			if parens == 0 {
+				return zoneEntry{}, l.errorf(l.line, l.column, errZoneUnbalancedParentheses)
+			}
+			parens--
+			l.advance()
+		default:
+			tok, err := l.token()
+			if err != nil {
+				return zoneEntry{}, err
+			}
+			entry.tokens = append(entry.tokens, tok)
+			entry.endLine, entry.endColumn = l.line, l.column
+		}
+	}
+
+	if parens != 0 {
+		return zoneEntry{}, l.errorf(parenLine, parenCol, errZoneUnbalancedParentheses)
+	}
+	return entry, nil
+}
+
+func (l *zoneLexer) token() (zoneToken, error) {
+	tok := zoneToken{line: l.line, column: l.column}
+	inQuote := false
+	quoteLine, quoteCol := 0, 0
+
+loop:
+	for l.offset < len(l.data) {
+		c := l.data[l.offset]
+		switch {
+		case c == '"':
+			if !inQuote {
+				quoteLine, quoteCol = l.line, l.column
+			}
+			inQuote = !inQuote
+			tok.quoted = true
+			l.advance()
+		case c == '\\':
+			if l.offset+1 == len(l.data) {
+				return zoneToken{}, l.errorf(l.line, l.column, errZoneInvalidEscape)
+			}
+			tok.text = append(tok.text, c, l.data[l.offset+1])
+			l.advance()
+			l.advance()
+		case inQuote:
+			tok.text = append(tok.text, c)
+			l.advance()
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '(' || c == ')':
+			break loop
+		default:
+			tok.text = append(tok.text, c)
+			l.advance()
+		}
+	}
+
+	if inQuote {
+		return zoneToken{}, l.errorf(quoteLine, quoteCol, errZoneUnterminatedQuote)
+	}
+	return tok, nil
+}
+
+// parseName parses a domain name, relative names are completed with the origin.
+func (l *zoneLexer) parseName(tok *zoneToken) (Name, error) {
+	if string(tok.text) == "@" && !tok.quoted {
+		if l.origin.Length == 0 {
+			return Name{}, l.tokenError(tok, errZoneNoOrigin)
+		}
+		return l.origin, nil
+	}
+
+	n, err := ParseName(string(tok.text))
+	if err != nil {
+		return Name{}, l.tokenError(tok, err)
+	}
+
+	if isAbsoluteZoneName(tok.text) {
+		return n, nil
+	}
+
+	if l.origin.Length == 0 {
+		return Name{}, l.tokenError(tok, errZoneNoOrigin)
+	}
+	if int(n.Length)-1+int(l.origin.Length) > maxEncodedNameLen {
+		return Name{}, l.tokenError(tok, errInvalidName)
+	}
+	n.Length += uint8(copy(n.Name[n.Length-1:], l.origin.asSlice()) - 1)
+	return n, nil
+}
+
+// isAbsoluteZoneName reports whether the name ends with a non-escaped dot.
+func isAbsoluteZoneName(name []byte) bool {
+	if len(name) == 0 || name[len(name)-1] != '.' {
+		return false
+	}
+	backslashes := 0
+	for i := len(name) - 2; i >= 0 && name[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// zoneGenerator generates resources for the $GENERATE directive (BIND extension):
+//
+//	$GENERATE start-stop[/step] lhs [ttl] [class] type rhs
+//
+// Every "$" in the template is replaced with the iterator value, "${offset[,width[,base]]}"
+// can be used to modify the value, base is one of: d, o, x or X. "\$" is a literal "$".
+type zoneGenerator struct {
+	lex      *zoneLexer
+	line     int
+	column   int
+	template []byte
+
+	cur, stop, step int64
+}
+
+func newZoneGenerator(lex *zoneLexer, tokens []zoneToken) (*zoneGenerator, error) {
+	if len(tokens) < 4 {
+		return nil, lex.tokenError(&tokens[0], errZoneInvalidGenerate)
+	}
+
+	rangeTok := &tokens[1]
+	rng := string(rangeTok.text)
+	step := int64(1)
+	if i := strings.IndexByte(rng, '/'); i >= 0 {
+		v, err := strconv.ParseUint(rng[i+1:], 10, 32)
+		if err != nil || v == 0 {
+			return nil, lex.tokenError(rangeTok, errZoneInvalidGenerate)
+		}
+		step = int64(v)
+		rng = rng[:i]
+	}
+	i := strings.IndexByte(rng, '-')
+	if i < 0 {
+		return nil, lex.tokenError(rangeTok, errZoneInvalidGenerate)
+	}
+	start, err := strconv.ParseUint(rng[:i], 10, 32)
+	if err != nil {
+		return nil, lex.tokenError(rangeTok, errZoneInvalidGenerate)
+	}
+	stop, err := strconv.ParseUint(rng[i+1:], 10, 32)
+	if err != nil || stop < start {
+		return nil, lex.tokenError(rangeTok, errZoneInvalidGenerate)
+	}
+	if stop-start > maxZoneGenerateRange {
+		return nil, lex.tokenError(rangeTok, errZoneGenerateTooLarge)
+	}
+
+	var template []byte
+	for i, tok := range tokens[2:] {
+		if i != 0 {
+			template = append(template, ' ')
+		}
+		if tok.quoted {
+			template = append(template, '"')
+		}
+		template = append(template, tok.text...)
+		if tok.quoted {
+			template = append(template, '"')
+		}
+	}
+
+	g := &zoneGenerator{
+		lex:      lex,
+		line:     tokens[0].line,
+		column:   tokens[0].column,
+		template: template,
+		cur:      int64(start),
+		stop:     int64(stop),
+		step:     step,
+	}
+
+	// Validate the template.
+	if _, err := g.expand(0); err != nil {
+		return nil, lex.tokenError(&tokens[0], err)
+	}
+	return g, nil
+}
+
+func (g *zoneGenerator) next() (*zoneLexer, bool) {
+	if g.cur > g.stop {
+		return nil, false
+	}
+	text, _ := g.expand(g.cur)
+	g.cur += g.step
+
+	lex := newZoneLexer(g.lex.file, text, g.lex.origin)
+	lex.fixed = true
+	lex.fixedLine, lex.fixedColumn = g.line, g.column
+	return lex, true
+}
+
+func (g *zoneGenerator) expand(v int64) ([]byte, error) {
+	t := g.template
+	out := make([]byte, 0, len(t)+16)
+	for i := 0; i < len(t); i++ {
+		switch t[i] {
+		case '\\':
+			out = append(out, t[i])
+			if i+1 < len(t) {
+				i++
+				out = append(out, t[i])
+			}
+		case '$':
+			if i+1 == len(t) || t[i+1] != '{' {
+				out = strconv.AppendInt(out, v, 10)
+				continue
+			}
+			end := strings.IndexByte(string(t[i:]), '}')
+			if end < 0 {
+				return nil, errZoneInvalidGenerate
+			}
+			modifiers := strings.Split(string(t[i+2:i+end]), ",")
+			i += end
+
+			offset, err := strconv.ParseInt(modifiers[0], 10, 32)
+			if err != nil || len(modifiers) > 3 {
+				return nil, errZoneInvalidGenerate
+			}
+			width := uint64(0)
+			if len(modifiers) > 1 {
+				width, err = strconv.ParseUint(modifiers[1], 10, 8)
+				if err != nil {
+					return nil, errZoneInvalidGenerate
+				}
+			}
+			format := "d"
+			if len(modifiers) > 2 {
+				format = modifiers[2]
+			}
+			switch format {
+			case "d", "o", "x", "X":
+			default:
+				return nil, errZoneInvalidGenerate
+			}
+			out = append(out, fmt.Sprintf("%0*"+format, width, v+offset)...)
+		default:
+			out = append(out, t[i])
+		}
+	}
+	return out, nil
+}
+
+// zoneRData parses the resource data of a single resource.
+type zoneRData struct {
+	lex    *zoneLexer
+	entry  *zoneEntry
+	tokens []zoneToken
+}
+
+func (r *zoneRData) next() (*zoneToken, error) {
+	if len(r.tokens) == 0 {
+		return nil, r.lex.errorf(r.entry.endLine, r.entry.endColumn, errZoneMissingRData)
+	}
+	tok := &r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return tok, nil
+}
+
+func (r *zoneRData) end() error {
+	if len(r.tokens) != 0 {
+		return r.lex.tokenError(&r.tokens[0], errZoneTrailingRData)
+	}
+	return nil
+}
+
+func (r *zoneRData) name() (Name, error) {
+	tok, err := r.next()
+	if err != nil {
+		return Name{}, err
+	}
+	return r.lex.parseName(tok)
+}
+
+func (r *zoneRData) uint(bitSize int) (uint64, error) {
+	tok, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(string(tok.text), 10, bitSize)
+	if err != nil || tok.quoted {
+		return 0, r.lex.tokenError(tok, errZoneInvalidValue)
+	}
+	return v, nil
+}
+
+func (r *zoneRData) uint8() (uint8, error) {
+	v, err := r.uint(8)
+	return uint8(v), err
+}
+
+func (r *zoneRData) uint16() (uint16, error) {
+	v, err := r.uint(16)
+	return uint16(v), err
+}
+
+func (r *zoneRData) uint32() (uint32, error) {
+	v, err := r.uint(32)
+	return uint32(v), err
+}
+
+func (r *zoneRData) ttl() (uint32, error) {
+	tok, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	ttl, ok := parseZoneTTL(tok.text)
+	if !ok || tok.quoted {
+		return 0, r.lex.tokenError(tok, errZoneInvalidTTL)
+	}
+	return ttl, nil
+}
+
+func (r *zoneRData) charString() ([]byte, error) {
+	tok, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	s, err := unescapeCharacterString(tok.text)
+	if err != nil {
+		return nil, r.lex.tokenError(tok, err)
+	}
+	if len(s) > math.MaxUint8 {
+		return nil, r.lex.tokenError(tok, errZoneInvalidCharString)
+	}
+	return s, nil
+}
+
+func (r *zoneRData) addr() (netip.Addr, *zoneToken, error) {
+	tok, err := r.next()
+	if err != nil {
+		return netip.Addr{}, nil, err
+	}
+	addr, err := netip.ParseAddr(string(tok.text))
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, nil, r.lex.tokenError(tok, errZoneInvalidAddress)
+	}
+	return addr, tok, nil
+}
+
+// rest concatenates all remaining tokens, used for base64 and hex
+// encoded fields, which might be split by whitespace.
+func (r *zoneRData) rest() (*zoneToken, []byte, error) {
+	if len(r.tokens) == 0 {
+		return nil, nil, r.lex.errorf(r.entry.endLine, r.entry.endColumn, errZoneMissingRData)
+	}
+	first := &r.tokens[0]
+	var data []byte
+	for _, tok := range r.tokens {
+		data = append(data, tok.text...)
+	}
+	r.tokens = nil
+	return first, data, nil
+}
+
+func (r *zoneRData) base64() ([]byte, error) {
+	tok, data, err := r.rest()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(out, data)
+	if err != nil {
+		return nil, r.lex.tokenError(tok, errZoneInvalidBase64)
+	}
+	return out[:n], nil
+}
+
+func (r *zoneRData) hex(data []byte, tok *zoneToken) ([]byte, error) {
+	out := make([]byte, hex.DecodedLen(len(data)))
+	if _, err := hex.Decode(out, data); err != nil {
+		return nil, r.lex.tokenError(tok, errZoneInvalidHex)
+	}
+	return out, nil
+}
+
+func (r *zoneRData) salt() ([]byte, error) {
+	tok, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if string(tok.text) == "-" {
+		return nil, nil
+	}
+	salt, err := r.hex(tok.text, tok)
+	if err != nil {
+		return nil, err
+	}
+	if len(salt) > math.MaxUint8 {
+		return nil, r.lex.tokenError(tok, errInvalidNSEC3)
+	}
+	return salt, nil
+}
+
+// time parses a time in the YYYYMMDDHHmmSS format or as
+// a decimal number of seconds (RFC 4034, Section 3.2).
+func (r *zoneRData) time() (uint32, error) {
+	tok, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	if len(tok.text) == 14 {
+		t, err := time.Parse("20060102150405", string(tok.text))
+		if err != nil {
+			return 0, r.lex.tokenError(tok, errZoneInvalidTime)
+		}
+		return uint32(t.Unix()), nil
+	}
+	v, err := strconv.ParseUint(string(tok.text), 10, 32)
+	if err != nil {
+		return 0, r.lex.tokenError(tok, errZoneInvalidTime)
+	}
+	return uint32(v), nil
+}
+
+func (r *zoneRData) typ() (Type, error) {
+	tok, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	t, ok := parseZoneType(tok.text)
+	if !ok {
+		return 0, r.lex.tokenError(tok, errZoneUnknownType)
+	}
+	return t, nil
+}
+
+func (r *zoneRData) typeBitmap() (TypeBitmap, error) {
+	var types []Type
+	for len(r.tokens) != 0 {
+		t, err := r.typ()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return NewTypeBitmap(types...), nil
+}
+
+func (r *zoneRData) body(typ Type) (ResourceBody, error) {
+	if len(r.tokens) != 0 && string(r.tokens[0].text) == `\#` && !r.tokens[0].quoted {
+		return r.generic(typ)
+	}
+
+	body, err := r.typedBody(typ)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.end(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// generic parses the generic resource data format (RFC 3597, Section 5), resource
+// data of known (and registered) types is converted to the corresponding resource type.
+func (r *zoneRData) generic(typ Type) (ResourceBody, error) {
+	genericTok, _ := r.next()
+
+	length, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+
+	var rdata []byte
+	if len(r.tokens) != 0 {
+		tok, data, _ := r.rest()
+		rdata, err = r.hex(data, tok)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rdata) != int(length) {
+		return nil, r.lex.tokenError(genericTok, errZoneInvalidRDataLength)
+	}
+
+	body, err := decodeResourceBody(typ, rdata)
+	if err != nil {
+		return nil, r.lex.tokenError(genericTok, err)
+	}
+	return body, nil
+}
+
+// decodeResourceBody decodes the rdata in wire format of the typ type.
+func decodeResourceBody(typ Type, rdata []byte) (ResourceBody, error) {
+	msg := make([]byte, headerLen, headerLen+11+len(rdata))
+	packUint16(msg[6:], 1) // ANCount
+	msg = append(msg, 0)
+	msg = appendUint16(msg, uint16(typ))
+	msg = appendUint16(msg, uint16(ClassIN))
+	msg = appendUint32(msg, 0)
+	msg = appendUint16(msg, uint16(len(rdata)))
+	msg = append(msg, rdata...)
+
+	p, _, err := Parse(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if _, err := p.ResourceHeader(); err != nil {
+		return nil, err
+	}
+	return p.ResourceBody()
+}
+
+func (r *zoneRData) typedBody(typ Type) (ResourceBody, error) {
+	switch typ {
+	case TypeA:
+		addr, tok, err := r.addr()
+		if err != nil {
+			return nil, err
+		}
+		if !addr.Is4() {
+			return nil, r.lex.tokenError(tok, errZoneInvalidAddress)
+		}
+		return ResourceA{A: addr.As4()}, nil
+	case TypeAAAA:
+		addr, tok, err := r.addr()
+		if err != nil {
+			return nil, err
+		}
+		if !addr.Is6() {
+			return nil, r.lex.tokenError(tok, errZoneInvalidAddress)
+		}
+		return ResourceAAAA{AAAA: addr.As16()}, nil
+	case TypeNS:
+		ns, err := r.name()
+		return ResourceNS{NS: ns}, err
+	case TypeCNAME:
+		cname, err := r.name()
+		return ResourceCNAME{CNAME: cname}, err
+	case TypePTR:
+		ptr, err := r.name()
+		return ResourcePTR{PTR: ptr}, err
+	case TypeDNAME:
+		dname, err := r.name()
+		return ResourceDNAME{DNAME: dname}, err
+	case TypeSOA:
+		return r.soa()
+	case TypeMX:
+		pref, err := r.uint16()
+		if err != nil {
+			return nil, err
+		}
+		mx, err := r.name()
+		return ResourceMX{Pref: pref, MX: mx}, err
+	case TypeTXT:
+		var txt ResourceTXT
+		for len(r.tokens) != 0 || len(txt.TXT) == 0 {
+			s, err := r.charString()
+			if err != nil {
+				return nil, err
+			}
+			txt.TXT = append(txt.TXT, s)
+		}
+		return txt, nil
+	case TypeSRV:
+		var (
+			srv ResourceSRV
+			err error
+		)
+		if srv.Priority, err = r.uint16(); err != nil {
+			return nil, err
+		}
+		if srv.Weight, err = r.uint16(); err != nil {
+			return nil, err
+		}
+		if srv.Port, err = r.uint16(); err != nil {
+			return nil, err
+		}
+		srv.Target, err = r.name()
+		return srv, err
+	case TypeCAA:
+		return r.caa()
+	case TypeSVCB:
+		return r.svcb()
+	case TypeHTTPS:
+		svcb, err := r.svcb()
+		return ResourceHTTPS(svcb), err
+	case TypeDNSKEY:
+		return r.dnskey()
+	case TypeCDNSKEY:
+		dnskey, err := r.dnskey()
+		return ResourceCDNSKEY(dnskey), err
+	case TypeDS:
+		return r.ds()
+	case TypeCDS:
+		ds, err := r.ds()
+		return ResourceCDS(ds), err
+	case TypeRRSIG:
+		return r.rrsig()
+	case TypeSIG:
+		rrsig, err := r.rrsig()
+		return ResourceSIG(rrsig), err
+	case TypeNSEC:
+		var (
+			nsec ResourceNSEC
+			err  error
+		)
+		if nsec.NextDomain, err = r.name(); err != nil {
+			return nil, err
+		}
+		nsec.TypeBitmap, err = r.typeBitmap()
+		return nsec, err
+	case TypeNSEC3:
+		return r.nsec3()
+	case TypeNSEC3PARAM:
+		var (
+			nsec3param ResourceNSEC3PARAM
+			err        error
+		)
+		alg, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		nsec3param.HashAlgorithm = NSEC3HashAlgorithm(alg)
+		if nsec3param.Flags, err = r.uint8(); err != nil {
+			return nil, err
+		}
+		if nsec3param.Iterations, err = r.uint16(); err != nil {
+			return nil, err
+		}
+		nsec3param.Salt, err = r.salt()
+		return nsec3param, err
+	}
+
+	if len(r.tokens) == 0 {
+		return nil, r.lex.errorf(r.entry.endLine, r.entry.endColumn, errZoneUnknownTypeRData)
+	}
+	return nil, r.lex.tokenError(&r.tokens[0], errZoneUnknownTypeRData)
+}
+
+func (r *zoneRData) soa() (ResourceSOA, error) {
+	var (
+		soa ResourceSOA
+		err error
+	)
+	if soa.NS, err = r.name(); err != nil {
+		return ResourceSOA{}, err
+	}
+	if soa.Mbox, err = r.name(); err != nil {
+		return ResourceSOA{}, err
+	}
+	if soa.Serial, err = r.uint32(); err != nil {
+		return ResourceSOA{}, err
+	}
+	for _, v := range []*uint32{&soa.Refresh, &soa.Retry, &soa.Expire, &soa.Minimum} {
+		if *v, err = r.ttl(); err != nil {
+			return ResourceSOA{}, err
+		}
+	}
+	return soa, nil
+}
+
+func (r *zoneRData) caa() (ResourceCAA, error) {
+	var (
+		caa ResourceCAA
+		err error
+	)
+	if caa.Flags, err = r.uint8(); err != nil {
+		return ResourceCAA{}, err
+	}
+	tag, err := r.next()
+	if err != nil {
+		return ResourceCAA{}, err
+	}
+	if !isValidCAATag(tag.text) {
+		return ResourceCAA{}, r.lex.tokenError(tag, errInvalidCAATag)
+	}
+	caa.Tag = tag.text
+
+	valueTok, err := r.next()
+	if err != nil {
+		return ResourceCAA{}, err
+	}
+	if caa.Value, err = unescapeCharacterString(valueTok.text); err != nil {
+		return ResourceCAA{}, r.lex.tokenError(valueTok, err)
+	}
+	return caa, nil
+}
+
+func (r *zoneRData) dnskey() (ResourceDNSKEY, error) {
+	var (
+		dnskey ResourceDNSKEY
+		err    error
+	)
+	if dnskey.Flags, err = r.uint16(); err != nil {
+		return ResourceDNSKEY{}, err
+	}
+	if dnskey.Protocol, err = r.uint8(); err != nil {
+		return ResourceDNSKEY{}, err
+	}
+	alg, err := r.uint8()
+	if err != nil {
+		return ResourceDNSKEY{}, err
+	}
+	dnskey.Algorithm = DNSSECAlgorithm(alg)
+	dnskey.PublicKey, err = r.base64()
+	return dnskey, err
+}
+
+func (r *zoneRData) ds() (ResourceDS, error) {
+	var (
+		ds  ResourceDS
+		err error
+	)
+	if ds.KeyTag, err = r.uint16(); err != nil {
+		return ResourceDS{}, err
+	}
+	alg, err := r.uint8()
+	if err != nil {
+		return ResourceDS{}, err
+	}
+	ds.Algorithm = DNSSECAlgorithm(alg)
+	digestType, err := r.uint8()
+	if err != nil {
+		return ResourceDS{}, err
+	}
+	ds.DigestType = DigestType(digestType)
+	tok, data, err := r.rest()
+	if err != nil {
+		return ResourceDS{}, err
+	}
+	ds.Digest, err = r.hex(data, tok)
+	return ds, err
+}
+
+func (r *zoneRData) rrsig() (ResourceRRSIG, error) {
+	var (
+		rrsig ResourceRRSIG
+		err   error
+	)
+	if rrsig.TypeCovered, err = r.typ(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	alg, err := r.uint8()
+	if err != nil {
+		return ResourceRRSIG{}, err
+	}
+	rrsig.Algorithm = DNSSECAlgorithm(alg)
+	if rrsig.Labels, err = r.uint8(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if rrsig.OriginalTTL, err = r.uint32(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if rrsig.Expiration, err = r.time(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if rrsig.Inception, err = r.time(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if rrsig.KeyTag, err = r.uint16(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	if rrsig.SignerName, err = r.name(); err != nil {
+		return ResourceRRSIG{}, err
+	}
+	rrsig.Signature, err = r.base64()
+	return rrsig, err
+}
+
+func (r *zoneRData) nsec3() (ResourceNSEC3, error) {
+	var (
+		nsec3 ResourceNSEC3
+		err   error
+	)
+	alg, err := r.uint8()
+	if err != nil {
+		return ResourceNSEC3{}, err
+	}
+	nsec3.HashAlgorithm = NSEC3HashAlgorithm(alg)
+	if nsec3.Flags, err = r.uint8(); err != nil {
+		return ResourceNSEC3{}, err
+	}
+	if nsec3.Iterations, err = r.uint16(); err != nil {
+		return ResourceNSEC3{}, err
+	}
+	if nsec3.Salt, err = r.salt(); err != nil {
+		return ResourceNSEC3{}, err
+	}
+
+	tok, err := r.next()
+	if err != nil {
+		return ResourceNSEC3{}, err
+	}
+	next := make([]byte, len(tok.text))
+	copy(next, tok.text)
+	lowerASCII(next)
+	if nsec3.NextHashedOwner, err = nsec3Base32.DecodeString(string(next)); err != nil || len(nsec3.NextHashedOwner) > math.MaxUint8 {
+		return ResourceNSEC3{}, r.lex.tokenError(tok, errInvalidNSEC3)
+	}
+
+	nsec3.TypeBitmap, err = r.typeBitmap()
+	return nsec3, err
+}
+
+func (r *zoneRData) svcb() (ResourceSVCB, error) {
+	var (
+		svcb ResourceSVCB
+		err  error
+	)
+	if svcb.Priority, err = r.uint16(); err != nil {
+		return ResourceSVCB{}, err
+	}
+	if svcb.Target, err = r.name(); err != nil {
+		return ResourceSVCB{}, err
+	}
+
+	var keys []SVCParamKey
+	for len(r.tokens) != 0 {
+		tok, _ := r.next()
+		param, err := parseZoneSVCParam(tok.text)
+		if err != nil {
+			return ResourceSVCB{}, r.lex.tokenError(tok, err)
+		}
+		key := param.svcParamKey()
+		for _, v := range keys {
+			if v == key {
+				return ResourceSVCB{}, r.lex.tokenError(tok, errZoneDuplicateSVCParam)
+			}
+		}
+		keys = append(keys, key)
+		svcb.Params = append(svcb.Params, param)
+	}
+
+	// Service parameters might appear in any order in the presentation format (RFC 9460, Section 2.1).
+	sort.SliceStable(svcb.Params, func(i, j int) bool {
+		return svcb.Params[i].svcParamKey() < svcb.Params[j].svcParamKey()
+	})
+	return svcb, nil
+}
+
+func parseZoneSVCParamKey(s string) (SVCParamKey, bool) {
+	for k := SVCParamKeyMandatory; k <= SVCParamKeyIPv6Hint; k++ {
+		if s == k.String() {
+			return k, true
+		}
+	}
+	if strings.HasPrefix(s, "key") && len(s) > 3 && isDigit(s[3]) {
+		v, err := strconv.ParseUint(s[3:], 10, 16)
+		if err == nil {
+			return SVCParamKey(v), true
+		}
+	}
+	return 0, false
+}
+
+// splitZoneValueList splits a comma-separated value list (RFC 9460, Appendix A.1),
+// the "\," and "\\" escapes are decoded.
+func splitZoneValueList(value []byte) [][]byte {
+	var (
+		list [][]byte
+		cur  = []byte{}
+	)
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i+1 < len(value) {
+				i++
+			}
+			cur = append(cur, value[i])
+		case ',':
+			list = append(list, cur)
+			cur = []byte{}
+		default:
+			cur = append(cur, value[i])
+		}
+	}
+	return append(list, cur)
+}
+
+func parseZoneSVCParam(text []byte) (SVCParam, error) {
+	keyText, valueText, hasValue := strings.Cut(string(text), "=")
+	key, ok := parseZoneSVCParamKey(keyText)
+	if !ok {
+		return nil, errZoneInvalidSVCParam
+	}
+
+	value, err := unescapeCharacterString([]byte(valueText))
+	if err != nil {
+		return nil, err
+	}
+
+	if key == SVCParamKeyNoDefaultALPN {
+		if hasValue {
+			return nil, errZoneInvalidSVCParam
+		}
+		return &SVCParamNoDefaultALPN{}, nil
+	}
+
+	if !hasValue && key != SVCParamKeyMandatory && key != SVCParamKeyALPN && key != SVCParamKeyIPv4Hint && key != SVCParamKeyIPv6Hint && key != SVCParamKeyPort && key != SVCParamKeyECH {
+		return &SVCParamRaw{Key: key}, nil
+	}
+
+	if !hasValue || len(value) == 0 {
+		return nil, errZoneInvalidSVCParam
+	}
+
+	switch key {
+	case SVCParamKeyMandatory:
+		var param SVCParamMandatory
+		for _, v := range strings.Split(string(value), ",") {
+			k, ok := parseZoneSVCParamKey(v)
+			if !ok || k == SVCParamKeyMandatory {
+				return nil, errZoneInvalidSVCParam
+			}
+			param.Keys = append(param.Keys, k)
+		}
+		sort.Slice(param.Keys, func(i, j int) bool { return param.Keys[i] < param.Keys[j] })
+		return &param, nil
+	case SVCParamKeyALPN:
+		var param SVCParamALPN
+		for _, v := range splitZoneValueList(value) {
+			if len(v) == 0 || len(v) > math.MaxUint8 {
+				return nil, errZoneInvalidSVCParam
+			}
+			param.ALPN = append(param.ALPN, v)
+		}
+		return &param, nil
+	case SVCParamKeyPort:
+		port, err := strconv.ParseUint(string(value), 10, 16)
+		if err != nil {
+			return nil, errZoneInvalidSVCParam
+		}
+		return &SVCParamPort{Port: uint16(port)}, nil
+	case SVCParamKeyIPv4Hint:
+		var param SVCParamIPv4Hint
+		for _, v := range strings.Split(string(value), ",") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil || !addr.Is4() {
+				return nil, errZoneInvalidSVCParam
+			}
+			param.Hints = append(param.Hints, addr.As4())
+		}
+		return &param, nil
+	case SVCParamKeyECH:
+		ech, err := base64.StdEncoding.DecodeString(string(value))
+		if err != nil {
+			return nil, errZoneInvalidSVCParam
+		}
+		return &SVCParamECH{ECH: ech}, nil
+	case SVCParamKeyIPv6Hint:
+		var param SVCParamIPv6Hint
+		for _, v := range strings.Split(string(value), ",") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil || !addr.Is6() || addr.Zone() != "" {
+				return nil, errZoneInvalidSVCParam
+			}
+			param.Hints = append(param.Hints, addr.As16())
+		}
+		return &param, nil
+	default:
+		return &SVCParamRaw{Key: key, Value: value}, nil
+	}
+}
@@ -0,0 +1,338 @@
+package dnsmsg
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func parseTestZone(t *testing.T, zone string, config ZoneParserConfig) ([]Resource, error) {
+	t.Helper()
+	z := NewZoneParser(strings.NewReader(zone), config)
+	var out []Resource
+	for {
+		res, err := z.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, res)
+	}
+}
+
+func TestZoneParser(t *testing.T) {
+	const zone = `
+$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1 admin.example.com. (
+		2023010101 ; serial
+		3600       ; refresh
+		10m        ; retry
+		1w         ; expire
+		300 )      ; minimum
+	IN	NS	ns1
+	NS	ns2.example.net.
+ns1	300	A	192.0.2.1
+	IN 600	AAAA	2001:db8::1
+www	CNAME	@
+mail	MX	10 mail
+txt	TXT	"v=spf1 -all" plain "a\"b\\c" "\065\066"
+_sip._tcp	SRV	1 2 5060 sip
+caa	CAA	0 issue "ca.example.net"
+svc	HTTPS	1 . port=8443 alpn="h2,h3" mandatory=port,alpn ipv4hint=192.0.2.1
+ds	DS	60485 5 1 ( 2BB183AF5F22588179A53B0A
+		98631FAD1A292118 )
+key	DNSKEY	257 3 15 AQID BA==
+sig	RRSIG	A 15 2 3600 20040426181525 1080411325 2642 example.com. AQID
+nsec	NSEC	host A MX RRSIG NSEC TYPE1234
+nsec3	NSEC3	1 1 12 aabbccdd 369iqae0ui3qabkpaot1sr9snrtg85sf A RRSIG
+nsec3	NSEC3PARAM	1 0 0 -
+generic	A	\# 4 C0000202
+unknown	CLASS3	TYPE65280	\# 3 ABCDEF
+empty	TYPE65281	\# 0
+$ORIGIN sub.example.com.
+host	A	192.0.2.3
+`
+
+	res, err := parseTestZone(t, zone, ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expect := []string{
+		"example.com. 3600 IN SOA ns1.example.com. admin.example.com. 2023010101 3600 600 604800 300",
+		"example.com. 3600 IN NS ns1.example.com.",
+		"example.com. 3600 IN NS ns2.example.net.",
+		"ns1.example.com. 300 IN A 192.0.2.1",
+		"ns1.example.com. 600 IN AAAA 2001:db8::1",
+		"www.example.com. 3600 IN CNAME example.com.",
+		"mail.example.com. 3600 IN MX 10 mail.example.com.",
+		`txt.example.com. 3600 IN TXT "v=spf1 -all" "plain" "a\"b\\c" "AB"`,
+		"_sip._tcp.example.com. 3600 IN SRV 1 2 5060 sip.example.com.",
+		`caa.example.com. 3600 IN CAA 0 issue "ca.example.net"`,
+		"svc.example.com. 3600 IN HTTPS 1 . mandatory=alpn,port alpn=h2,h3 port=8443 ipv4hint=192.0.2.1",
+		"ds.example.com. 3600 IN DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		"key.example.com. 3600 IN DNSKEY 257 3 15 AQIDBA==",
+		"sig.example.com. 3600 IN RRSIG A 15 2 3600 20040426181525 20040327181525 2642 example.com. AQID",
+		"nsec.example.com. 3600 IN NSEC host.example.com. A MX RRSIG NSEC TYPE1234",
+		"nsec3.example.com. 3600 IN NSEC3 1 1 12 AABBCCDD 369IQAE0UI3QABKPAOT1SR9SNRTG85SF A RRSIG",
+		"nsec3.example.com. 3600 IN NSEC3PARAM 1 0 0 -",
+		"generic.example.com. 3600 IN A 192.0.2.2",
+		`unknown.example.com. 3600 CLASS3 TYPE65280 \# 3 ABCDEF`,
+		`empty.example.com. 3600 CLASS3 TYPE65281 \# 0`,
+		"host.sub.example.com. 3600 CLASS3 A 192.0.2.3",
+	}
+
+	if len(res) != len(expect) {
+		for _, v := range res {
+			t.Log(v.String())
+		}
+		t.Fatalf("got %v resources, want: %v", len(res), len(expect))
+	}
+
+	for i, v := range res {
+		if s := v.String(); s != expect[i] {
+			t.Errorf("%v: got: %q, want: %q", i, s, expect[i])
+		}
+		if v.Header.Type != v.Body.ResourceType() {
+			t.Errorf("%v: header type %v does not match body type %v", i, v.Header.Type, v.Body.ResourceType())
+		}
+	}
+
+	if _, ok := res[17].Body.(ResourceA); !ok {
+		t.Errorf("generic A resource parsed as %T, want: ResourceA", res[17].Body)
+	}
+}
+
+func TestZoneParserTTLAndClassInheritance(t *testing.T) {
+	const zone = `example.com. 100 CH TXT a
+	TXT b
+www.example.com. TXT c
+$TTL 200
+www.example.com. 300 TXT d
+	TXT e
+`
+	res, err := parseTestZone(t, strings.Replace(zone, "CH", "CLASS3", 1), ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expect := []string{
+		`example.com. 100 CLASS3 TXT "a"`,
+		`example.com. 100 CLASS3 TXT "b"`,
+		`www.example.com. 100 CLASS3 TXT "c"`,
+		`www.example.com. 300 CLASS3 TXT "d"`,
+		`www.example.com. 200 CLASS3 TXT "e"`,
+	}
+	if len(res) != len(expect) {
+		t.Fatalf("got %v resources, want: %v", len(res), len(expect))
+	}
+	for i, v := range res {
+		if s := v.String(); s != expect[i] {
+			t.Errorf("%v: got: %q, want: %q", i, s, expect[i])
+		}
+	}
+
+	res, err = parseTestZone(t, "@ SOA ns admin 1 2 3 4 5\nwww A 192.0.2.1\n", ZoneParserConfig{
+		Origin: MustParseName("example.com"),
+		Class:  ClassANY,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res) != 2 || res[0].Header.TTL != 5 || res[1].Header.TTL != 5 || res[0].Header.Class != ClassANY {
+		t.Fatalf("unexpected resources: %v", res)
+	}
+}
+
+func TestZoneParserInclude(t *testing.T) {
+	files := map[string]string{
+		"sub.zone": "$ORIGIN other.com.\nhost A 192.0.2.2\n",
+		"err.zone": "\n\nhost A 192.0.2\n",
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(f)), nil
+	}
+
+	const zone = `$ORIGIN example.com.
+$TTL 60
+$INCLUDE sub.zone sub
+host A 192.0.2.1
+$INCLUDE "sub.zone"
+`
+
+	res, err := parseTestZone(t, zone, ZoneParserConfig{Open: open})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := []string{
+		"host.other.com. 60 IN A 192.0.2.2",
+		"host.example.com. 60 IN A 192.0.2.1",
+		"host.other.com. 60 IN A 192.0.2.2",
+	}
+	if len(res) != len(expect) {
+		t.Fatalf("got %v resources, want: %v", len(res), len(expect))
+	}
+	for i, v := range res {
+		if s := v.String(); s != expect[i] {
+			t.Errorf("%v: got: %q, want: %q", i, s, expect[i])
+		}
+	}
+
+	_, err = parseTestZone(t, "$TTL 60\n$INCLUDE err.zone example.com.\n", ZoneParserConfig{File: "main.zone", Open: open})
+	var zoneErr *ZoneParseError
+	if !errors.As(err, &zoneErr) || zoneErr.File != "err.zone" || zoneErr.Line != 3 || zoneErr.Column != 8 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = parseTestZone(t, "$INCLUDE missing.zone\n", ZoneParserConfig{Open: open})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v, want: %v", err, os.ErrNotExist)
+	}
+
+	_, err = parseTestZone(t, "$INCLUDE sub.zone\n", ZoneParserConfig{})
+	if !errors.Is(err, errZoneIncludeNotAllowed) {
+		t.Fatalf("unexpected error: %v, want: %v", err, errZoneIncludeNotAllowed)
+	}
+
+	files["loop.zone"] = "$INCLUDE loop.zone\n"
+	_, err = parseTestZone(t, "$INCLUDE loop.zone\n", ZoneParserConfig{Open: open})
+	if !errors.Is(err, errZoneIncludeTooDeep) {
+		t.Fatalf("unexpected error: %v, want: %v", err, errZoneIncludeTooDeep)
+	}
+}
+
+func TestZoneParserGenerate(t *testing.T) {
+	const zone = `$ORIGIN example.com.
+$TTL 60
+$GENERATE 1-3 host-$ A 192.0.2.$
+$GENERATE 10-14/2 ${-10,3,d}.rev 120 PTR host-${0,2,x}.example.com.
+$GENERATE 0-0 a\$ TXT "${15,0,X}"
+`
+	res, err := parseTestZone(t, zone, ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := []string{
+		"host-1.example.com. 60 IN A 192.0.2.1",
+		"host-2.example.com. 60 IN A 192.0.2.2",
+		"host-3.example.com. 60 IN A 192.0.2.3",
+		"000.rev.example.com. 120 IN PTR host-0a.example.com.",
+		"002.rev.example.com. 120 IN PTR host-0c.example.com.",
+		"004.rev.example.com. 120 IN PTR host-0e.example.com.",
+		`a$.example.com. 60 IN TXT "F"`,
+	}
+	if len(res) != len(expect) {
+		for _, v := range res {
+			t.Log(v.String())
+		}
+		t.Fatalf("got %v resources, want: %v", len(res), len(expect))
+	}
+	for i, v := range res {
+		if s := v.String(); s != expect[i] {
+			t.Errorf("%v: got: %q, want: %q", i, s, expect[i])
+		}
+	}
+
+	_, err = parseTestZone(t, "$ORIGIN example.com.\n$TTL 60\n\n$GENERATE 1-2 host-$ A 192.0.2.$.5\n", ZoneParserConfig{})
+	var zoneErr *ZoneParseError
+	if !errors.As(err, &zoneErr) || zoneErr.Line != 4 || zoneErr.Column != 1 || !errors.Is(err, errZoneInvalidAddress) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestZoneParserErrors(t *testing.T) {
+	cases := []struct {
+		zone   string
+		line   int
+		column int
+		err    error
+	}{
+		{"example.com. 60 A 192.0.2.1 (\n", 1, 29, errZoneUnbalancedParentheses},
+		{"example.com. 60 A 192.0.2.1 )\n", 1, 29, errZoneUnbalancedParentheses},
+		{"example.com. 60 TXT \"abc\n", 1, 21, errZoneUnterminatedQuote},
+		{"\texample.com. 60 A 192.0.2.1\n", 1, 2, errZoneNoOwner},
+		{"www 60 A 192.0.2.1\n", 1, 1, errZoneNoOrigin},
+		{"example.com. A 192.0.2.1\n", 1, 1, errZoneNoTTL},
+		{"example.com. 60 IN\n", 1, 19, errZoneMissingType},
+		{"example.com. 60 FOO 1\n", 1, 17, errZoneUnknownType},
+		{"$ORIGIN example.com.\na 300 \"\"\n", 2, 7, errZoneUnknownType},
+		{"$ORIGIN example.com.\na 300 IN \"\"\n", 2, 10, errZoneUnknownType},
+		{"example.com. 60 TYPE65280 abc\n", 1, 27, errZoneUnknownTypeRData},
+		{"example.com. 60 OPT \\# 0\n", 1, 17, errZoneMetaType},
+		{"example.com. 60 A\n", 1, 18, errZoneMissingRData},
+		{"example.com. 60 A 192.0.2.1 2\n", 1, 29, errZoneTrailingRData},
+		{"example.com. 60 A 2001:db8::1\n", 1, 19, errZoneInvalidAddress},
+		{"example.com. 60 MX 65536 mail.example.com.\n", 1, 20, errZoneInvalidValue},
+		{"example.com. 60 A \\# 3 C00002\n", 1, 19, errInvalidDNSMessage},
+		{"example.com. 60 A \\# 4 C00002\n", 1, 19, errZoneInvalidRDataLength},
+		{"example.com. 60 TYPE1000 \\# 1 ZZ\n", 1, 31, errZoneInvalidHex},
+		{"example.com. 60 DNSKEY 257 3 15 !!!!\n", 1, 33, errZoneInvalidBase64},
+		{"example.com. 60 RRSIG A 15 2 60 2004x 2004 1 example.com. AQID\n", 1, 33, errZoneInvalidTime},
+		{"example.com. 60 SVCB 1 . port=1 port=2\n", 1, 33, errZoneDuplicateSVCParam},
+		{"example.com. 60 SVCB 1 . foo=1\n", 1, 26, errZoneInvalidSVCParam},
+		{"$FOO\n", 1, 1, errZoneUnknownDirective},
+		{"$TTL 1y\n", 1, 6, errZoneInvalidTTL},
+		{"$GENERATE 3-1 a A 192.0.2.1\n", 1, 11, errZoneInvalidGenerate},
+		{"$GENERATE 0-4294967295 a A 192.0.2.1\n", 1, 11, errZoneGenerateTooLarge},
+		{"$GENERATE 0-65536/2 a A 192.0.2.1\n", 1, 11, errZoneGenerateTooLarge},
+	}
+
+	for _, tt := range cases {
+		_, err := parseTestZone(t, tt.zone, ZoneParserConfig{File: "test.zone"})
+		var zoneErr *ZoneParseError
+		if !errors.As(err, &zoneErr) {
+			t.Errorf("%q: unexpected error: %v, want: *ZoneParseError", tt.zone, err)
+			continue
+		}
+		if zoneErr.File != "test.zone" || zoneErr.Line != tt.line || zoneErr.Column != tt.column || !errors.Is(err, tt.err) {
+			t.Errorf("%q: unexpected error: %v, want: test.zone:%v:%v: %v", tt.zone, err, tt.line, tt.column, tt.err)
+		}
+	}
+}
+
+func TestZoneParserStickyError(t *testing.T) {
+	z := NewZoneParser(strings.NewReader("$TTL 60\nexample.com. FOO\nexample.com. A 192.0.2.1\n"), ZoneParserConfig{})
+	_, err := z.Next()
+	if err == nil {
+		t.Fatal("z.Next() unexpected success")
+	}
+	if _, err2 := z.Next(); err2 != err {
+		t.Fatalf("z.Next() unexpected error: %v, want: %v", err2, err)
+	}
+}
+
+func TestParseZoneTTL(t *testing.T) {
+	cases := []struct {
+		in  string
+		ttl uint32
+		ok  bool
+	}{
+		{"0", 0, true},
+		{"3600", 3600, true},
+		{"1h", 3600, true},
+		{"1H30m", 5400, true},
+		{"1w2d3h4m5s", 788645, true},
+		{"1h30", 3630, true},
+		{"4294967295", 4294967295, true},
+		{"4294967296", 0, false},
+		{"h", 0, false},
+		{"1x", 0, false},
+		{"1hh", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range cases {
+		ttl, ok := parseZoneTTL([]byte(tt.in))
+		if ttl != tt.ttl || ok != tt.ok {
+			t.Errorf("parseZoneTTL(%q) = (%v, %v), want: (%v, %v)", tt.in, ttl, ok, tt.ttl, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package dnsmsg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+)
+
+var errZoneWriterInvalidResource = errors.New("invalid resource")
+
+// ZoneWriterConfig is a configuration of the [ZoneWriter].
+type ZoneWriterConfig struct {
+	// Origin, when not zero, is written as an $ORIGIN directive at the start of
+	// the zone file, owner names that are equal to, or are subdomains of the Origin
+	// are written relative to it.
+	Origin Name
+
+	// Sort enables sorting of the resources in the canonical order (RFC 4034, Section 6),
+	// by owner name, class, type and resource data. Duplicate resources are removed.
+	Sort bool
+}
+
+// ZoneWriter writes resources in the zone file format (RFC 1035, Section 5), that
+// can be parsed by the [ZoneParser].
+//
+// Resources are buffered until [ZoneWriter.Flush] is called, resources with the same
+// owner name, class and type (RRSets) are written together, in the order
+// of first occurrence (unless sorting is enabled by [ZoneWriterConfig.Sort]).
+type ZoneWriter struct {
+	w         io.Writer
+	config    ZoneWriterConfig
+	resources []Resource
+}
+
+// NewZoneWriter creates a new [ZoneWriter] that writes to w.
+func NewZoneWriter(w io.Writer, config ZoneWriterConfig) *ZoneWriter {
+	return &ZoneWriter{w: w, config: config}
+}
+
+// Write buffers the resource, to be written by [ZoneWriter.Flush].
+// The header type must match the type of the resource body.
+func (z *ZoneWriter) Write(res Resource) error {
+	if res.Body == nil || res.Header.Name.Length == 0 || res.Header.Type != res.Body.ResourceType() {
+		return errZoneWriterInvalidResource
+	}
+	z.resources = append(z.resources, res)
+	return nil
+}
+
+// Flush writes all buffered resources.
+func (z *ZoneWriter) Flush() error {
+	resources, err := z.group()
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(z.w)
+	if z.config.Origin.Length != 0 {
+		w.WriteString("$ORIGIN " + z.config.Origin.String() + "\n")
+	}
+
+	var (
+		line      []byte
+		lastOwner Name
+	)
+	for i := range resources {
+		res := &resources[i]
+
+		line = line[:0]
+		if !lastOwner.Equal(&res.Header.Name) {
+			line = z.appendOwner(line, &res.Header.Name)
+			lastOwner = res.Header.Name
+		}
+		line = append(line, '\t')
+		line = strconv.AppendUint(line, uint64(res.Header.TTL), 10)
+		line = append(line, '\t')
+		line = append(line, res.Header.Class.String()...)
+		line = append(line, '\t')
+		line = append(line, res.Header.Type.String()...)
+		if rdata := res.Body.String(); rdata != "" {
+			line = append(line, '\t')
+			line = append(line, rdata...)
+		}
+		line = append(line, '\n')
+		w.Write(line)
+	}
+
+	z.resources = z.resources[:0]
+	return w.Flush()
+}
+
+// appendOwner appends the owner name, relative to the origin when possible.
+// A leading "$" is escaped, otherwise the line would be parsed as a control entry.
+func (z *ZoneWriter) appendOwner(dst []byte, owner *Name) []byte {
+	s := z.ownerString(owner)
+	if len(s) != 0 && s[0] == '$' {
+		dst = append(dst, '\\')
+	}
+	return append(dst, s...)
+}
+
+func (z *ZoneWriter) ownerString(owner *Name) string {
+	origin := &z.config.Origin
+	if origin.Length == 0 || !owner.isSubdomainOf(origin) {
+		return owner.String()
+	}
+	if owner.Equal(origin) {
+		return "@"
+	}
+
+	var relative Name
+	relative.Length = owner.Length - origin.Length + 1
+	copy(relative.Name[:relative.Length-1], owner.Name[:])
+	s := relative.String()
+	return s[:len(s)-1]
+}
+
+type zoneWriterResource struct {
+	Resource
+	index int
+	rdata []byte
+}
+
+// group orders the buffered resources, so that RRSets are written together.
+func (z *ZoneWriter) group() ([]Resource, error) {
+	resources := make([]zoneWriterResource, len(z.resources))
+	first := make(map[string]int)
+	for i, res := range z.resources {
+		resources[i].Resource = res
+
+		key := canonicalNameKey(&res.Header.Name) + string(appendUint16(appendUint16(nil, uint16(res.Header.Class)), uint16(res.Header.Type)))
+		idx, ok := first[key]
+		if !ok {
+			idx = i
+			first[key] = i
+		}
+		resources[i].index = idx
+
+		if z.config.Sort {
+			rdata, err := canonicalResourceRData(&res)
+			if err != nil {
+				return nil, err
+			}
+			resources[i].rdata = rdata
+		}
+	}
+
+	if !z.config.Sort {
+		sort.SliceStable(resources, func(i, j int) bool {
+			return resources[i].index < resources[j].index
+		})
+	} else {
+		sort.SliceStable(resources, func(i, j int) bool {
+			a, b := &resources[i].Header, &resources[j].Header
+			if c := a.Name.Compare(&b.Name); c != 0 {
+				return c < 0
+			}
+			if a.Class != b.Class {
+				return a.Class < b.Class
+			}
+			if a.Type != b.Type {
+				return a.Type < b.Type
+			}
+			return bytes.Compare(resources[i].rdata, resources[j].rdata) < 0
+		})
+	}
+
+	out := make([]Resource, 0, len(resources))
+	for i, res := range resources {
+		if z.config.Sort && i != 0 {
+			prev := &resources[i-1]
+			if prev.index == res.index && bytes.Equal(prev.rdata, res.rdata) {
+				continue
+			}
+		}
+		out = append(out, res.Resource)
+	}
+	return out, nil
+}
+
+// canonicalResourceRData returns the resource data of res in the canonical form (RFC 4034, Section 6.2).
+func canonicalResourceRData(res *Resource) ([]byte, error) {
+	b := StartBuilder(make([]byte, 0, 512), 0, 0)
+	b.StartAnswers()
+	if err := b.Resource(res.Header, res.Body); err != nil {
+		return nil, err
+	}
+
+	p, _, err := Parse(b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err := p.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if _, err := p.ResourceHeader(); err != nil {
+		return nil, err
+	}
+	rdata, err := p.appendUncompressedResourceData(nil)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalRData(res.Header.Type, rdata)
+}
@@ -0,0 +1,210 @@
+package dnsmsg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZoneWriter(t *testing.T) {
+	const zone = `$ORIGIN example.com.
+$TTL 3600
+www	A	192.0.2.2
+@	SOA	ns1 admin 1 3600 600 86400 300
+www	AAAA	2001:db8::1
+www	A	192.0.2.1
+@	NS	ns1
+ns1.example.net.	A	192.0.2.3
+a\.b	TXT	"x y"
+`
+	res, err := parseTestZone(t, zone, ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out strings.Builder
+	w := NewZoneWriter(&out, ZoneWriterConfig{Origin: MustParseName("example.com")})
+	for _, v := range res {
+		if err := w.Write(v); err != nil {
+			t.Fatalf("w.Write() unexpected error: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("w.Flush() unexpected error: %v", err)
+	}
+
+	const expect = "$ORIGIN example.com.\n" +
+		"www\t3600\tIN\tA\t192.0.2.2\n" +
+		"\t3600\tIN\tA\t192.0.2.1\n" +
+		"@\t3600\tIN\tSOA\tns1.example.com. admin.example.com. 1 3600 600 86400 300\n" +
+		"www\t3600\tIN\tAAAA\t2001:db8::1\n" +
+		"@\t3600\tIN\tNS\tns1.example.com.\n" +
+		"ns1.example.net.\t3600\tIN\tA\t192.0.2.3\n" +
+		"a\\.b\t3600\tIN\tTXT\t\"x y\"\n"
+	if out.String() != expect {
+		t.Fatalf("unexpected zone file:\n%v\nwant:\n%v", out.String(), expect)
+	}
+
+	roundTrip, err := parseTestZone(t, out.String(), ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(roundTrip) != len(res) {
+		t.Fatalf("got %v resources after round trip, want: %v", len(roundTrip), len(res))
+	}
+}
+
+func TestZoneWriterSort(t *testing.T) {
+	const zone = `$ORIGIN example.com.
+$TTL 60
+z	A	192.0.2.1
+*.z	A	192.0.2.1
+a	MX	10 B.example.com.
+a	MX	10 a.example.com.
+a	A	192.0.2.2
+A	A	192.0.2.1
+a	A	192.0.2.2
+@	NS	ns1
+@	SOA	ns1 admin 1 3600 600 86400 300
+unknown	TYPE65280	\# 1 02
+unknown	TYPE65280	\# 1 01
+`
+	res, err := parseTestZone(t, zone, ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out strings.Builder
+	w := NewZoneWriter(&out, ZoneWriterConfig{Sort: true})
+	for _, v := range res {
+		if err := w.Write(v); err != nil {
+			t.Fatalf("w.Write() unexpected error: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("w.Flush() unexpected error: %v", err)
+	}
+
+	const expect = "example.com.\t60\tIN\tNS\tns1.example.com.\n" +
+		"\t60\tIN\tSOA\tns1.example.com. admin.example.com. 1 3600 600 86400 300\n" +
+		"A.example.com.\t60\tIN\tA\t192.0.2.1\n" +
+		"\t60\tIN\tA\t192.0.2.2\n" +
+		"\t60\tIN\tMX\t10 a.example.com.\n" +
+		"\t60\tIN\tMX\t10 B.example.com.\n" +
+		"unknown.example.com.\t60\tIN\tTYPE65280\t\\# 1 01\n" +
+		"\t60\tIN\tTYPE65280\t\\# 1 02\n" +
+		"z.example.com.\t60\tIN\tA\t192.0.2.1\n" +
+		"*.z.example.com.\t60\tIN\tA\t192.0.2.1\n"
+	if out.String() != expect {
+		t.Fatalf("unexpected zone file:\n%v\nwant:\n%v", out.String(), expect)
+	}
+}
+
+func TestZoneWriterInvalidResource(t *testing.T) {
+	w := NewZoneWriter(&strings.Builder{}, ZoneWriterConfig{})
+	err := w.Write(Resource{
+		Header: ResourceHeader{Name: MustParseName("example.com"), Type: TypeAAAA, Class: ClassIN},
+		Body:   ResourceA{},
+	})
+	if err != errZoneWriterInvalidResource {
+		t.Fatalf("w.Write() unexpected error: %v, want: %v", err, errZoneWriterInvalidResource)
+	}
+}
+
+func TestZoneWriterDollarOwner(t *testing.T) {
+	resources := []Resource{
+		{
+			Header: ResourceHeader{Name: MustParseName("$TTL.example.com"), Type: TypeA, Class: ClassIN, TTL: 60},
+			Body:   ResourceA{A: [4]byte{192, 0, 2, 1}},
+		},
+		{
+			Header: ResourceHeader{Name: MustParseName("$ORIGIN.example.net"), Type: TypeA, Class: ClassIN, TTL: 60},
+			Body:   ResourceA{A: [4]byte{192, 0, 2, 2}},
+		},
+	}
+
+	var out strings.Builder
+	w := NewZoneWriter(&out, ZoneWriterConfig{Origin: MustParseName("example.com")})
+	for _, v := range resources {
+		if err := w.Write(v); err != nil {
+			t.Fatalf("w.Write() unexpected error: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("w.Flush() unexpected error: %v", err)
+	}
+
+	const expect = "$ORIGIN example.com.\n" +
+		"\\$TTL\t60\tIN\tA\t192.0.2.1\n" +
+		"\\$ORIGIN.example.net.\t60\tIN\tA\t192.0.2.2\n"
+	if out.String() != expect {
+		t.Fatalf("unexpected zone file:\n%v\nwant:\n%v", out.String(), expect)
+	}
+
+	roundTrip, err := parseTestZone(t, out.String(), ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(roundTrip) != len(resources) {
+		t.Fatalf("got %v resources after round trip, want: %v", len(roundTrip), len(resources))
+	}
+	for i := range roundTrip {
+		if !roundTrip[i].Header.Name.Equal(&resources[i].Header.Name) {
+			t.Errorf("%v: got owner %v after round trip, want: %v", i, roundTrip[i].Header.Name.String(), resources[i].Header.Name.String())
+		}
+	}
+}
+
+func TestZoneWriterSpecialCharacters(t *testing.T) {
+	names := []string{
+		"a;b.example.com",
+		"a(b.example.com",
+		"a)b.example.com",
+		"a\"b.example.com",
+		`a\032b.example.com`,
+		`a\009b.example.com`,
+		"@.example.com",
+		"@.example.net",
+	}
+
+	var resources []Resource
+	for _, v := range names {
+		name := MustParseName(v)
+		resources = append(resources, Resource{
+			Header: ResourceHeader{Name: name, Type: TypeCNAME, Class: ClassIN, TTL: 60},
+			Body:   ResourceCNAME{CNAME: name},
+		})
+	}
+
+	var out strings.Builder
+	w := NewZoneWriter(&out, ZoneWriterConfig{Origin: MustParseName("example.com")})
+	for _, v := range resources {
+		if err := w.Write(v); err != nil {
+			t.Fatalf("w.Write() unexpected error: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("w.Flush() unexpected error: %v", err)
+	}
+
+	roundTrip, err := parseTestZone(t, out.String(), ZoneParserConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v, zone file:\n%v", err, out.String())
+	}
+	if len(roundTrip) != len(resources) {
+		t.Fatalf("got %v resources after round trip, want: %v, zone file:\n%v", len(roundTrip), len(resources), out.String())
+	}
+
+	for _, v := range resources {
+		found := false
+		for _, r := range roundTrip {
+			cname, ok := r.Body.(ResourceCNAME)
+			if ok && r.Header.Name.Equal(&v.Header.Name) && cname.CNAME.Equal(&v.Header.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%v not found after round trip, zone file:\n%v", v.Header.Name.String(), out.String())
+		}
+	}
+}